@@ -0,0 +1,58 @@
+package server
+
+import (
+	"time"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/config"
+	"github.com/afga95/lab-go-otel-zipkin/shared/telemetry"
+)
+
+// Configuração do Serviço A, lida uma única vez do ambiente por LoadConfig
+type Config struct {
+	Port        string
+	ServiceBURL string
+
+	RetryMaxAttempts int
+	RetryBudget      int
+
+	MaxHeaderBytes int
+	StrictJSON     bool
+	VerboseErrors  bool
+	RequestTimeout time.Duration
+
+	EnablePprof bool
+	PprofAddr   string
+
+	Tracing TracingConfig
+}
+
+// Configuração da exportação de traces, comum aos serviços. Valores zero mantêm os
+// defaults do SDK
+type TracingConfig = telemetry.Config
+
+// Lê e valida todas as variáveis de ambiente. Retorna os *config.Error de todas as
+// variáveis inválidas combinados com errors.Join
+func LoadConfig() (Config, error) {
+	var p config.Parser
+
+	cfg := Config{
+		Port:        p.Str("PORT", "8080"),
+		ServiceBURL: p.Str("SERVICE_B_URL", "http://localhost:8082"),
+
+		RetryMaxAttempts: p.PositiveInt("RETRY_MAX_ATTEMPTS", 3),
+		RetryBudget:      p.NonNegativeInt("RETRY_BUDGET", 2),
+
+		MaxHeaderBytes: p.PositiveInt("MAX_HEADER_BYTES", 64<<10),
+		StrictJSON:     p.Bool("STRICT_JSON"),
+		VerboseErrors:  p.Bool("VERBOSE_ERRORS"),
+		RequestTimeout: p.Millis("REQUEST_TIMEOUT"),
+
+		EnablePprof: p.Bool("ENABLE_PPROF"),
+		PprofAddr:   p.Str("PPROF_ADDR", "localhost:6060"),
+
+		Tracing: telemetry.ConfigFromEnv(&p),
+	}
+
+	return cfg, p.Err()
+}
+
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/config"
+	"github.com/afga95/lab-go-otel-zipkin/shared/telemetry"
+)
+
+func TestLoadConfigDefaults(t *testing.T) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if cfg.Port != "8080" || cfg.ServiceBURL != "http://localhost:8082" {
+		t.Errorf("Port/ServiceBURL = %q/%q, want 8080/http://localhost:8082", cfg.Port, cfg.ServiceBURL)
+	}
+	if cfg.RetryMaxAttempts != 3 || cfg.RetryBudget != 2 {
+		t.Errorf("retry = %d/%d, want 3/2", cfg.RetryMaxAttempts, cfg.RetryBudget)
+	}
+	if cfg.MaxHeaderBytes != 64<<10 || cfg.RequestTimeout != 0 {
+		t.Errorf("MaxHeaderBytes/RequestTimeout = %d/%v, want 65536/0", cfg.MaxHeaderBytes, cfg.RequestTimeout)
+	}
+	if cfg.StrictJSON || cfg.VerboseErrors || cfg.EnablePprof {
+		t.Error("flags booleanas devem vir desligadas por padrão")
+	}
+	if cfg.Tracing.Exporter != telemetry.ExporterOTLP || cfg.Tracing.OTLPEndpoint != "localhost:4317" {
+		t.Errorf("Tracing = %+v, want otlp em localhost:4317", cfg.Tracing)
+	}
+}
+
+// Cada variável inválida vira um *config.Error com o nome da variável
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"RETRY_MAX_ATTEMPTS", "0"},
+		{"RETRY_BUDGET", "-1"},
+		{"MAX_HEADER_BYTES", "abc"},
+		{"STRICT_JSON", "sim"},
+		{"ENABLE_PPROF", "yes"},
+		{"REQUEST_TIMEOUT", "1s"},
+		{"TRACE_EXPORTER", "zipkin"},
+		{"TRACE_SAMPLE_RATIO", "1.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+
+			_, err := LoadConfig()
+			var cfgErr *config.Error
+			if !errors.As(err, &cfgErr) {
+				t.Fatalf("LoadConfig err = %v, want *config.Error", err)
+			}
+			if cfgErr.Var != tt.name {
+				t.Errorf("Var = %q, want %q", cfgErr.Var, tt.name)
+			}
+		})
+	}
+}
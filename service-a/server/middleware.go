@@ -0,0 +1,50 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/telemetry"
+)
+
+// Responde os health checks (/health, /livez, /readyz) antes do roteador, sem passar
+// pelo tracing e pelos demais middlewares, para que probes não gerem spans nem sejam limitadas
+func healthCheckMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			switch r.URL.Path {
+			case "/health", "/livez", "/readyz":
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Quanto do corpo é lido para achar o CEP antes do tracing; corpos maiores seguem sem ele
+const samplingPeekBytes = 4 << 10
+
+// Lê o CEP do corpo do POST e o coloca no baggage antes do otelmux, para que a decisão
+// de amostragem (TRACE_TARGET_REGIONS) seja tomada no span raiz. O corpo é devolvido
+// intacto ao handler
+func samplingCEPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.Body != nil {
+			peek, err := io.ReadAll(io.LimitReader(r.Body, samplingPeekBytes))
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(peek), r.Body), r.Body}
+
+			var req CEPRequest
+			if err == nil && json.Unmarshal(peek, &req) == nil {
+				r = r.WithContext(telemetry.ContextWithSamplingCEP(r.Context(), req.CEP))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
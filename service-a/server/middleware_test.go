@@ -0,0 +1,44 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// O CEP do corpo vai para o baggage antes do tracing, e o handler recebe o corpo intacto
+func TestSamplingCEPMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantBaggage string
+	}{
+		{"cep válido", `{"cep": "20040-020"}`, "20040020"},
+		{"cep inválido", `{"cep": "123"}`, ""},
+		{"json inválido", `{"cep": `, ""},
+		{"corpo maior que o limite", `{"cep": "20040020", "x": "` + strings.Repeat("a", samplingPeekBytes) + `"}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBody, gotBaggage string
+			handler := samplingCEPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				gotBody = string(b)
+				gotBaggage = baggage.FromContext(r.Context()).Member("cep").Value()
+			}))
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+			if gotBody != tt.body {
+				t.Errorf("corpo no handler = %q, want %q", gotBody, tt.body)
+			}
+			if gotBaggage != tt.wantBaggage {
+				t.Errorf("baggage cep = %q, want %q", gotBaggage, tt.wantBaggage)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Escreve a resposta de erro em JSON e registra o status e a mensagem no span
+func writeError(w http.ResponseWriter, span trace.Span, status int, msg string) {
+	writeDetailedError(w, span, status, msg, "", "")
+}
+
+// Respostas de erro com código e detalhes (VERBOSE_ERRORS)
+var verboseErrors bool
+
+// Como writeError; com VERBOSE_ERRORS=true inclui o código e o detalhe do erro
+func writeDetailedError(w http.ResponseWriter, span trace.Span, status int, msg, code, details string) {
+	recordErrorStatus(span, status, msg)
+
+	body := ErrorResponse{Message: msg, TraceID: traceIDOf(span)}
+	if verboseErrors {
+		body.Code, body.Details = code, details
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// Marca o span como erro com o status HTTP devolvido ao cliente
+func recordErrorStatus(span trace.Span, status int, msg string) {
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+	span.SetStatus(codes.Error, msg)
+}
+
+func traceIDOf(span trace.Span) string {
+	if sc := span.SpanContext(); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Troca o tracer do pacote por um que grava os spans, restaurado ao fim do teste
+func withSpanRecorder(tb testing.TB) *tracetest.SpanRecorder {
+	tb.Helper()
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	prev := tracer
+	tracer = tp.Tracer("service-a")
+	tb.Cleanup(func() {
+		tracer = prev
+		tp.Shutdown(context.Background())
+	})
+	return sr
+}
+
+// Span encerrado com o nome dado; falha o teste se não houver
+func endedSpan(tb testing.TB, sr *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	tb.Helper()
+	for _, s := range sr.Ended() {
+		if s.Name() == name {
+			return s
+		}
+	}
+	tb.Fatalf("span %q não encontrado", name)
+	return nil
+}
+
+// Valor do atributo key no span; vazio se ausente
+func spanAttr(s sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range s.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestWriteDetailedError(t *testing.T) {
+	defer func(v bool) { verboseErrors = v }(verboseErrors)
+
+	tests := []struct {
+		name     string
+		verbose  bool
+		status   int
+		wantCode string
+	}{
+		{"422", false, http.StatusUnprocessableEntity, ""},
+		{"422 verboso", true, http.StatusUnprocessableEntity, "INVALID_FORMAT"},
+		{"500", false, http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verboseErrors = tt.verbose
+			sr := withSpanRecorder(t)
+			_, span := tracer.Start(context.Background(), "cep_handler")
+
+			rec := httptest.NewRecorder()
+			writeDetailedError(rec, span, tt.status, "invalid zipcode", "INVALID_FORMAT", "7 dígitos")
+			span.End()
+
+			if rec.Code != tt.status || rec.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("status = %d, Content-Type = %q, want %d e application/json", rec.Code, rec.Header().Get("Content-Type"), tt.status)
+			}
+			var body ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("corpo inválido: %v (%s)", err, rec.Body)
+			}
+			if body.Message != "invalid zipcode" || body.Code != tt.wantCode || body.TraceID != span.SpanContext().TraceID().String() {
+				t.Errorf("corpo = %+v, want message, code %q e trace_id %s", body, tt.wantCode, span.SpanContext().TraceID())
+			}
+
+			ended := endedSpan(t, sr, "cep_handler")
+			if ended.Status().Code != codes.Error {
+				t.Errorf("status do span = %+v, want Error", ended.Status())
+			}
+			if got := spanAttr(ended, "http.response.status_code").AsInt64(); got != int64(tt.status) {
+				t.Errorf("http.response.status_code = %d, want %d", got, tt.status)
+			}
+		})
+	}
+}
+
+// Sem span válido (ex.: tracing desligado) o corpo não leva trace_id
+func TestWriteErrorWithoutTrace(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeError(rec, trace.SpanFromContext(context.Background()), http.StatusInternalServerError, "internal server error")
+
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := body["trace_id"]; ok || body["message"] != "internal server error" {
+		t.Errorf("corpo = %v, want só message", body)
+	}
+}
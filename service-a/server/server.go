@@ -0,0 +1,289 @@
+// Serviço A: recebe e valida CEPs via POST e consulta o Serviço B
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/afga95/lab-go-otel-zipkin/service-a/serviceb"
+	"github.com/afga95/lab-go-otel-zipkin/shared/telemetry"
+	"github.com/afga95/lab-go-otel-zipkin/shared/types"
+	"github.com/afga95/lab-go-otel-zipkin/shared/validation"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Estruturas de dados
+type CEPRequest struct {
+	CEP string `json:"cep"`
+}
+
+type ErrorResponse = types.ErrorResponse
+
+var (
+	// Tracer do pacote. O tracer global delega para o provider configurado em
+	// initTracer, então é seguro usá-lo antes da inicialização (no-op até lá)
+	tracer = otel.Tracer("service-a")
+
+	serviceBClient *serviceb.Client
+	serviceBURL    string
+	strictJSON     bool
+	requestTimeout time.Duration
+)
+
+// Inicializa tracing, cliente do Serviço B e rotas e atende requisições até ctx ser
+// cancelado. Exportada para o main e para rodar o serviço em processo nos testes de
+// integração do Serviço B
+func Run(ctx context.Context, cfg Config) error {
+	// Inicializar OpenTelemetry
+	tp, err := initTracer(cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("erro ao inicializar tracer: %w", err)
+	}
+	defer tp.Shutdown(context.Background())
+
+	serviceBURL = cfg.ServiceBURL
+	strictJSON = cfg.StrictJSON
+	verboseErrors = cfg.VerboseErrors
+	requestTimeout = cfg.RequestTimeout
+
+	// Cliente do Serviço B com instrumentação OpenTelemetry
+	serviceBClient = serviceb.NewClient(serviceBURL,
+		serviceb.WithHTTPClient(&http.Client{
+			Timeout:   30 * time.Second,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		}),
+		serviceb.WithRetry(cfg.RetryMaxAttempts, cfg.RetryBudget),
+	)
+
+	// Configuração das rotas
+	r := mux.NewRouter()
+	r.Use(samplingCEPMiddleware)
+	r.Use(otelmux.Middleware("service-a"))
+
+	// Rota principal para receber CEP
+	r.HandleFunc("/", cepHandler).Methods("POST")
+
+	// Rota raiz com informações da API
+	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"service":     "CEP Input Service",
+			"version":     "1.0.0",
+			"description": "Serviço A - Responsável por receber e validar CEPs",
+			"endpoints": map[string]string{
+				"input":  "POST / - Receber CEP",
+				"health": "GET /health - Health check",
+				"livez":  "GET /livez - Liveness probe",
+				"readyz": "GET /readyz - Readiness probe",
+			},
+		})
+	}).Methods("GET")
+
+	// Log de inicialização
+	log.Printf("Serviço A iniciando na porta %s", cfg.Port)
+	log.Printf("Service B URL: %s", serviceBURL)
+	log.Printf("Endpoints disponíveis:")
+	log.Printf("  POST /      - Receber CEP")
+	log.Printf("  GET /health - Health check")
+	log.Printf("  GET /livez  - Liveness probe")
+	log.Printf("  GET /readyz - Readiness probe")
+
+	if cfg.EnablePprof {
+		telemetry.StartPprofServer(cfg.PprofAddr)
+	}
+
+	server := newServer(cfg, healthCheckMiddleware(r))
+	return serve(ctx, server)
+}
+
+// Servidor HTTP do serviço. ReadHeaderTimeout limita o tempo para receber os headers
+// (slowloris) e MaxHeaderBytes o seu tamanho (431 acima dele)
+func newServer(cfg Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      15 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// Atende no servidor até ctx ser cancelado e então encerra aguardando as requisições em andamento
+func serve(ctx context.Context, server *http.Server) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		log.Printf("Encerrando o servidor...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	}
+}
+
+// Inicializa o OpenTelemetry tracer
+func initTracer(cfg TracingConfig) (*sdktrace.TracerProvider, error) {
+	// Configuração do resource
+	res, err := resource.New(context.Background(),
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String("service-a"),
+			semconv.ServiceVersionKey.String("1.0.0"),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar resource: %w", err)
+	}
+
+	exporter, err := telemetry.NewTraceExporter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	secondary, err := telemetry.NewSecondaryTraceExporter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Amostragem: sempre para as regiões em TRACE_TARGET_REGIONS, taxa TRACE_SAMPLE_RATIO para o resto
+	sampler, err := telemetry.NewRegionSampler(cfg.TargetRegions, cfg.SampleRatio)
+	if err != nil {
+		return nil, err
+	}
+
+	// Configuração do trace provider. Cada collector tem o seu batch processor (e a sua
+	// fila): o secundário recebe os mesmos spans sem atrasar o principal
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithBatcher(telemetry.WithAttributeFilter(telemetry.WithTruncationMarking(exporter), cfg), telemetry.BatchSpanProcessorOptions(cfg)...),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithRawSpanLimits(telemetry.SpanLimits(cfg)),
+	}
+	if secondary != nil {
+		opts = append(opts, sdktrace.WithBatcher(telemetry.WithAttributeFilter(telemetry.WithTruncationMarking(secondary), cfg), telemetry.BatchSpanProcessorOptions(cfg)...))
+	}
+	tp := sdktrace.NewTracerProvider(opts...)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return tp, nil
+}
+
+// Handler principal para receber CEP
+func cepHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Prazo da requisição (REQUEST_TIMEOUT), repassado ao Serviço B em X-Request-Deadline
+	if requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+		defer cancel()
+	}
+
+	// Decodifica o JSON do request antes de iniciar o span, para que o CEP já
+	// esteja nos atributos iniciais. Com STRICT_JSON=true, campos desconhecidos
+	// (ex.: {"zipcode": ...}) são rejeitados em vez de resultarem num CEP vazio
+	var cepReq CEPRequest
+	decoder := json.NewDecoder(r.Body)
+	if strictJSON {
+		decoder.DisallowUnknownFields()
+	}
+	decodeErr := decoder.Decode(&cepReq)
+
+	// Inicia span para o handler, já com o CEP
+	ctx, span := tracer.Start(ctx, "cep_handler", trace.WithAttributes(attribute.String("cep", cepReq.CEP)))
+	defer span.End()
+
+	// Configura headers de resposta
+	w.Header().Set("Content-Type", "application/json")
+
+	if decodeErr != nil {
+		span.RecordError(decodeErr)
+		if field, ok := strings.CutPrefix(decodeErr.Error(), "json: unknown field "); ok {
+			span.SetAttributes(attribute.String("error", "unknown_field"))
+			writeError(w, span, http.StatusBadRequest, "unknown field "+field)
+			return
+		}
+		span.SetAttributes(attribute.String("error", "invalid_json"))
+		writeError(w, span, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	// Validação: CEP deve ser string e ter formato válido
+	if cepReq.CEP == "" || !validation.IsValidCEP(cepReq.CEP) {
+		span.SetAttributes(attribute.String("validation", "invalid_zipcode"))
+		code, details := validation.DescribeCEP(cepReq.CEP)
+		writeDetailedError(w, span, http.StatusUnprocessableEntity, "invalid zipcode", code, details)
+		return
+	}
+
+	// Chama o Serviço B
+	response, err := callServiceB(ctx, cepReq.CEP)
+	if err != nil {
+		span.RecordError(err)
+
+		// Trata diferentes tipos de erro do Serviço B. 429 é repassado com o Retry-After,
+		// para o cliente saber quando tentar de novo; indisponibilidade vira 502
+		var statusErr *serviceb.StatusError
+		switch {
+		case errors.Is(err, serviceb.ErrInvalidZipcode):
+			writeError(w, span, http.StatusUnprocessableEntity, "invalid zipcode")
+		case errors.Is(err, serviceb.ErrZipcodeNotFound):
+			writeError(w, span, http.StatusNotFound, "can not find zipcode")
+		case errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusTooManyRequests:
+			if statusErr.RetryAfter != "" {
+				w.Header().Set("Retry-After", statusErr.RetryAfter)
+			}
+			writeError(w, span, http.StatusTooManyRequests, "too many requests")
+		case errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusBadGateway || statusErr.StatusCode == http.StatusServiceUnavailable):
+			writeError(w, span, http.StatusBadGateway, "service b unavailable")
+		default:
+			writeError(w, span, http.StatusInternalServerError, "internal server error")
+		}
+		return
+	}
+
+	// Sucesso
+	span.SetAttributes(
+		attribute.String("city", response.City),
+		attribute.Float64("temp_c", float64(response.TempC)),
+	)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Chama o Serviço B
+func callServiceB(ctx context.Context, cep string) (*serviceb.TemperatureResponse, error) {
+	// Inicia span para chamada ao Serviço B
+	ctx, span := tracer.Start(ctx, "call_service_b")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("service", "service-b"),
+		attribute.String("cep", cep),
+	)
+
+	return serviceBClient.GetTemperature(ctx, cep)
+}
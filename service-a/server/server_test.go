@@ -0,0 +1,241 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/afga95/lab-go-otel-zipkin/service-a/serviceb"
+)
+
+// Aponta o cliente do Serviço B para handler, restaurado ao fim do teste
+func withMockServiceB(tb testing.TB, handler http.Handler) {
+	tb.Helper()
+	srv := httptest.NewServer(handler)
+	prev := serviceBClient
+	tb.Cleanup(func() {
+		srv.Close()
+		serviceBClient = prev
+	})
+	serviceBClient = serviceb.NewClient(srv.URL)
+}
+
+// Serviço B simulado que responde 200 com São Paulo para qualquer CEP
+func mockServiceB() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"city":"São Paulo","temp_C":23.5,"temp_F":74.3,"temp_K":296.65}`))
+	})
+}
+
+// Com STRICT_JSON=true, campos desconhecidos são 400; sem ele, são ignorados e o CEP
+// ausente cai na validação (422)
+func TestCEPHandlerStrictJSON(t *testing.T) {
+	defer func(b bool) { strictJSON = b }(strictJSON)
+	withMockServiceB(t, mockServiceB())
+
+	tests := []struct {
+		name        string
+		strict      bool
+		body        string
+		wantStatus  int
+		wantMessage string
+	}{
+		{"estrito, campo desconhecido", true, `{"zipcode": "01001000"}`, http.StatusBadRequest, `unknown field "zipcode"`},
+		{"estrito, campo extra", true, `{"cep": "01001000", "x": 1}`, http.StatusBadRequest, `unknown field "x"`},
+		{"estrito, corpo válido", true, `{"cep": "01001000"}`, http.StatusOK, ""},
+		{"leniente, campo desconhecido", false, `{"zipcode": "01001000"}`, http.StatusUnprocessableEntity, "invalid zipcode"},
+		{"leniente, campo extra", false, `{"cep": "01001000", "x": 1}`, http.StatusOK, ""},
+		{"json inválido", false, `{"cep": `, http.StatusBadRequest, "invalid request body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strictJSON = tt.strict
+			rec := httptest.NewRecorder()
+			cepHandler(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantMessage == "" {
+				return
+			}
+			var body struct {
+				Message string `json:"message"`
+			}
+			json.NewDecoder(rec.Body).Decode(&body)
+			if body.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", body.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+// Com VERBOSE_ERRORS=true cada falha de validação do CEP traz o próprio código e
+// detalhe; sem ele, só a mensagem
+func TestCEPHandlerVerboseErrors(t *testing.T) {
+	defer func(v bool) { verboseErrors = v }(verboseErrors)
+	withMockServiceB(t, mockServiceB())
+
+	tests := []struct {
+		name        string
+		verbose     bool
+		body        string
+		wantCode    string
+		wantDetails string
+	}{
+		{"ausente", true, `{}`, "MISSING", "zipcode is required"},
+		{"caractere inválido", true, `{"cep": "0100100a"}`, "INVALID_CHARACTERS", "expected only digits, got 'a' at position 8"},
+		{"sete dígitos", true, `{"cep": "0100100"}`, "INVALID_FORMAT", "expected 8 digits, got 7"},
+		{"malformado", true, `{"cep": "0100\u00001000"}`, "MALFORMED", "zipcode contains control characters or invalid UTF-8"},
+		{"desligado", false, `{"cep": "0100100"}`, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verboseErrors = tt.verbose
+			rec := httptest.NewRecorder()
+			cepHandler(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+
+			var body ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != http.StatusUnprocessableEntity || body.Message != "invalid zipcode" || body.Code != tt.wantCode || body.Details != tt.wantDetails {
+				t.Errorf("resposta = %d %+v, want 422 invalid zipcode com code %q e details %q", rec.Code, body, tt.wantCode, tt.wantDetails)
+			}
+		})
+	}
+}
+
+// Erros do Serviço B: 429 passa adiante com o Retry-After, 502/503 viram 502 e os
+// demais status inesperados, 500
+func TestCEPHandlerServiceBErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		retryAfter     string
+		wantStatus     int
+		wantMessage    string
+		wantRetryAfter string
+	}{
+		{"429 com Retry-After", http.StatusTooManyRequests, "2", http.StatusTooManyRequests, "too many requests", "2"},
+		{"429 sem Retry-After", http.StatusTooManyRequests, "", http.StatusTooManyRequests, "too many requests", ""},
+		{"502", http.StatusBadGateway, "", http.StatusBadGateway, "service b unavailable", ""},
+		{"503", http.StatusServiceUnavailable, "1", http.StatusBadGateway, "service b unavailable", ""},
+		{"414", http.StatusRequestURITooLong, "", http.StatusInternalServerError, "internal server error", ""},
+		{"500", http.StatusInternalServerError, "", http.StatusInternalServerError, "internal server error", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Sem retentativas, para os 5xx não esperarem o backoff
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+			defer func(c *serviceb.Client) { serviceBClient = c }(serviceBClient)
+			serviceBClient = serviceb.NewClient(srv.URL, serviceb.WithRetry(1, 0))
+
+			rec := httptest.NewRecorder()
+			cepHandler(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"cep": "01001000"}`)))
+
+			var body ErrorResponse
+			json.NewDecoder(rec.Body).Decode(&body)
+			if rec.Code != tt.wantStatus || body.Message != tt.wantMessage {
+				t.Errorf("resposta = %d %q, want %d %q", rec.Code, body.Message, tt.wantStatus, tt.wantMessage)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+// Headers acima de MAX_HEADER_BYTES são recusados com 431 antes de chegar ao handler. O
+// net/http soma 4 KiB de folga ao limite, por isso o header grande tem 16 KiB
+func TestServerMaxHeaderBytes(t *testing.T) {
+	server := newServer(Config{MaxHeaderBytes: 1 << 10}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ln)
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		size int
+		want int
+	}{
+		{"header pequeno", 100, http.StatusOK},
+		{"header acima do limite", 16 << 10, http.StatusRequestHeaderFieldsTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "http://"+ln.Addr().String()+"/", nil)
+			req.Header.Set("X-Grande", strings.Repeat("a", tt.size))
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+// Run atende até o contexto ser cancelado e então encerra sem erro
+func TestRunStopsOnCancel(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	l.Close()
+
+	t.Setenv("PORT", port)
+	t.Setenv("TRACE_EXPORTER", "file")
+	t.Setenv("TRACE_FILE_PATH", t.TempDir()+"/traces.jsonl")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- Run(ctx, cfg) }()
+
+	// Espera o /health responder antes de cancelar
+	healthy := false
+	for deadline := time.Now().Add(5 * time.Second); !healthy && time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if resp, err := http.Get("http://localhost:" + port + "/health"); err == nil {
+			resp.Body.Close()
+			healthy = resp.StatusCode == http.StatusOK
+		}
+	}
+	if !healthy {
+		t.Fatal("Run não respondeu /health")
+	}
+
+	cancel()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("Run = %v, want nil", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run não retornou após o cancelamento")
+	}
+}
@@ -0,0 +1,121 @@
+// Package serviceb implementa um cliente tipado para o Serviço B (temperatura por CEP),
+// instrumentado com otelhttp e com retentativas limitadas pelo orçamento da requisição.
+package serviceb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/types"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Resposta de temperatura do Serviço B
+type TemperatureResponse = types.TemperatureResponse
+
+// Erros retornados pelo Serviço B para CEPs inválidos (422) ou inexistentes (404)
+var (
+	ErrInvalidZipcode  = errors.New("invalid zipcode")
+	ErrZipcodeNotFound = errors.New("can not find zipcode")
+)
+
+// Erro para status inesperado do Serviço B. RetryAfter guarda o header Retry-After da
+// resposta (ex.: 429), vazio se ausente
+type StatusError struct {
+	StatusCode int
+	RetryAfter string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("erro no serviço B: status %d", e.StatusCode)
+}
+
+// Cliente do Serviço B
+type Client struct {
+	baseURL     string
+	httpClient  *http.Client
+	maxAttempts int
+	retryBudget int
+}
+
+// Opção de configuração do cliente
+type Option func(*Client)
+
+// Usa o http.Client informado em vez do padrão instrumentado com otelhttp
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// Define o máximo de tentativas por chamada e o orçamento de retentativas por requisição
+func WithRetry(maxAttempts, budget int) Option {
+	return func(c *Client) {
+		c.maxAttempts = maxAttempts
+		c.retryBudget = budget
+	}
+}
+
+// Cria um cliente para o Serviço B em baseURL (ex.: http://service-b:8080)
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+		maxAttempts: 3,
+		retryBudget: 2,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Consulta a temperatura do CEP. Retorna ErrInvalidZipcode, ErrZipcodeNotFound ou
+// *StatusError conforme a resposta do Serviço B
+func (c *Client) GetTemperature(ctx context.Context, cep string) (*TemperatureResponse, error) {
+	span := trace.SpanFromContext(ctx)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/"+url.PathEscape(cep), nil)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar request: %w", err)
+	}
+
+	// Faz a chamada HTTP, com retentativas limitadas pelo orçamento da requisição
+	resp, err := c.doWithRetry(ctx, req, newRetryBudget(c.retryBudget))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao chamar serviço B: %w", err)
+	}
+	defer resp.Body.Close()
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+
+	// Trata diferentes códigos de status
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var tempResp TemperatureResponse
+		if err := json.NewDecoder(resp.Body).Decode(&tempResp); err != nil {
+			return nil, fmt.Errorf("erro ao decodificar resposta: %w", err)
+		}
+		return &tempResp, nil
+
+	case http.StatusUnprocessableEntity:
+		return nil, ErrInvalidZipcode
+
+	case http.StatusNotFound:
+		return nil, ErrZipcodeNotFound
+
+	default:
+		return nil, &StatusError{StatusCode: resp.StatusCode, RetryAfter: resp.Header.Get("Retry-After")}
+	}
+}
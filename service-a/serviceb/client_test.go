@@ -0,0 +1,105 @@
+package serviceb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// O CEP vai para o Serviço B como string, sem perder os zeros à esquerda
+func TestGetTemperatureKeepsLeadingZeros(t *testing.T) {
+	tests := []string{"01001000", "00100000", "00000001"}
+
+	for _, cep := range tests {
+		t.Run(cep, func(t *testing.T) {
+			var got string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.URL.Path
+				w.Write([]byte(`{"city":"São Paulo","temp_C":23.5,"temp_F":74.3,"temp_K":296.5}`))
+			}))
+			defer srv.Close()
+
+			c := NewClient(srv.URL+"/", WithHTTPClient(srv.Client()))
+			if _, err := c.GetTemperature(context.Background(), cep); err != nil {
+				t.Fatal(err)
+			}
+			if got != "/"+cep {
+				t.Errorf("path = %q, want %q", got, "/"+cep)
+			}
+		})
+	}
+}
+
+// Respostas do Serviço B simulado viram o resultado tipado ou o erro correspondente
+func TestGetTemperature(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int // status de cada chamada; a última se repete
+		body       string
+		wantTempC  float64
+		wantErr    error
+		wantStatus int // StatusError esperado
+		wantCalls  int32
+	}{
+		{"200", []int{200}, `{"city":"São Paulo","temp_C":23.5,"temp_F":74.3,"temp_K":296.5}`, 23.5, nil, 0, 1},
+		{"422", []int{422}, `{"message":"invalid zipcode"}`, 0, ErrInvalidZipcode, 0, 1},
+		{"404", []int{404}, `{"message":"can not find zipcode"}`, 0, ErrZipcodeNotFound, 0, 1},
+		{"503 e depois 200", []int{503, 200}, `{"city":"São Paulo","temp_C":23.5}`, 23.5, nil, 0, 2},
+		{"500 persistente", []int{500}, `{"message":"internal server error"}`, 0, nil, 500, 3},
+		{"400 sem retentativa", []int{400}, `{"message":"malformed zipcode"}`, 0, nil, 400, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(calls.Add(1))
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.statuses[min(n, len(tt.statuses))-1])
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			c := NewClient(srv.URL, WithHTTPClient(srv.Client()), WithRetry(3, 2))
+			resp, err := c.GetTemperature(context.Background(), "01001000")
+
+			switch {
+			case tt.wantStatus != 0:
+				var statusErr *StatusError
+				if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantStatus {
+					t.Errorf("err = %v, want StatusError %d", err, tt.wantStatus)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("err = %v, want %v", err, tt.wantErr)
+				}
+			default:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if float64(resp.TempC) != tt.wantTempC || resp.City != "São Paulo" {
+					t.Errorf("resp = %+v, want São Paulo a %.1f", resp, tt.wantTempC)
+				}
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("chamadas = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// Resposta 200 com corpo que não é o JSON esperado é erro, não uma temperatura zerada
+func TestGetTemperatureInvalidBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html>proxy</html>`))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, WithHTTPClient(srv.Client()))
+	if resp, err := c.GetTemperature(context.Background(), "01001000"); err == nil {
+		t.Errorf("GetTemperature = %+v, want erro de decodificação", resp)
+	}
+}
@@ -0,0 +1,125 @@
+package serviceb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Header com o tempo restante do prazo da requisição, em ms. Enviado a cada tentativa
+// quando ctx tem prazo, para que o Serviço B não ultrapasse o prazo do chamador
+const deadlineHeader = "X-Request-Deadline"
+
+func setDeadlineHeader(ctx context.Context, req *http.Request) {
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set(deadlineHeader, strconv.FormatInt(max(time.Until(deadline).Milliseconds(), 0), 10))
+	}
+}
+
+// Header com o orçamento de retentativas restante da requisição. É enviado ao
+// Serviço B, que consome retentativas dele e devolve o que sobrou na resposta,
+// limitando o total de retentativas em toda a cadeia de serviços
+const retryBudgetHeader = "X-Retry-Budget"
+
+// Orçamento de retentativas compartilhado pela requisição
+type retryBudget struct {
+	remaining atomic.Int64
+}
+
+func newRetryBudget(n int) *retryBudget {
+	b := &retryBudget{}
+	b.remaining.Store(int64(n))
+	return b
+}
+
+// Consome uma retentativa do orçamento; retorna false se esgotado
+func (b *retryBudget) take() bool {
+	for {
+		n := b.remaining.Load()
+		if n <= 0 {
+			return false
+		}
+		if b.remaining.CompareAndSwap(n, n-1) {
+			return true
+		}
+	}
+}
+
+func (b *retryBudget) left() int {
+	return int(b.remaining.Load())
+}
+
+// Atualiza o orçamento com o restante informado pelo Serviço B (nunca aumenta)
+func (b *retryBudget) syncFrom(resp *http.Response) {
+	v := resp.Header.Get(retryBudgetHeader)
+	if v == "" {
+		return
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return
+	}
+	for {
+		cur := b.remaining.Load()
+		if int64(n) >= cur || b.remaining.CompareAndSwap(cur, int64(n)) {
+			return
+		}
+	}
+}
+
+// Executa a requisição ao Serviço B com retentativas para falhas transitórias
+// (erro de rede ou 5xx), propagando o orçamento restante em cada tentativa
+func (c *Client) doWithRetry(ctx context.Context, req *http.Request, budget *retryBudget) (*http.Response, error) {
+	span := trace.SpanFromContext(ctx)
+
+	for attempt := 1; ; attempt++ {
+		attemptReq := req.Clone(ctx)
+		attemptReq.Header.Set(retryBudgetHeader, strconv.Itoa(budget.left()))
+		setDeadlineHeader(ctx, attemptReq)
+
+		resp, err := c.httpClient.Do(attemptReq)
+		if resp != nil {
+			budget.syncFrom(resp)
+		}
+		if !isRetryable(resp, err) || attempt >= c.maxAttempts || !budget.take() {
+			span.SetAttributes(attribute.Int("retry.attempts", attempt))
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		span.AddEvent("retry", trace.WithAttributes(
+			attribute.Int("retry.attempt", attempt),
+			attribute.Int("retry.budget_left", budget.left()),
+		))
+
+		select {
+		case <-time.After(retryBackoff(attempt)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Falhas transitórias: erro de rede (exceto cancelamento) ou 5xx do Serviço B
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+// Backoff exponencial entre tentativas: 100ms, 200ms, 400ms...
+func retryBackoff(attempt int) time.Duration {
+	return 100 * time.Millisecond << (attempt - 1)
+}
@@ -0,0 +1,155 @@
+package serviceb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		if got := retryBackoff(tt.attempt); got != tt.want {
+			t.Errorf("retryBackoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *http.Response
+		err  error
+		want bool
+	}{
+		{"erro de rede", nil, errors.New("connection refused"), true},
+		{"cancelada", nil, context.Canceled, false},
+		{"prazo", nil, context.DeadlineExceeded, false},
+		{"503", &http.Response{StatusCode: http.StatusServiceUnavailable}, nil, true},
+		{"422", &http.Response{StatusCode: http.StatusUnprocessableEntity}, nil, false},
+		{"429", &http.Response{StatusCode: http.StatusTooManyRequests}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.resp, tt.err); got != tt.want {
+				t.Errorf("isRetryable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryBudgetSyncFrom(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"sem header", "", 3},
+		{"reduz", "1", 1},
+		{"zera", "0", 0},
+		{"nunca aumenta", "10", 3},
+		{"inválido", "x", 3},
+		{"negativo", "-1", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newRetryBudget(3)
+			resp := &http.Response{Header: http.Header{}}
+			if tt.header != "" {
+				resp.Header.Set(retryBudgetHeader, tt.header)
+			}
+			b.syncFrom(resp)
+			if got := b.left(); got != tt.want {
+				t.Errorf("left = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// O orçamento devolvido pelo Serviço B limita as retentativas do cliente: com o orçamento
+// gasto lá, uma falha não é repetida aqui
+func TestClientRetryBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		remaining string // X-Retry-Budget da resposta do Serviço B
+		wantCalls int32
+	}{
+		{"orçamento intacto", "", 3},
+		{"orçamento parcial", "1", 2},
+		{"orçamento esgotado no Serviço B", "0", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			var sent []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				sent = append(sent, r.Header.Get(retryBudgetHeader))
+				if tt.remaining != "" {
+					w.Header().Set(retryBudgetHeader, tt.remaining)
+				}
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer srv.Close()
+
+			c := NewClient(srv.URL, WithHTTPClient(srv.Client()), WithRetry(3, 2))
+			_, err := c.GetTemperature(context.Background(), "01001000")
+
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("err = %v, want StatusError 503", err)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("chamadas = %d, want %d", got, tt.wantCalls)
+			}
+			if sent[0] != "2" {
+				t.Errorf("X-Retry-Budget enviado = %q, want 2", sent[0])
+			}
+		})
+	}
+}
+
+// Com prazo no contexto, cada chamada leva o tempo restante em X-Request-Deadline; sem
+// prazo, o header não é enviado
+func TestClientDeadlineHeader(t *testing.T) {
+	var sent []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = append(sent, r.Header.Get(deadlineHeader))
+		w.Write([]byte(`{"city":"São Paulo","temp_C":23.5,"temp_F":74.3,"temp_K":296.5}`))
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL, WithHTTPClient(srv.Client()))
+
+	if _, err := c.GetTemperature(context.Background(), "01001000"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := c.GetTemperature(ctx, "01001000"); err != nil {
+		t.Fatal(err)
+	}
+
+	if sent[0] != "" {
+		t.Errorf("sem prazo: %s = %q, want vazio", deadlineHeader, sent[0])
+	}
+	ms, err := strconv.Atoi(sent[1])
+	if err != nil || ms <= 0 || ms > 2000 {
+		t.Errorf("com prazo: %s = %q, want restante em ms até 2000", deadlineHeader, sent[1])
+	}
+}
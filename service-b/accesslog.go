@@ -0,0 +1,32 @@
+package main
+
+import (
+	"log"
+	"math/rand"
+	"net/http"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/gorilla/mux"
+)
+
+// Log de acesso amostrado: respostas de erro (status >= 400) são sempre registradas e
+// as demais com probabilidade rate (ACCESS_LOG_SAMPLE_RATE), reduzindo o custo de log
+// em alto volume sem perder as falhas
+func accessLogMiddleware(rate float64) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// httpsnoop preserva as interfaces do ResponseWriter (ex.: Flusher do SSE)
+			m := httpsnoop.CaptureMetrics(next, w, r)
+			if shouldLogAccess(m.Code, rate) {
+				log.Printf("%s %s %d %dB %s", r.Method, r.URL.Path, m.Code, m.Written, m.Duration)
+			}
+		})
+	}
+}
+
+func shouldLogAccess(status int, rate float64) bool {
+	if status >= http.StatusBadRequest {
+		return true
+	}
+	return rate >= 1 || (rate > 0 && rand.Float64() < rate)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Em muitas requisições a fração registrada fica próxima de ACCESS_LOG_SAMPLE_RATE, e
+// respostas de erro são sempre registradas
+func TestShouldLogAccess(t *testing.T) {
+	const n = 10000
+
+	tests := []struct {
+		name   string
+		status int
+		rate   float64
+		lo, hi int
+	}{
+		{"sucesso, rate 1", http.StatusOK, 1, n, n},
+		{"sucesso, rate 0", http.StatusOK, 0, 0, 0},
+		{"sucesso, rate 0.1", http.StatusOK, 0.1, 800, 1200},
+		{"sucesso, rate 0.5", http.StatusOK, 0.5, 4500, 5500},
+		{"redirecionamento, rate 0", http.StatusFound, 0, 0, 0},
+		{"404, rate 0", http.StatusNotFound, 0, n, n},
+		{"500, rate 0.1", http.StatusInternalServerError, 0.1, n, n},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logged := 0
+			for i := 0; i < n; i++ {
+				if shouldLogAccess(tt.status, tt.rate) {
+					logged++
+				}
+			}
+			if logged < tt.lo || logged > tt.hi {
+				t.Errorf("registradas %d de %d, want entre %d e %d", logged, n, tt.lo, tt.hi)
+			}
+		})
+	}
+}
+
+// Com rate 0 o middleware só registra as respostas de erro, com método, caminho e status
+func TestAccessLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+
+	handler := accessLogMiddleware(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/123" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	for _, path := range []string{"/01001000", "/123", "/01001000"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "GET /123 422 2B") {
+		t.Errorf("log = %q, want só a linha do 422", buf.String())
+	}
+}
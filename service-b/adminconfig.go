@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+// Valor exibido no lugar dos segredos
+const redacted = "REDACTED"
+
+// Cópia da configuração sem os segredos (chaves da WeatherAPI, token de admin e senha
+// do Redis). Segredos vazios continuam vazios, para mostrar que não foram configurados
+func redactConfig(cfg Config) Config {
+	if cfg.WeatherAPIKey != "" {
+		cfg.WeatherAPIKey = redacted
+	}
+	// Os pesos não são segredo e ficam visíveis
+	keys := make([]weightedAPIKey, len(cfg.WeatherAPIKeys))
+	for i, k := range cfg.WeatherAPIKeys {
+		keys[i] = weightedAPIKey{Key: redacted, Weight: k.Weight}
+	}
+	cfg.WeatherAPIKeys = keys
+	if cfg.AdminToken != "" {
+		cfg.AdminToken = redacted
+	}
+	// A senha pode vir na própria URL do Redis
+	if u, err := url.Parse(cfg.RedisURL); err == nil && u.User != nil {
+		u.User = url.User(redacted)
+		cfg.RedisURL = u.String()
+	}
+	return cfg
+}
+
+// GET /admin/config: configuração efetiva, com os segredos ocultos, para conferir as
+// variáveis de ambiente de um deploy. Exige "Authorization: Bearer <ADMIN_TOKEN>"
+func adminConfigHandler(token string, cfg Config) http.HandlerFunc {
+	safe := redactConfig(cfg)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !adminAuthorized(w, r, token) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(safe)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Chaves da WeatherAPI, trocáveis em tempo de execução: com WEATHER_API_KEY_FILE,
+// um SIGHUP relê o arquivo (ex.: secret montado) sem reiniciar o serviço. Com várias
+// chaves (WEATHER_API_KEYS), cada tentativa usa a próxima do round-robin ponderado,
+// pulando por cooldown as que receberam 429
+type apiKeyStore struct {
+	mu           sync.Mutex
+	keys         []weightedAPIKey
+	current      []int // peso acumulado de cada chave no round-robin ponderado
+	limitedUntil map[string]time.Time
+	cooldown     time.Duration
+	file         string
+}
+
+// Chave de WEATHER_API_KEYS com o seu peso no round-robin ("chave:peso", default 1),
+// ex.: a chave de um plano com o triplo da cota recebe peso 3
+type weightedAPIKey struct {
+	Key    string
+	Weight int
+}
+
+// Primeira chave configurada; vazio se nenhuma
+func (s *apiKeyStore) Get() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.keys) == 0 {
+		return ""
+	}
+	return s.keys[0].Key
+}
+
+// Todas as chaves configuradas, para ocultá-las em logs e spans
+func (s *apiKeyStore) All() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, len(s.keys))
+	for i, k := range s.keys {
+		keys[i] = k.Key
+	}
+	return keys
+}
+
+func (s *apiKeyStore) Set(key string) {
+	s.SetKeys([]weightedAPIKey{{Key: key, Weight: 1}})
+}
+
+func (s *apiKeyStore) SetKeys(keys []weightedAPIKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = s.keys[:0]
+	for _, k := range keys {
+		if k.Key != "" {
+			s.keys = append(s.keys, weightedAPIKey{Key: k.Key, Weight: max(k.Weight, 1)})
+		}
+	}
+	s.current = make([]int, len(s.keys))
+	s.limitedUntil = make(map[string]time.Time)
+}
+
+// Próxima chave do round-robin ponderado e sua posição, pulando as que estão em
+// cooldown. Se todas estiverem em cooldown, escolhe entre todas mesmo assim
+func (s *apiKeyStore) Next() (string, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.keys) == 0 {
+		return "", -1
+	}
+
+	now := time.Now()
+	i := s.pick(func(k weightedAPIKey) bool { return now.After(s.limitedUntil[k.Key]) })
+	if i < 0 {
+		i = s.pick(func(weightedAPIKey) bool { return true })
+	}
+	return s.keys[i].Key, i
+}
+
+// Smooth weighted round-robin (o do nginx) entre as chaves elegíveis: cada uma soma o
+// seu peso ao acumulado, a de maior acumulado é escolhida e perde o total dos pesos.
+// Com pesos 3 e 1 a sequência é a, a, b, a, sem rajadas na mesma chave. Chamado com
+// s.mu travado; -1 se nenhuma for elegível
+func (s *apiKeyStore) pick(eligible func(weightedAPIKey) bool) int {
+	best, total := -1, 0
+	for i, k := range s.keys {
+		if !eligible(k) {
+			continue
+		}
+		s.current[i] += k.Weight
+		total += k.Weight
+		if best < 0 || s.current[i] > s.current[best] {
+			best = i
+		}
+	}
+	if best >= 0 {
+		s.current[best] -= total
+	}
+	return best
+}
+
+// Tira a chave do round-robin pelo cooldown após um 429
+func (s *apiKeyStore) markRateLimited(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.limitedUntil != nil {
+		s.limitedUntil[key] = time.Now().Add(s.cooldown)
+	}
+}
+
+// Hook de cada tentativa à WeatherAPI (ver withAttemptHook): escolhe a chave da tentativa
+// e, se ela receber 429, a tira do round-robin já nesse primeiro 429, de modo que a
+// retentativa sai com outra chave. O índice da chave usada vai no span
+func (s *apiKeyStore) attemptHook(span trace.Span) attemptHook {
+	return func(req *http.Request) func(*http.Response) {
+		key, index := s.Next()
+		q := req.URL.Query()
+		q.Set("key", key)
+		req.URL.RawQuery = q.Encode()
+		span.SetAttributes(attribute.Int("weather.api_key_index", index))
+
+		return func(resp *http.Response) {
+			if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+				s.markRateLimited(key)
+				span.AddEvent("weather_api_key_rate_limited", trace.WithAttributes(attribute.Int("weather.api_key_index", index)))
+			}
+		}
+	}
+}
+
+// Relê a chave do arquivo configurado; mantém a chave atual em caso de erro
+func (s *apiKeyStore) reload() error {
+	if s.file == "" {
+		return errors.New("WEATHER_API_KEY_FILE não configurado")
+	}
+	key, err := readAPIKeyFile(s.file)
+	if err != nil {
+		return err
+	}
+	s.Set(key)
+	return nil
+}
+
+func readAPIKeyFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler chave da WeatherAPI: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", fmt.Errorf("arquivo de chave da WeatherAPI vazio: %s", path)
+	}
+	return key, nil
+}
+
+// Recarrega a chave a cada SIGHUP até ctx ser cancelado
+func watchAPIKeyReload(ctx context.Context, store *apiKeyStore) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				if err := store.reload(); err != nil {
+					log.Printf("SIGHUP: chave da WeatherAPI mantida: %v", err)
+					continue
+				}
+				log.Printf("SIGHUP: chave da WeatherAPI recarregada de %s", store.file)
+			}
+		}
+	}()
+}
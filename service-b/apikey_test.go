@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestAPIKeyStoreNext(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []weightedAPIKey
+		limited []string
+		want    string // chaves escolhidas em sequência
+	}{
+		{"sem chaves", nil, nil, ""},
+		{"uma chave", []weightedAPIKey{{"a", 1}}, nil, "aaaa"},
+		{"round-robin", []weightedAPIKey{{"a", 1}, {"b", 1}, {"c", 1}}, nil, "abcabc"},
+		{"pesos 3 e 1", []weightedAPIKey{{"a", 3}, {"b", 1}}, nil, "aabaaaba"},
+		{"pesos 2, 1 e 1", []weightedAPIKey{{"a", 2}, {"b", 1}, {"c", 1}}, nil, "abcaabca"},
+		{"peso zero vale 1", []weightedAPIKey{{"a", 0}, {"b", 1}}, nil, "abab"},
+		{"chave vazia ignorada", []weightedAPIKey{{"", 5}, {"a", 1}}, nil, "aa"},
+		{"pula a limitada", []weightedAPIKey{{"a", 1}, {"b", 1}, {"c", 1}}, []string{"b"}, "acac"},
+		{"pula a limitada com peso", []weightedAPIKey{{"a", 3}, {"b", 1}}, []string{"a"}, "bbb"},
+		{"todas limitadas", []weightedAPIKey{{"a", 1}, {"b", 1}}, []string{"a", "b"}, "abab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &apiKeyStore{cooldown: time.Minute}
+			s.SetKeys(tt.keys)
+			for _, k := range tt.limited {
+				s.markRateLimited(k)
+			}
+
+			var got strings.Builder
+			for range tt.want {
+				key, i := s.Next()
+				if i >= 0 && s.keys[i].Key != key {
+					t.Fatalf("Next = %q, %d: índice não corresponde à chave", key, i)
+				}
+				got.WriteString(key)
+			}
+			if got.String() != tt.want {
+				t.Errorf("sequência = %q, want %q", got.String(), tt.want)
+			}
+			if tt.want == "" {
+				if key, i := s.Next(); key != "" || i != -1 {
+					t.Errorf("Next = %q, %d, want \"\", -1", key, i)
+				}
+			}
+		})
+	}
+}
+
+func TestAPIKeyStoreCooldownExpires(t *testing.T) {
+	s := &apiKeyStore{cooldown: 20 * time.Millisecond}
+	s.SetKeys([]weightedAPIKey{{"a", 1}, {"b", 1}})
+	s.markRateLimited("a")
+
+	for i := 0; i < 2; i++ {
+		if key, _ := s.Next(); key != "b" {
+			t.Fatalf("Next = %q durante o cooldown, want b", key)
+		}
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		key, _ := s.Next()
+		seen[key] = true
+	}
+	if !seen["a"] {
+		t.Error("a não voltou ao round-robin após o cooldown")
+	}
+}
+
+func TestWeightedKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []weightedAPIKey
+		wantErr bool
+	}{
+		{"vazio", "", nil, false},
+		{"sem peso", "a,b", []weightedAPIKey{{"a", 1}, {"b", 1}}, false},
+		{"com peso", "a:3, b", []weightedAPIKey{{"a", 3}, {"b", 1}}, false},
+		{"peso zero", "a:0", nil, true},
+		{"peso negativo", "a:-1", nil, true},
+		{"peso inválido", "a:x", nil, true},
+		{"chave vazia", ":2", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WEATHER_API_KEYS", tt.value)
+			p := &envParser{}
+			got := p.weightedKeys("WEATHER_API_KEYS")
+			if (p.Err() != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", p.Err(), tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("weightedKeys = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("weightedKeys[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRedactConfigKeepsKeyWeights(t *testing.T) {
+	cfg := redactConfig(Config{WeatherAPIKeys: []weightedAPIKey{{"secret", 3}}})
+	if got := cfg.WeatherAPIKeys[0]; got.Key != redacted || got.Weight != 3 {
+		t.Errorf("WeatherAPIKeys[0] = %v, want {%s 3}", got, redacted)
+	}
+}
+
+// A retentativa de um 429 sai com a próxima chave, e a chave que recebeu o 429 fica
+// fora do round-robin já a partir desse primeiro 429
+func TestAttemptHookRotatesKeyOn429(t *testing.T) {
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("key")
+		keys = append(keys, key)
+		if key == "limited" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	defer func(c *http.Client, attempts int, afterMax time.Duration) {
+		httpClient, retryMaxAttempts, retryAfterMax = c, attempts, afterMax
+	}(httpClient, retryMaxAttempts, retryAfterMax)
+	httpClient = srv.Client()
+	retryMaxAttempts = 3
+	retryAfterMax = time.Second
+
+	store := &apiKeyStore{cooldown: time.Minute}
+	store.SetKeys([]weightedAPIKey{{"limited", 1}, {"ok", 1}})
+
+	ctx := context.WithValue(context.Background(), retryBudgetKey{}, newRetryBudget(5))
+	ctx = withAttemptHook(ctx, store.attemptHook(trace.SpanFromContext(ctx)))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/current.json?q=x", nil)
+
+	resp, err := doWithRetry(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if strings.Join(keys, ",") != "limited,ok" {
+		t.Errorf("chaves usadas = %v, want [limited ok]", keys)
+	}
+
+	// limited está em cooldown: as próximas consultas só usam ok
+	for i := 0; i < 3; i++ {
+		if key, _ := store.Next(); key != "ok" {
+			t.Errorf("Next = %q, want ok", key)
+		}
+	}
+	if req.URL.Query().Get("key") != "" {
+		t.Error("a requisição original não deve ser alterada")
+	}
+}
+
+// Um SIGHUP relê WEATHER_API_KEY_FILE e as consultas seguintes já saem com a chave nova;
+// um arquivo inválido mantém a chave anterior
+func TestAPIKeyReloadOnSIGHUP(t *testing.T) {
+	var mu sync.Mutex
+	var usedKey string
+	withMockUpstreams(t, mockViaCEP(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		usedKey = r.URL.Query().Get("key")
+		mu.Unlock()
+		mockWeatherAPI(23.5).ServeHTTP(w, r)
+	}))
+
+	file := filepath.Join(t.TempDir(), "weather-api-key")
+	writeKey := func(key string) {
+		if err := os.WriteFile(file, []byte(key+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeKey("chave-antiga")
+
+	defer func(s *apiKeyStore) { weatherAPIKey = s }(weatherAPIKey)
+	weatherAPIKey = &apiKeyStore{file: file}
+	if err := weatherAPIKey.reload(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watchAPIKeyReload(ctx, weatherAPIKey)
+
+	handler := newWeatherRouter()
+	lookup := func() string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return usedKey
+	}
+	// Envia SIGHUP e espera a chave do store mudar para want
+	hup := func(want string) {
+		t.Helper()
+		if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for weatherAPIKey.Get() != want && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	if got := lookup(); got != "chave-antiga" {
+		t.Fatalf("chave antes do SIGHUP = %q, want chave-antiga", got)
+	}
+
+	writeKey("chave-nova")
+	hup("chave-nova")
+	if got := lookup(); got != "chave-nova" {
+		t.Errorf("chave após o SIGHUP = %q, want chave-nova", got)
+	}
+
+	// Arquivo vazio: o reload falha e a chave anterior continua em uso
+	writeKey("")
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := lookup(); got != "chave-nova" {
+		t.Errorf("chave após SIGHUP com arquivo vazio = %q, want chave-nova", got)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Membro do baggage com a cidade resolvida pelo CEP
+const cityBaggageKey = "city"
+
+// Adiciona a cidade ao baggage do contexto, para que os spans filhos a recebam
+// como atributo (ver baggageSpanProcessor)
+func withCityBaggage(ctx context.Context, city string) context.Context {
+	if city == "" {
+		return ctx
+	}
+	member, err := baggage.NewMemberRaw(cityBaggageKey, city)
+	if err != nil {
+		logf(ctx, "Erro ao adicionar cidade ao baggage: %v", err)
+		return ctx
+	}
+	bag, err := baggage.FromContext(ctx).SetMember(member)
+	if err != nil {
+		logf(ctx, "Erro ao adicionar cidade ao baggage: %v", err)
+		return ctx
+	}
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+// Copia membros do baggage como atributos de cada span iniciado, evitando que cada
+// span precise repetir o mesmo atributo
+type baggageSpanProcessor struct {
+	keys []string
+}
+
+func (p baggageSpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	bag := baggage.FromContext(parent)
+	for _, key := range p.keys {
+		if m := bag.Member(key); m.Key() != "" {
+			s.SetAttributes(attribute.String(key, m.Value()))
+		}
+	}
+}
+
+func (baggageSpanProcessor) OnEnd(sdktrace.ReadOnlySpan)      {}
+func (baggageSpanProcessor) Shutdown(context.Context) error   { return nil }
+func (baggageSpanProcessor) ForceFlush(context.Context) error { return nil }
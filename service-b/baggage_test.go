@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWithCityBaggage(t *testing.T) {
+	ctx := withCityBaggage(context.Background(), "São Paulo")
+	if got := baggage.FromContext(ctx).Member(cityBaggageKey).Value(); got != "São Paulo" {
+		t.Errorf("city no baggage = %q, want São Paulo", got)
+	}
+	if ctx := withCityBaggage(context.Background(), ""); baggage.FromContext(ctx).Len() != 0 {
+		t.Error("cidade vazia não deve entrar no baggage")
+	}
+}
+
+// A cidade resolvida pelo CEP chega como atributo aos spans iniciados depois dela (a
+// chamada à WeatherAPI), mas não aos iniciados antes
+func TestCityBaggageOnWeatherSpan(t *testing.T) {
+	withMockUpstreams(t, mockViaCEP(), mockWeatherAPI(23.5))
+
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(baggageSpanProcessor{keys: []string{cityBaggageKey}}),
+		sdktrace.WithSpanProcessor(sr),
+	)
+	defer tp.Shutdown(context.Background())
+	defer func(prev trace.Tracer) { tracer = prev }(tracer)
+	tracer = tp.Tracer("service-b")
+
+	rec := httptest.NewRecorder()
+	newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	if got := spanAttr(endedSpan(t, sr, "get_weather_info"), cityBaggageKey).AsString(); got != mockCEP.Localidade {
+		t.Errorf("city em get_weather_info = %q, want %q", got, mockCEP.Localidade)
+	}
+	if got := spanAttr(endedSpan(t, sr, "weather_handler"), cityBaggageKey).AsString(); got != "" {
+		t.Errorf("city em weather_handler = %q, want ausente", got)
+	}
+}
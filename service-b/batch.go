@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Resultado de um CEP dentro do batch
+type BatchItemResult struct {
+	CEP    string               `json:"cep"`
+	Status int                  `json:"status"`
+	Result *TemperatureResponse `json:"result,omitempty"`
+	Error  string               `json:"error,omitempty"`
+}
+
+// Handler do batch: recebe um array JSON de CEPs e responde um resultado por CEP, na
+// mesma ordem da entrada (ou como SSE, com Accept: text/event-stream). Itens inválidos
+// não invalidam o batch: cada item tem o seu próprio status. Um array vazio responde 204
+// (ou 400, via BATCH_EMPTY_STATUS), um batch acima de BATCH_MAX_ITEMS responde 400 e um
+// corpo acima de BATCH_MAX_BODY_BYTES responde 413
+func batchHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx, span := tracer.Start(ctx, "batch_handler")
+	defer span.End()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var ceps []string
+	r.Body = http.MaxBytesReader(w, r.Body, int64(batchMaxBodyBytes))
+	if err := json.NewDecoder(r.Body).Decode(&ceps); err != nil {
+		span.RecordError(err)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			span.SetAttributes(attribute.String("error", "body_too_large"))
+			writeError(w, span, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body too large: max %d bytes", batchMaxBodyBytes))
+			return
+		}
+		span.SetAttributes(attribute.String("error", "invalid_json"))
+		writeError(w, span, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	span.SetAttributes(attribute.Int("batch.size", len(ceps)))
+
+	if len(ceps) == 0 {
+		if batchEmptyStatus == http.StatusBadRequest {
+			writeError(w, span, http.StatusBadRequest, "empty batch")
+			return
+		}
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if len(ceps) > batchMaxItems {
+		writeError(w, span, http.StatusBadRequest, fmt.Sprintf("batch too large: max %d items", batchMaxItems))
+		return
+	}
+
+	// Prazo total do batch: itens não concluídos até lá retornam 504 individualmente
+	batchCtx, cancel := context.WithTimeout(ctx, batchTimeout)
+	defer cancel()
+
+	// País dos códigos postais do batch (?country=, default: BR)
+	country := r.URL.Query().Get("country")
+
+	// Accept: text/event-stream envia cada resultado assim que concluído
+	if acceptsEventStream(r.Header.Get("Accept")) {
+		streamBatch(batchCtx, w, country, ceps)
+	} else {
+		results := make([]BatchItemResult, len(ceps))
+		for i, cep := range ceps {
+			results[i] = deadlineExceededItem(cep)
+		}
+		resolveBatch(batchCtx, country, ceps, func(i int, result BatchItemResult) {
+			results[i] = result
+		})
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(results)
+	}
+
+	if batchCtx.Err() != nil {
+		span.SetAttributes(attribute.Bool("batch.deadline_exceeded", true))
+	}
+}
+
+// Indica se o Accept lista text/event-stream com q > 0 (ex.: "text/event-stream;
+// charset=utf-8" ou "application/json;q=0.5, text/event-stream"). Curingas como */*
+// não contam: sem pedido explícito, a resposta é o array JSON
+func acceptsEventStream(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil || mediaType != "text/event-stream" {
+			continue
+		}
+		if v, ok := params["q"]; ok {
+			if q, err := strconv.ParseFloat(v, 64); err != nil || q <= 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Resultado de um item não concluído dentro do prazo do batch
+func deadlineExceededItem(cep string) BatchItemResult {
+	return BatchItemResult{CEP: cep, Status: http.StatusGatewayTimeout, Error: "batch deadline exceeded"}
+}
+
+// Tratamento de CEPs repetidos no batch (BATCH_DUPLICATES)
+const (
+	batchDuplicatesDedupe    = "dedupe"     // uma consulta por CEP distinto
+	batchDuplicatesFetchEach = "fetch_each" // uma consulta por item
+)
+
+var batchDuplicates = batchDuplicatesDedupe
+
+// Resolve até batchConcurrency itens em paralelo, chamando done (de várias goroutines,
+// com índices distintos) para cada item concluído. Com BATCH_DUPLICATES=dedupe, CEPs
+// repetidos são consultados uma vez e o resultado é repassado a cada ocorrência. Itens
+// não iniciados até o fim do prazo de ctx não geram chamada
+func resolveBatch(ctx context.Context, country string, ceps []string, done func(i int, result BatchItemResult)) {
+	groups := batchGroups(ceps)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("batch.unique", len(groups)))
+
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+dispatch:
+	for _, indexes := range groups {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break dispatch
+		}
+
+		wg.Add(1)
+		go func(indexes []int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			result := resolveBatchItem(ctx, country, indexes, ceps[indexes[0]])
+			for _, i := range indexes {
+				done(i, result)
+			}
+		}(indexes)
+	}
+	wg.Wait()
+}
+
+// Índices da entrada agrupados por CEP, na ordem da primeira ocorrência. Sem dedupe,
+// cada item é o seu próprio grupo
+func batchGroups(ceps []string) [][]int {
+	groups := make([][]int, 0, len(ceps))
+	if batchDuplicates != batchDuplicatesDedupe {
+		for i := range ceps {
+			groups = append(groups, []int{i})
+		}
+		return groups
+	}
+
+	pos := make(map[string]int, len(ceps))
+	for i, cep := range ceps {
+		if g, ok := pos[cep]; ok {
+			groups[g] = append(groups[g], i)
+			continue
+		}
+		pos[cep] = len(groups)
+		groups = append(groups, []int{i})
+	}
+	return groups
+}
+
+// Evento SSE do batch: o resultado com a posição do CEP na entrada
+type BatchStreamEvent struct {
+	Index int `json:"index"`
+	BatchItemResult
+}
+
+// Envia os resultados como Server-Sent Events ("result") na ordem de conclusão, seguidos
+// dos itens que estouraram o prazo e de um evento "done". O span do batch fica aberto
+// até o último evento
+func streamBatch(ctx context.Context, w http.ResponseWriter, country string, ceps []string) {
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	events := make(chan BatchStreamEvent, len(ceps))
+	go func() {
+		resolveBatch(ctx, country, ceps, func(i int, result BatchItemResult) {
+			events <- BatchStreamEvent{Index: i, BatchItemResult: result}
+		})
+		close(events)
+	}()
+
+	sent := make([]bool, len(ceps))
+	for ev := range events {
+		sent[ev.Index] = true
+		writeSSE(w, rc, "result", ev)
+	}
+	for i, cep := range ceps {
+		if !sent[i] {
+			writeSSE(w, rc, "result", BatchStreamEvent{Index: i, BatchItemResult: deadlineExceededItem(cep)})
+		}
+	}
+	writeSSE(w, rc, "done", map[string]int{"count": len(ceps)})
+}
+
+func writeSSE(w http.ResponseWriter, rc *http.ResponseController, event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Erro ao codificar evento %s: %v", event, err)
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	rc.Flush()
+}
+
+// Resolve um CEP do batch no seu próprio span, compartilhado pelas ocorrências em indexes
+func resolveBatchItem(ctx context.Context, country string, indexes []int, cep string) BatchItemResult {
+	ctx, span := tracer.Start(ctx, "batch_item")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("batch.index", indexes[0]),
+		attribute.Int("batch.occurrences", len(indexes)),
+	)
+
+	result, lookupErr := lookupTemperature(ctx, country, cep, false)
+	if lookupErr != nil {
+		// Falha causada pelo fim do prazo do batch, não pelo CEP
+		if ctx.Err() != nil {
+			lookupErr = &lookupError{Status: http.StatusGatewayTimeout, Message: "batch deadline exceeded"}
+		}
+		recordErrorStatus(span, lookupErr.Status, lookupErr.Message, lookupErr.Upstream)
+		return BatchItemResult{CEP: cep, Status: lookupErr.Status, Error: lookupErr.Message}
+	}
+
+	return BatchItemResult{CEP: cep, Status: http.StatusOK, Result: &result.Response}
+}
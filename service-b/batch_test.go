@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Configuração do batch para os testes, restaurada ao fim de cada um
+func withBatchConfig(tb testing.TB) {
+	tb.Helper()
+	prevEmpty, prevItems, prevBody := batchEmptyStatus, batchMaxItems, batchMaxBodyBytes
+	prevConcurrency, prevTimeout, prevDuplicates := batchConcurrency, batchTimeout, batchDuplicates
+	tb.Cleanup(func() {
+		batchEmptyStatus, batchMaxItems, batchMaxBodyBytes = prevEmpty, prevItems, prevBody
+		batchConcurrency, batchTimeout, batchDuplicates = prevConcurrency, prevTimeout, prevDuplicates
+	})
+
+	batchEmptyStatus = http.StatusNoContent
+	batchMaxItems = 50
+	batchMaxBodyBytes = 64 << 10
+	batchConcurrency = 4
+	batchTimeout = 5 * time.Second
+	batchDuplicates = batchDuplicatesDedupe
+}
+
+// POST /batch com o corpo dado e o status esperado; devolve os resultados do array JSON
+func postBatch(t *testing.T, body string, wantStatus int) []BatchItemResult {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	batchHandler(rec, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body)))
+	if rec.Code != wantStatus {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, wantStatus, rec.Body)
+	}
+	if rec.Code != http.StatusOK {
+		return nil
+	}
+
+	var results []BatchItemResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatalf("resposta não é um array de resultados: %v (%s)", err, rec.Body)
+	}
+	return results
+}
+
+func TestAcceptsEventStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   bool
+	}{
+		{"vazio", "", false},
+		{"exato", "text/event-stream", true},
+		{"maiúsculas", "Text/Event-Stream", true},
+		{"com charset", "text/event-stream; charset=utf-8", true},
+		{"na lista", "application/json;q=0.5, text/event-stream", true},
+		{"com espaços", "  text/event-stream  ", true},
+		{"q positivo", "text/event-stream;q=0.1", true},
+		{"q zero", "text/event-stream;q=0", false},
+		{"q inválido", "text/event-stream;q=abc", false},
+		{"só json", "application/json", false},
+		{"curinga", "*/*", false},
+		{"curinga de text", "text/*", false},
+		{"malformado", "text/event-stream;;", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := acceptsEventStream(tt.accept); got != tt.want {
+				t.Errorf("acceptsEventStream(%q) = %v, want %v", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchHandlerBodyLimit(t *testing.T) {
+	defer func(n, max int) { batchMaxBodyBytes, batchMaxItems = n, max }(batchMaxBodyBytes, batchMaxItems)
+	batchMaxBodyBytes = 32
+	batchMaxItems = 50
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"acima do limite", `["01001000","01001000","01001000","01001000"]`, http.StatusRequestEntityTooLarge},
+		{"json inválido dentro do limite", `["01001000"`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			batchHandler(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestBatchGroups(t *testing.T) {
+	defer func(mode string) { batchDuplicates = mode }(batchDuplicates)
+
+	tests := []struct {
+		name string
+		mode string
+		ceps []string
+		want [][]int
+	}{
+		{"vazio", batchDuplicatesDedupe, nil, [][]int{}},
+		{"sem repetidos", batchDuplicatesDedupe, []string{"a", "b"}, [][]int{{0}, {1}}},
+		{"agrupa repetidos", batchDuplicatesDedupe, []string{"a", "b", "a", "a"}, [][]int{{0, 2, 3}, {1}}},
+		{"ordem da primeira ocorrência", batchDuplicatesDedupe, []string{"b", "a", "b"}, [][]int{{0, 2}, {1}}},
+		{"fetch_each", batchDuplicatesFetchEach, []string{"a", "b", "a"}, [][]int{{0}, {1}, {2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batchDuplicates = tt.mode
+			got := batchGroups(tt.ceps)
+			if !slices.EqualFunc(got, tt.want, slices.Equal[[]int]) {
+				t.Errorf("batchGroups(%v) = %v, want %v", tt.ceps, got, tt.want)
+			}
+		})
+	}
+}
+
+// Array vazio responde 204 sem corpo (ou 400 com BATCH_EMPTY_STATUS=400); cada item tem o
+// seu próprio status, na ordem da entrada, sem que um inválido derrube o batch
+func TestBatchHandlerItems(t *testing.T) {
+	withBatchConfig(t)
+	withMockUpstreams(t, mockViaCEP(), mockWeatherAPI(23.5))
+
+	t.Run("vazio", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		batchHandler(rec, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`[]`)))
+		if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 || rec.Header().Get("Content-Type") != "" {
+			t.Errorf("status = %d, corpo %q, Content-Type %q, want 204 sem corpo",
+				rec.Code, rec.Body, rec.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("vazio com BATCH_EMPTY_STATUS=400", func(t *testing.T) {
+		defer func(s int) { batchEmptyStatus = s }(batchEmptyStatus)
+		batchEmptyStatus = http.StatusBadRequest
+		postBatch(t, `[]`, http.StatusBadRequest)
+	})
+
+	t.Run("um item", func(t *testing.T) {
+		results := postBatch(t, `["01001000"]`, http.StatusOK)
+		if len(results) != 1 || results[0].Status != http.StatusOK || results[0].Result == nil {
+			t.Fatalf("results = %+v, want um resultado 200", results)
+		}
+		if results[0].Result.TempC != 23.5 {
+			t.Errorf("temp_C = %v, want 23.5", results[0].Result.TempC)
+		}
+	})
+
+	t.Run("válidos e inválidos", func(t *testing.T) {
+		results := postBatch(t, `["01001000","0100\u00001000","1234567","01001-000"]`, http.StatusOK)
+		want := []struct {
+			cep    string
+			status int
+		}{
+			{"01001000", http.StatusOK},
+			{"0100\x001000", http.StatusBadRequest},
+			{"1234567", http.StatusUnprocessableEntity},
+			{"01001-000", http.StatusOK},
+		}
+		if len(results) != len(want) {
+			t.Fatalf("len(results) = %d, want %d", len(results), len(want))
+		}
+		for i, w := range want {
+			got := results[i]
+			if got.CEP != w.cep || got.Status != w.status {
+				t.Errorf("results[%d] = %q %d, want %q %d", i, got.CEP, got.Status, w.cep, w.status)
+			}
+			if (got.Status == http.StatusOK) != (got.Result != nil) || (got.Status != http.StatusOK) != (got.Error != "") {
+				t.Errorf("results[%d]: result e error inconsistentes com o status: %+v", i, got)
+			}
+		}
+	})
+}
+
+// CEPs repetidos: com dedupe, uma chamada ao ViaCEP por CEP distinto; com fetch_each, uma
+// por item (em sequência, para que o coalesce não agrupe as simultâneas). Nos dois modos cada ocorrência tem o seu resultado, na posição da entrada
+func TestBatchHandlerDuplicates(t *testing.T) {
+	withBatchConfig(t)
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls[r.URL.Path]++
+		mu.Unlock()
+		mockViaCEP().ServeHTTP(w, r)
+	}), mockWeatherAPI(23.5))
+
+	input := []string{"01001000", "01310100", "01001000", "1234567", "01001000", "1234567"}
+	body, _ := json.Marshal(input)
+
+	tests := []struct {
+		name        string
+		mode        string
+		concurrency int
+		wantCalls   map[string]int
+	}{
+		{"dedupe", batchDuplicatesDedupe, 4, map[string]int{"/ws/01001000/json/": 1, "/ws/01310100/json/": 1}},
+		{"fetch_each", batchDuplicatesFetchEach, 1, map[string]int{"/ws/01001000/json/": 3, "/ws/01310100/json/": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batchDuplicates, batchConcurrency = tt.mode, tt.concurrency
+			clear(calls)
+
+			results := postBatch(t, string(body), http.StatusOK)
+			if len(results) != len(input) {
+				t.Fatalf("len(results) = %d, want %d", len(results), len(input))
+			}
+			for i, cep := range input {
+				wantStatus := http.StatusOK
+				if cep == "1234567" {
+					wantStatus = http.StatusUnprocessableEntity
+				}
+				if results[i].CEP != cep || results[i].Status != wantStatus {
+					t.Errorf("results[%d] = %q %d, want %q %d", i, results[i].CEP, results[i].Status, cep, wantStatus)
+				}
+				if wantStatus == http.StatusOK && (results[i].Result == nil || results[i].Result.TempC != 23.5) {
+					t.Errorf("results[%d].result = %+v, want temp_C 23.5", i, results[i].Result)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !maps.Equal(calls, tt.wantCalls) {
+				t.Errorf("chamadas ao ViaCEP = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// Acima de BATCH_MAX_ITEMS o batch inteiro é recusado com 400, sem consultar nenhum CEP
+func TestBatchHandlerTooManyItems(t *testing.T) {
+	withBatchConfig(t)
+	batchMaxItems = 3
+	calls := 0
+	withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		mockViaCEP().ServeHTTP(w, r)
+	}), mockWeatherAPI(23.5))
+
+	postBatch(t, `["01001000","01001000","01001000"]`, http.StatusOK)
+	calls = 0
+	postBatch(t, `["01001000","01310100","20040020","30130010"]`, http.StatusBadRequest)
+	if calls != 0 {
+		t.Errorf("chamadas ao ViaCEP = %d, want 0", calls)
+	}
+}
+
+// Com o prazo do batch esgotado no meio, os itens concluídos saem com o resultado e os
+// pendentes com 504 individual, na ordem da entrada
+func TestBatchHandlerPartialDeadline(t *testing.T) {
+	withBatchConfig(t)
+	batchTimeout = 200 * time.Millisecond
+	withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// O CEP lento só responde depois do prazo do batch
+		if strings.Contains(r.URL.Path, "99999999") {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+		mockViaCEP().ServeHTTP(w, r)
+	}), mockWeatherAPI(23.5))
+
+	start := time.Now()
+	results := postBatch(t, `["01001000","99999999","01310100"]`, http.StatusOK)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("batch levou %s, want próximo de BATCH_TIMEOUT", elapsed)
+	}
+
+	want := []int{http.StatusOK, http.StatusGatewayTimeout, http.StatusOK}
+	if len(results) != len(want) {
+		t.Fatalf("len(results) = %d, want %d", len(results), len(want))
+	}
+	for i, status := range want {
+		if results[i].Status != status {
+			t.Errorf("results[%d] (%s) = %d %q, want %d", i, results[i].CEP, results[i].Status, results[i].Error, status)
+		}
+	}
+	if results[1].Error != "batch deadline exceeded" {
+		t.Errorf("erro do item atrasado = %q, want batch deadline exceeded", results[1].Error)
+	}
+}
+
+// Com Accept: text/event-stream, cada resultado chega assim que concluído, antes dos
+// itens lentos, e o stream termina com "done" depois de um evento por item. O span do
+// batch só termina com o stream
+func TestBatchHandlerStream(t *testing.T) {
+	withBatchConfig(t)
+	release := make(chan struct{})
+	withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "99999999") {
+			<-release
+		}
+		mockViaCEP().ServeHTTP(w, r)
+	}), mockWeatherAPI(23.5))
+	// Libera o CEP lento também se o teste falhar antes, para o mock poder fechar
+	unblock := sync.OnceFunc(func() { close(release) })
+	t.Cleanup(unblock)
+	sr := withSpanRecorder(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(batchHandler))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`["99999999","01001000","01310100"]`))
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusOK || ct != "text/event-stream" {
+		t.Fatalf("status = %d, Content-Type = %q, want 200 text/event-stream", resp.StatusCode, ct)
+	}
+
+	type sseEvent struct{ name, data string }
+	events := make(chan sseEvent)
+	go func() {
+		defer close(events)
+		var ev sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				ev.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				ev.data = strings.TrimPrefix(line, "data: ")
+			case line == "":
+				events <- ev
+				ev = sseEvent{}
+			}
+		}
+	}()
+
+	seen := map[int]BatchItemResult{}
+	readResult := func() BatchStreamEvent {
+		t.Helper()
+		ev, ok := <-events
+		if !ok || ev.name != "result" {
+			t.Fatalf("evento = %+v (aberto %v), want result", ev, ok)
+		}
+		var result BatchStreamEvent
+		if err := json.Unmarshal([]byte(ev.data), &result); err != nil {
+			t.Fatalf("data inválido %q: %v", ev.data, err)
+		}
+		if _, dup := seen[result.Index]; dup {
+			t.Errorf("índice %d repetido", result.Index)
+		}
+		seen[result.Index] = result.BatchItemResult
+		return result
+	}
+
+	// Os dois CEPs rápidos chegam enquanto o lento ainda espera
+	for i := 0; i < 2; i++ {
+		if ev := readResult(); ev.Index == 0 {
+			t.Fatalf("CEP lento chegou antes de liberado: %+v", ev)
+		}
+	}
+	if slices.ContainsFunc(sr.Ended(), func(s sdktrace.ReadOnlySpan) bool { return s.Name() == "batch_handler" }) {
+		t.Error("span do batch terminou antes do fim do stream")
+	}
+
+	unblock()
+	if ev := readResult(); ev.Index != 0 || ev.CEP != "99999999" {
+		t.Errorf("último resultado = %+v, want o CEP lento no índice 0", ev)
+	}
+	done, ok := <-events
+	if !ok || done.name != "done" || done.data != `{"count":3}` {
+		t.Fatalf("evento final = %+v (aberto %v), want done com count 3", done, ok)
+	}
+	if extra, ok := <-events; ok {
+		t.Errorf("evento após done: %+v", extra)
+	}
+
+	for i := 0; i < 3; i++ {
+		if seen[i].Status != http.StatusOK || seen[i].Result == nil {
+			t.Errorf("resultado %d = %+v, want 200", i, seen[i])
+		}
+	}
+	endedSpan(t, sr, "batch_handler")
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name      string
+		steps     []string
+		wantState string
+	}{
+		{"abaixo do limite", []string{"falha", "permite"}, breakerClosed},
+		{"abre no limite", []string{"falha", "falha", "bloqueia"}, breakerOpen},
+		{"sucesso zera as falhas", []string{"falha", "sucesso", "falha", "permite"}, breakerClosed},
+		{"half-open após o cooldown", []string{"falha", "falha", "expira", "permite", "bloqueia"}, breakerHalfOpen},
+		{"teste bem-sucedido fecha", []string{"falha", "falha", "expira", "permite", "sucesso", "permite"}, breakerClosed},
+		{"teste com falha reabre", []string{"falha", "falha", "expira", "permite", "falha", "bloqueia"}, breakerOpen},
+		{"abort libera o teste", []string{"falha", "falha", "expira", "permite", "abort", "permite"}, breakerHalfOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreaker(2, time.Minute)
+			for i, step := range tt.steps {
+				switch step {
+				case "falha":
+					b.record(false)
+				case "sucesso":
+					b.record(true)
+				case "abort":
+					b.abort()
+				case "expira":
+					b.openedAt = time.Now().Add(-b.cooldown)
+				case "permite", "bloqueia":
+					if got := b.allow(); got != (step == "permite") {
+						t.Fatalf("passo %d: allow = %v, want %v", i, got, step == "permite")
+					}
+				}
+			}
+			if got := b.State(); got != tt.wantState {
+				t.Errorf("State = %q, want %q", got, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := newCircuitBreaker(0, time.Minute)
+	for i := 0; i < 3; i++ {
+		b.record(false)
+	}
+	if !b.allow() || b.State() != breakerClosed {
+		t.Errorf("breaker desabilitado: allow = %v, State = %q", b.allow(), b.State())
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Cache com expiração por TTL. A implementação vem de CACHE_BACKEND: memory (default,
+// por réplica) ou redis (compartilhado entre réplicas)
+type Cache[V any] interface {
+	// Retorna o valor se presente e não expirado
+	Get(ctx context.Context, key string) (V, bool)
+	Set(ctx context.Context, key string, value V)
+	Delete(ctx context.Context, key string)
+}
+
+// Backends de cache em CACHE_BACKEND
+const (
+	cacheBackendMemory = "memory"
+	cacheBackendRedis  = "redis"
+)
+
+// Backend ativo; com redis, redisClient é o cliente compartilhado pelos caches
+var (
+	cacheBackend = cacheBackendMemory
+	redisClient  redisCmdable
+)
+
+// Máximo de entradas de cada cache em memória (CACHE_MAX_ENTRIES). CEPs distintos são
+// ilimitados: sem o teto, uma varredura de CEPs válidos cresceria o mapa sem fim
+const defaultCacheMaxEntries = 10000
+
+var cacheMaxEntries = defaultCacheMaxEntries
+
+// Cria o cache nomeado (ex.: "cep", "weather") no backend ativo, com as operações
+// registradas como spans filhos
+func newCache[V any](name string, ttl time.Duration) Cache[V] {
+	// TTL zero desabilita o cache: nada a registrar
+	if ttl <= 0 {
+		return newTTLCache[V](ttl, 0)
+	}
+
+	var c Cache[V]
+	if cacheBackend == cacheBackendRedis {
+		c = newRedisCache[V](redisClient, name, ttl)
+	} else {
+		c = newTTLCache[V](ttl, cacheMaxEntries)
+	}
+	return tracedCache[V]{name: name, backend: cacheBackend, next: c}
+}
+
+// Cache em memória com expiração por TTL e no máximo maxEntries entradas. Cheio, o Set
+// descarta as expiradas e, se ainda faltar espaço, a usada há mais tempo (LRU). Com
+// maxEntries zero não há teto
+type ttlCache[V any] struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List // *cacheEntry[V], da mais recente para a mais antiga
+}
+
+type cacheEntry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+func newTTLCache[V any](ttl time.Duration, maxEntries int) *ttlCache[V] {
+	return &ttlCache[V]{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Retorna o valor se presente e não expirado. Com TTL zero o cache fica desabilitado
+func (c *ttlCache[V]) Get(_ context.Context, key string) (V, bool) {
+	var zero V
+	if c.ttl <= 0 {
+		return zero, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+	entry := elem.Value.(*cacheEntry[V])
+	if time.Now().After(entry.expiresAt) {
+		c.remove(elem)
+		return zero, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (c *ttlCache[V]) Set(_ context.Context, key string, value V) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry[V])
+		entry.value, entry.expiresAt = value, expiresAt
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	if c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.sweep()
+		for len(c.entries) >= c.maxEntries {
+			c.remove(c.lru.Back())
+		}
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry[V]{key: key, value: value, expiresAt: expiresAt})
+}
+
+func (c *ttlCache[V]) Delete(_ context.Context, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// Remove as entradas expiradas. Chamado com c.mu travado
+func (c *ttlCache[V]) sweep() {
+	now := time.Now()
+	for elem := c.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		if now.After(elem.Value.(*cacheEntry[V]).expiresAt) {
+			c.remove(elem)
+		}
+		elem = prev
+	}
+}
+
+// Chamado com c.mu travado
+func (c *ttlCache[V]) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry[V]).key)
+}
+
+// Registra cada operação do cache como span filho (cache.get, cache.set, cache.delete)
+type tracedCache[V any] struct {
+	name    string
+	backend string
+	next    Cache[V]
+}
+
+func (c tracedCache[V]) start(ctx context.Context, op, key string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "cache."+op, trace.WithAttributes(
+		attribute.String("cache.name", c.name),
+		attribute.String("cache.backend", c.backend),
+		attribute.String("cache.key", key),
+	))
+}
+
+func (c tracedCache[V]) Get(ctx context.Context, key string) (V, bool) {
+	ctx, span := c.start(ctx, "get", key)
+	defer span.End()
+
+	v, ok := c.next.Get(ctx, key)
+	span.SetAttributes(attribute.Bool("cache.hit", ok))
+	return v, ok
+}
+
+func (c tracedCache[V]) Set(ctx context.Context, key string, value V) {
+	ctx, span := c.start(ctx, "set", key)
+	defer span.End()
+
+	c.next.Set(ctx, key, value)
+}
+
+func (c tracedCache[V]) Delete(ctx context.Context, key string) {
+	ctx, span := c.start(ctx, "delete", key)
+	defer span.End()
+
+	c.next.Delete(ctx, key)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Comandos do Redis usados pelos caches
+type redisCmdable interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Set(ctx context.Context, key string, value any, expiration time.Duration) *redis.StatusCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+}
+
+// Conecta ao Redis de REDIS_URL (ex.: redis://localhost:6379/0). A conexão é verificada,
+// mas uma falha só é registrada: o cache indisponível funciona como miss
+func newRedisClient(ctx context.Context, redisURL string) (*redis.Client, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("REDIS_URL inválida: %w", err)
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		logf(ctx, "Redis em %s indisponível: %v", opts.Addr, err)
+	}
+	return client, nil
+}
+
+// Cache no Redis: valores em JSON com o TTL como expiração da chave, sob o prefixo
+// service-b:<nome>:. Erros do Redis são registrados no span e tratados como miss
+type redisCache[V any] struct {
+	client redisCmdable
+	prefix string
+	ttl    time.Duration
+}
+
+func newRedisCache[V any](client redisCmdable, name string, ttl time.Duration) *redisCache[V] {
+	return &redisCache[V]{client: client, prefix: "service-b:" + name + ":", ttl: ttl}
+}
+
+// Com TTL zero o cache fica desabilitado, como no cache em memória
+func (c *redisCache[V]) Get(ctx context.Context, key string) (V, bool) {
+	var v V
+	if c.ttl <= 0 {
+		return v, false
+	}
+
+	data, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			trace.SpanFromContext(ctx).RecordError(err)
+		}
+		return v, false
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+		return v, false
+	}
+	return v, true
+}
+
+func (c *redisCache[V]) Set(ctx context.Context, key string, value V) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = c.client.Set(ctx, c.prefix+key, data, c.ttl).Err()
+	}
+	if err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+}
+
+func (c *redisCache[V]) Delete(ctx context.Context, key string) {
+	if err := c.client.Del(ctx, c.prefix+key).Err(); err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis em memória (miniredis) para o teste, encerrado ao fim dele
+func newTestRedis(tb testing.TB) (*miniredis.Miniredis, *redis.Client) {
+	tb.Helper()
+	mr := miniredis.RunT(tb)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	tb.Cleanup(func() { client.Close() })
+	return mr, client
+}
+
+func TestRedisCache(t *testing.T) {
+	ctx := context.Background()
+	mr, client := newTestRedis(t)
+	c := newRedisCache[CEP](client, "cep", time.Minute)
+
+	if _, ok := c.Get(ctx, "01001000"); ok {
+		t.Fatal("Get de chave ausente devolveu ok")
+	}
+
+	c.Set(ctx, "01001000", mockCEP)
+	if got, ok := c.Get(ctx, "01001000"); !ok || got != mockCEP {
+		t.Errorf("Get = %+v, %v, want %+v", got, ok, mockCEP)
+	}
+
+	// Chave sob o prefixo do cache, com o TTL como expiração
+	if !mr.Exists("service-b:cep:01001000") {
+		t.Errorf("chaves no Redis = %v, want service-b:cep:01001000", mr.Keys())
+	}
+	if ttl := mr.TTL("service-b:cep:01001000"); ttl != time.Minute {
+		t.Errorf("TTL = %s, want 1m", ttl)
+	}
+	mr.FastForward(2 * time.Minute)
+	if _, ok := c.Get(ctx, "01001000"); ok {
+		t.Error("Get depois do TTL devolveu ok")
+	}
+
+	c.Set(ctx, "01001000", mockCEP)
+	c.Delete(ctx, "01001000")
+	if _, ok := c.Get(ctx, "01001000"); ok {
+		t.Error("Get depois do Delete devolveu ok")
+	}
+
+	// Valor que não é o JSON esperado vale como miss
+	mr.Set("service-b:cep:20040020", "{")
+	if _, ok := c.Get(ctx, "20040020"); ok {
+		t.Error("Get de valor inválido devolveu ok")
+	}
+}
+
+func TestRedisCacheZeroTTL(t *testing.T) {
+	ctx := context.Background()
+	mr, client := newTestRedis(t)
+	c := newRedisCache[int](client, "weather", 0)
+
+	c.Set(ctx, "k", 1)
+	if _, ok := c.Get(ctx, "k"); ok || len(mr.Keys()) != 0 {
+		t.Errorf("TTL zero gravou no Redis: %v", mr.Keys())
+	}
+}
+
+// Com o Redis fora, o cache funciona como miss e registra o erro no span, sem falhar a
+// operação
+func TestRedisCacheUnreachable(t *testing.T) {
+	mr, client := newTestRedis(t)
+	mr.Close()
+	c := newRedisCache[CEP](client, "cep", time.Minute)
+	sr := withSpanRecorder(t)
+
+	ctx, span := tracer.Start(context.Background(), "handler")
+	c.Set(ctx, "01001000", mockCEP)
+	_, ok := c.Get(ctx, "01001000")
+	span.End()
+
+	if ok {
+		t.Error("Get com o Redis fora devolveu ok")
+	}
+	var errs int
+	for _, e := range endedSpan(t, sr, "handler").Events() {
+		if e.Name == "exception" {
+			errs++
+		}
+	}
+	if errs != 2 {
+		t.Errorf("erros registrados no span = %d, want 2 (Set e Get)", errs)
+	}
+}
+
+func TestNewRedisClient(t *testing.T) {
+	ctx := context.Background()
+
+	// Redis inacessível não impede a criação do cliente: só é registrado no log
+	mr, _ := newTestRedis(t)
+	addr := mr.Addr()
+	mr.Close()
+	client, err := newRedisClient(ctx, "redis://"+addr+"/0")
+	if err != nil || client == nil {
+		t.Fatalf("newRedisClient com o Redis fora = %v, %v, want cliente sem erro", client, err)
+	}
+	client.Close()
+
+	if _, err := newRedisClient(ctx, "http://localhost:6379"); err == nil {
+		t.Error("newRedisClient com URL inválida não devolveu erro")
+	}
+}
+
+// Com CACHE_BACKEND=redis o endereço do CEP fica no Redis e a segunda consulta não chama
+// o ViaCEP; com o Redis fora, as consultas seguem respondendo, só sem cache
+func TestWeatherHandlerRedisCache(t *testing.T) {
+	defer func(b string, c redisCmdable) { cacheBackend, redisClient = b, c }(cacheBackend, redisClient)
+
+	tests := []struct {
+		name       string
+		redisDown  bool
+		wantViaCEP int32
+	}{
+		{"Redis disponível", false, 1},
+		{"Redis fora", true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				mockViaCEP().ServeHTTP(w, r)
+			}), mockWeatherAPI(23.5))
+
+			mr, client := newTestRedis(t)
+			if tt.redisDown {
+				mr.Close()
+			}
+			cacheBackend, redisClient = cacheBackendRedis, client
+			cepCache = newCache[CEP]("cep", time.Hour)
+
+			for range 2 {
+				rec := httptest.NewRecorder()
+				newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+				if rec.Code != http.StatusOK {
+					t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body)
+				}
+			}
+			if n := calls.Load(); n != tt.wantViaCEP {
+				t.Errorf("chamadas ao ViaCEP = %d, want %d", n, tt.wantViaCEP)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTTLCacheGetSet(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		ttl    time.Duration
+		wait   time.Duration
+		wantOK bool
+	}{
+		{"dentro do ttl", time.Minute, 0, true},
+		{"expirado", 10 * time.Millisecond, 20 * time.Millisecond, false},
+		{"ttl zero desabilita", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTTLCache[int](tt.ttl, 0)
+			c.Set(ctx, "k", 1)
+			time.Sleep(tt.wait)
+			if v, ok := c.Get(ctx, "k"); ok != tt.wantOK || (ok && v != 1) {
+				t.Errorf("Get = %d, %v, want ok %v", v, ok, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTTLCacheMaxEntries(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		maxEntries int
+		ops        func(c *ttlCache[int])
+		wantKeys   []string
+		wantGone   []string
+	}{
+		{
+			name:       "descarta a mais antiga",
+			maxEntries: 2,
+			ops: func(c *ttlCache[int]) {
+				c.Set(ctx, "a", 1)
+				c.Set(ctx, "b", 2)
+				c.Set(ctx, "c", 3)
+			},
+			wantKeys: []string{"b", "c"},
+			wantGone: []string{"a"},
+		},
+		{
+			name:       "get renova a entrada",
+			maxEntries: 2,
+			ops: func(c *ttlCache[int]) {
+				c.Set(ctx, "a", 1)
+				c.Set(ctx, "b", 2)
+				c.Get(ctx, "a")
+				c.Set(ctx, "c", 3)
+			},
+			wantKeys: []string{"a", "c"},
+			wantGone: []string{"b"},
+		},
+		{
+			name:       "set de chave existente não descarta",
+			maxEntries: 2,
+			ops: func(c *ttlCache[int]) {
+				c.Set(ctx, "a", 1)
+				c.Set(ctx, "b", 2)
+				c.Set(ctx, "a", 10)
+			},
+			wantKeys: []string{"a", "b"},
+		},
+		{
+			name:       "delete libera espaço",
+			maxEntries: 2,
+			ops: func(c *ttlCache[int]) {
+				c.Set(ctx, "a", 1)
+				c.Set(ctx, "b", 2)
+				c.Delete(ctx, "a")
+				c.Set(ctx, "c", 3)
+			},
+			wantKeys: []string{"b", "c"},
+			wantGone: []string{"a"},
+		},
+		{
+			name:       "sem teto",
+			maxEntries: 0,
+			ops: func(c *ttlCache[int]) {
+				for i := 0; i < 100; i++ {
+					c.Set(ctx, fmt.Sprint(i), i)
+				}
+			},
+			wantKeys: []string{"0", "50", "99"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTTLCache[int](time.Minute, tt.maxEntries)
+			tt.ops(c)
+			if tt.maxEntries > 0 && len(c.entries) > tt.maxEntries {
+				t.Errorf("%d entradas, máximo %d", len(c.entries), tt.maxEntries)
+			}
+			if len(c.entries) != c.lru.Len() {
+				t.Errorf("mapa com %d entradas, lista com %d", len(c.entries), c.lru.Len())
+			}
+			for _, k := range tt.wantKeys {
+				if _, ok := c.Get(ctx, k); !ok {
+					t.Errorf("%q ausente", k)
+				}
+			}
+			for _, k := range tt.wantGone {
+				if _, ok := c.Get(ctx, k); ok {
+					t.Errorf("%q ainda presente", k)
+				}
+			}
+		})
+	}
+}
+
+// Cheio, o Set descarta primeiro as expiradas, mesmo que tenham sido usadas há pouco
+func TestTTLCacheSweepsExpiredBeforeEvicting(t *testing.T) {
+	ctx := context.Background()
+	c := newTTLCache[int](time.Minute, 3)
+	c.Set(ctx, "a", 1)
+	c.Set(ctx, "b", 2)
+	c.Set(ctx, "c", 3)
+
+	// b e c expiram; a continua válida, mas é a menos usada
+	for _, k := range []string{"b", "c"} {
+		c.entries[k].Value.(*cacheEntry[int]).expiresAt = time.Now().Add(-time.Second)
+	}
+	c.Set(ctx, "d", 4)
+
+	if len(c.entries) != 2 {
+		t.Errorf("%d entradas, want 2 (a, d)", len(c.entries))
+	}
+	for _, k := range []string{"a", "d"} {
+		if _, ok := c.Get(ctx, k); !ok {
+			t.Errorf("%q ausente", k)
+		}
+	}
+}
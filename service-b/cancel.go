@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Status não padrão (convenção do nginx) para a requisição abandonada pelo cliente
+const statusClientClosedRequest = 499
+
+// Erro de consulta quando a falha do upstream veio do fim do contexto da requisição, e
+// não do upstream: cliente desconectado (499) ou prazo da rota/do chamador esgotado (504).
+// Retorna nil se o contexto segue ativo
+func contextLookupError(ctx context.Context, span trace.Span, upstream string) *lookupError {
+	switch err := ctx.Err(); {
+	case errors.Is(err, context.Canceled):
+		span.SetAttributes(attribute.Bool("request.cancelled", true))
+		return &lookupError{Status: statusClientClosedRequest, Message: "client closed request", Upstream: upstream}
+	case errors.Is(err, context.DeadlineExceeded):
+		span.SetAttributes(attribute.Bool("request.deadline_exceeded", true))
+		return &lookupError{Status: http.StatusGatewayTimeout, Message: "deadline exceeded", Upstream: upstream}
+	}
+	return nil
+}
+
+// O cliente já desconectou: só registra o 499 (span e log de acesso), sem corpo e sem
+// marcar o span como erro nem entrar nos erros recentes
+func writeClientClosed(w http.ResponseWriter, span trace.Span) {
+	span.SetAttributes(attribute.Int("http.response.status_code", statusClientClosedRequest))
+	w.WriteHeader(statusClientClosedRequest)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Cliente que desconecta durante a chamada ao ViaCEP recebe 499, sem corpo, sem marcar
+// o span como erro e sem entrar nos erros recentes; prazo esgotado responde 504
+func TestWeatherHandlerCancelled(t *testing.T) {
+	defer func(ring *errorRing) { recentErrors = ring }(recentErrors)
+
+	tests := []struct {
+		name       string
+		ctx        func() (context.Context, context.CancelFunc)
+		cancel     bool // cancela o contexto quando o ViaCEP recebe a chamada
+		wantStatus int
+		wantAttr   attribute.Key
+		wantError  bool
+	}{
+		{"cliente desconectou", func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) },
+			true, statusClientClosedRequest, "request.cancelled", false},
+		{"prazo esgotado", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 50*time.Millisecond)
+		}, false, http.StatusGatewayTimeout, "request.deadline_exceeded", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recentErrors = newErrorRing(10)
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			// O ViaCEP só responde depois que a conexão do cliente cai
+			withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.cancel {
+					cancel()
+				}
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			}), mockWeatherAPI(23.5))
+			sr := withSpanRecorder(t)
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil).WithContext(ctx))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus == statusClientClosedRequest && rec.Body.Len() != 0 {
+				t.Errorf("corpo = %q, want vazio", rec.Body)
+			}
+
+			span := endedSpan(t, sr, "weather_handler")
+			if !spanAttr(span, tt.wantAttr).AsBool() {
+				t.Errorf("%s ausente no span", tt.wantAttr)
+			}
+			if got := spanAttr(span, "http.response.status_code").AsInt64(); got != int64(tt.wantStatus) {
+				t.Errorf("http.response.status_code = %d, want %d", got, tt.wantStatus)
+			}
+			if got := span.Status().Code == codes.Error; got != tt.wantError {
+				t.Errorf("span com erro = %v, want %v", got, tt.wantError)
+			}
+			if n := len(recentErrors.recent()); (n > 0) != tt.wantError {
+				t.Errorf("erros recentes = %d, want erro registrado: %v", n, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestLookupOutcomeCancelled(t *testing.T) {
+	if got := lookupOutcome(&lookupError{Status: statusClientClosedRequest}); got != outcomeCancelled {
+		t.Errorf("lookupOutcome(499) = %q, want %q", got, outcomeCancelled)
+	}
+	if got := lookupOutcome(&lookupError{Status: http.StatusGatewayTimeout}); got != outcomeUpstreamError {
+		t.Errorf("lookupOutcome(504) = %q, want %q", got, outcomeUpstreamError)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/validation"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Contagem de consultas em andamento por CEP, para que um CEP muito acessado não
+// monopolize a capacidade dos upstreams. Diferente do MAX_INFLIGHT_REQUESTS (global)
+// e do coalescing, que só junta chamadas idênticas ao upstream
+type cepInFlight struct {
+	mu     sync.Mutex
+	limit  int
+	counts map[string]int
+}
+
+// Reserva uma vaga para o CEP; retorna a contagem atual (incluindo esta, se aceita)
+func (c *cepInFlight) acquire(cep string) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.counts[cep]
+	if n >= c.limit {
+		return n, false
+	}
+	c.counts[cep] = n + 1
+	return n + 1, true
+}
+
+func (c *cepInFlight) release(cep string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts[cep] <= 1 {
+		delete(c.counts, cep)
+		return
+	}
+	c.counts[cep]--
+}
+
+// Limita as consultas simultâneas a um mesmo CEP (MAX_INFLIGHT_PER_CEP, 0 desabilita).
+// O excedente responde 429 com Retry-After; cep.inflight registra a disputa pelo CEP
+func perCEPLimitMiddleware(limit int) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+		inflight := &cepInFlight{limit: limit, counts: make(map[string]int)}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cep := validation.NormalizeCEP(mux.Vars(r)["cep"])
+			span := trace.SpanFromContext(r.Context())
+
+			n, ok := inflight.acquire(cep)
+			span.SetAttributes(
+				attribute.Int("cep.inflight", n),
+				attribute.Bool("cep.concurrency_limited", !ok),
+			)
+			if !ok {
+				w.Header().Set("Retry-After", "1")
+				writeError(w, span, http.StatusTooManyRequests, "too many concurrent lookups for this zipcode")
+				return
+			}
+			defer inflight.release(cep)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
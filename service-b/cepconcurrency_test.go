@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/validation"
+	"github.com/gorilla/mux"
+)
+
+// Com o CEP saturado, a consulta seguinte ao mesmo CEP responde 429 com Retry-After;
+// outro CEP continua sendo atendido e, liberadas as vagas, o CEP volta a ser aceito
+func TestPerCEPLimitMiddleware(t *testing.T) {
+	sr := withSpanRecorder(t)
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	r := mux.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, span := tracer.Start(r.Context(), "request")
+			defer span.End()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	r.Handle("/{cep}", perCEPLimitMiddleware(2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if validation.NormalizeCEP(mux.Vars(r)["cep"]) == "01001000" {
+			entered <- struct{}{}
+			<-release
+		}
+	})))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	// Ocupa as duas vagas do CEP, com e sem traço: a contagem é pelo CEP normalizado
+	var wg sync.WaitGroup
+	for _, path := range []string{"/01001000", "/01001-000"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			get(path)
+		}()
+	}
+	<-entered
+	<-entered
+	sr.Reset()
+
+	rec := get("/01001-000")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("CEP saturado = %d com Retry-After %q, want 429 com 1", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	span := endedSpan(t, sr, "request")
+	if n, limited := spanAttr(span, "cep.inflight").AsInt64(), spanAttr(span, "cep.concurrency_limited").AsBool(); n != 2 || !limited {
+		t.Errorf("cep.inflight = %d, cep.concurrency_limited = %v, want 2 e true", n, limited)
+	}
+
+	if rec := get("/20040020"); rec.Code != http.StatusOK {
+		t.Errorf("outro CEP = %d, want 200", rec.Code)
+	}
+
+	close(release)
+	wg.Wait()
+	sr.Reset()
+	go func() { <-entered }()
+	if rec := get("/01001000"); rec.Code != http.StatusOK {
+		t.Errorf("CEP liberado = %d, want 200", rec.Code)
+	}
+	span = endedSpan(t, sr, "request")
+	if n, limited := spanAttr(span, "cep.inflight").AsInt64(), spanAttr(span, "cep.concurrency_limited").AsBool(); n != 1 || limited {
+		t.Errorf("cep.inflight = %d, cep.concurrency_limited = %v, want 1 e false", n, limited)
+	}
+}
+
+// A contagem por CEP recusa acima do limite e some quando a última consulta termina
+func TestCEPInFlight(t *testing.T) {
+	c := &cepInFlight{limit: 1, counts: make(map[string]int)}
+	if n, ok := c.acquire("01001000"); !ok || n != 1 {
+		t.Fatalf("primeira vaga = %d, %v, want 1 e true", n, ok)
+	}
+	if n, ok := c.acquire("01001000"); ok || n != 1 {
+		t.Errorf("acquire com o CEP cheio = %d, %v, want 1 e false", n, ok)
+	}
+	if _, ok := c.acquire("20040020"); !ok {
+		t.Error("outro CEP recusado")
+	}
+	c.release("01001000")
+	c.release("20040020")
+	if len(c.counts) != 0 {
+		t.Errorf("contagens depois de liberar = %v, want vazio", c.counts)
+	}
+}
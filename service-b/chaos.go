@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Configuração da injeção de falhas para testes de resiliência
+type chaosConfig struct {
+	DelayRate float64       // probabilidade de atrasar a resposta
+	Delay     time.Duration // atraso injetado
+	ErrorRate float64       // probabilidade de responder 500
+	Seed      int64
+}
+
+// Injeta atrasos e erros 500 nas rotas conforme as probabilidades configuradas,
+// marcando o span com chaos.injected para distinguir falhas injetadas das reais
+func chaosMiddleware(cfg chaosConfig) mux.MiddlewareFunc {
+	var mu sync.Mutex
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	roll := func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return rng.Float64()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span := trace.SpanFromContext(r.Context())
+
+			if cfg.DelayRate > 0 && roll() < cfg.DelayRate {
+				span.SetAttributes(
+					attribute.String("chaos.injected", "delay"),
+					attribute.Int64("chaos.delay_ms", cfg.Delay.Milliseconds()),
+				)
+				select {
+				case <-time.After(cfg.Delay):
+				case <-r.Context().Done():
+					return
+				}
+			}
+
+			if cfg.ErrorRate > 0 && roll() < cfg.ErrorRate {
+				span.SetAttributes(attribute.String("chaos.injected", "error"))
+				writeError(w, span, http.StatusInternalServerError, "internal server error")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
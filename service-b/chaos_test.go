@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Com a seed fixa, a fração de falhas injetadas acompanha a probabilidade configurada e
+// se repete entre execuções
+func TestChaosMiddlewareRates(t *testing.T) {
+	const requests = 2000
+
+	tests := []struct {
+		name      string
+		cfg       chaosConfig
+		wantDelay float64
+		wantError float64
+	}{
+		{"desligado", chaosConfig{Seed: 1}, 0, 0},
+		{"só erros", chaosConfig{ErrorRate: 0.3, Seed: 1}, 0, 0.3},
+		{"só atrasos", chaosConfig{DelayRate: 0.2, Delay: time.Microsecond, Seed: 1}, 0.2, 0},
+		{"atrasos e erros", chaosConfig{DelayRate: 0.5, Delay: time.Microsecond, ErrorRate: 0.1, Seed: 7}, 0.5, 0.1},
+		{"sempre erro", chaosConfig{ErrorRate: 1, Seed: 1}, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := func() (delays, errors int) {
+				sr := withSpanRecorder(t)
+				handler := chaosMiddleware(tt.cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+				for i := 0; i < requests; i++ {
+					ctx, span := tracer.Start(context.Background(), "handler")
+					rec := httptest.NewRecorder()
+					handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil).WithContext(ctx))
+					span.End()
+					if rec.Code == http.StatusInternalServerError {
+						errors++
+					}
+				}
+				// chaos.injected vira "error" quando o mesmo pedido também falha; o atraso fica em chaos.delay_ms
+				for _, s := range sr.Ended() {
+					if spanAttr(s, "chaos.delay_ms").Type() != attribute.INVALID {
+						delays++
+					}
+				}
+				return delays, errors
+			}
+
+			delays, errors := run()
+			if got := float64(delays) / requests; got < tt.wantDelay-0.05 || got > tt.wantDelay+0.05 {
+				t.Errorf("fração de atrasos = %.3f, want %.2f ± 0.05", got, tt.wantDelay)
+			}
+			if got := float64(errors) / requests; got < tt.wantError-0.05 || got > tt.wantError+0.05 {
+				t.Errorf("fração de erros = %.3f, want %.2f ± 0.05", got, tt.wantError)
+			}
+
+			// Mesma seed, mesma sequência
+			if d, e := run(); d != delays || e != errors {
+				t.Errorf("segunda execução = %d atrasos e %d erros, want %d e %d", d, e, delays, errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Consultas idênticas e simultâneas aos upstreams (mesmo CEP, mesma localidade) são
+// agrupadas: só a primeira chama o upstream e as demais recebem o mesmo resultado
+var (
+	cepLookups     singleflight.Group
+	weatherLookups singleflight.Group
+)
+
+// Executa fn uma única vez por chave entre as chamadas simultâneas. joined indica que a
+// chamada aproveitou a consulta de outra já em andamento, que usa o ctx (prazo, trace e
+// orçamento de retentativas) de quem a iniciou. Se essa consulta falhar pelo ctx de quem
+// a iniciou (cliente desconectado, prazo esgotado) e o ctx do chamador seguir válido, a
+// chamada é refeita, possivelmente como a nova primeira. Quem aguarda desiste ao ter o
+// próprio ctx cancelado
+func coalesce[T any](ctx context.Context, g *singleflight.Group, key string, fn func() (T, error)) (v T, joined bool, err error) {
+	for {
+		leader := false
+		ch := g.DoChan(key, func() (interface{}, error) {
+			leader = true
+			return fn()
+		})
+
+		select {
+		case res := <-ch:
+			if res.Err != nil {
+				if !leader && isContextError(res.Err) && ctx.Err() == nil {
+					joined = true
+					continue
+				}
+				return v, joined || !leader, res.Err
+			}
+			return res.Val.(T), joined || !leader, nil
+		case <-ctx.Done():
+			return v, false, ctx.Err()
+		}
+	}
+}
+
+// Falha causada por cancelamento ou prazo do ctx, e não pelo upstream
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+func TestCoalesceSharesResult(t *testing.T) {
+	var g singleflight.Group
+	release := make(chan struct{})
+	calls := 0
+
+	type result struct {
+		v      int
+		joined bool
+		err    error
+	}
+	results := make(chan result, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			v, joined, err := coalesce(context.Background(), &g, "k", func() (int, error) {
+				calls++
+				<-release
+				return 42, nil
+			})
+			results <- result{v, joined, err}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	joinedCount := 0
+	for i := 0; i < 2; i++ {
+		r := <-results
+		if r.err != nil || r.v != 42 {
+			t.Fatalf("coalesce = %d, %v; want 42, nil", r.v, r.err)
+		}
+		if r.joined {
+			joinedCount++
+		}
+	}
+	if calls != 1 || joinedCount != 1 {
+		t.Errorf("calls = %d, joined = %d; want 1 and 1", calls, joinedCount)
+	}
+}
+
+// O cancelamento de quem iniciou a consulta não pode virar erro para quem só aguardava
+func TestCoalesceRetriesWhenLeaderCancelled(t *testing.T) {
+	var g singleflight.Group
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	started := make(chan struct{})
+
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, _, err := coalesce(leaderCtx, &g, "k", func() (int, error) {
+			close(started)
+			<-leaderCtx.Done()
+			return 0, fmt.Errorf("erro ao consultar CEP: %w", leaderCtx.Err())
+		})
+		leaderErr <- err
+	}()
+	<-started
+
+	type result struct {
+		v      int
+		joined bool
+		err    error
+	}
+	follower := make(chan result, 1)
+	go func() {
+		v, joined, err := coalesce(context.Background(), &g, "k", func() (int, error) {
+			return 42, nil
+		})
+		follower <- result{v, joined, err}
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancelLeader()
+
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader err = %v, want context.Canceled", err)
+	}
+	r := <-follower
+	if r.err != nil || r.v != 42 {
+		t.Fatalf("follower = %d, %v; want 42, nil", r.v, r.err)
+	}
+	if !r.joined {
+		t.Error("follower joined = false, want true")
+	}
+}
+
+func TestCoalesceFollowerGivesUpOnOwnCancel(t *testing.T) {
+	var g singleflight.Group
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+
+	go coalesce(context.Background(), &g, "k", func() (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := coalesce(ctx, &g, "k", func() (int, error) { return 2, nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
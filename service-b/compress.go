@@ -0,0 +1,134 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// Comprime as respostas com o primeiro algoritmo de RESPONSE_COMPRESSION (em ordem de
+// preferência, ex.: br,gzip) aceito pelo cliente em Accept-Encoding. Vary: Accept-Encoding
+// é sempre enviado para que caches intermediários não misturem as variantes
+func compressionMiddleware(encodings []string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if len(encodings) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), encodings)
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, span: trace.SpanFromContext(r.Context())}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// Escolhe, na ordem de preferência do servidor, o primeiro algoritmo aceito pelo
+// cliente (q > 0, com * valendo para os não listados). Retorna "" para identity
+func negotiateEncoding(acceptEncoding string, preferred []string) string {
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		accepted[name] = q
+	}
+
+	for _, enc := range preferred {
+		q, ok := accepted[enc]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > 0 {
+			return enc
+		}
+	}
+	return ""
+}
+
+// ResponseWriter que decide no WriteHeader se comprime: respostas sem corpo (204, 304),
+// já codificadas ou em stream (SSE, que precisa de cada evento no flush) seguem sem compressão
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	span        trace.Span
+	enc         io.WriteCloser
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+
+	h := cw.Header()
+	if code != http.StatusNoContent && code != http.StatusNotModified &&
+		h.Get("Content-Encoding") == "" && !strings.HasPrefix(h.Get("Content-Type"), "text/event-stream") {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		if cw.encoding == encodingBrotli {
+			cw.enc = brotli.NewWriter(cw.ResponseWriter)
+		} else {
+			cw.enc = gzip.NewWriter(cw.ResponseWriter)
+		}
+		cw.span.SetAttributes(attribute.String("http.response.content_encoding", cw.encoding))
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Descarrega o que está no compressor antes do flush da conexão
+func (cw *compressWriter) Flush() {
+	if f, ok := cw.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Permite ao http.ResponseController alcançar o ResponseWriter original
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressWriter) close() {
+	if cw.enc != nil {
+		cw.enc.Close()
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	preferred := []string{encodingBrotli, encodingGzip}
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		want           string
+	}{
+		{"sem header", "", ""},
+		{"só gzip", "gzip", encodingGzip},
+		{"preferência do servidor", "gzip, br", encodingBrotli},
+		{"maiúsculas e espaços", " GZIP ", encodingGzip},
+		{"br recusado", "br;q=0, gzip", encodingGzip},
+		{"q fracionário", "br;q=0.5", encodingBrotli},
+		{"q inválido vale 1", "br;q=x", encodingBrotli},
+		{"curinga", "*", encodingBrotli},
+		{"curinga não cobre o recusado", "br;q=0, *", encodingGzip},
+		{"curinga recusado", "*;q=0", ""},
+		{"nenhum suportado", "deflate, identity", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := negotiateEncoding(tt.acceptEncoding, preferred); got != tt.want {
+				t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.acceptEncoding, got, tt.want)
+			}
+		})
+	}
+}
+
+// A resposta sai no algoritmo negociado, com Vary: Accept-Encoding em todas as variantes;
+// 204 e HEAD seguem sem compressão
+func TestCompressionMiddleware(t *testing.T) {
+	const payload = `{"city":"São Paulo","temp_C":23.5}`
+	h := compressionMiddleware([]string{encodingBrotli, encodingGzip})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/vazio" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, payload)
+	}))
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		acceptEncoding string
+		wantEncoding   string
+	}{
+		{"br e gzip", http.MethodGet, "/", "gzip, br", encodingBrotli},
+		{"só gzip", http.MethodGet, "/", "gzip", encodingGzip},
+		{"br recusado", http.MethodGet, "/", "br;q=0, gzip", encodingGzip},
+		{"sem Accept-Encoding", http.MethodGet, "/", "", ""},
+		{"nenhum suportado", http.MethodGet, "/", "deflate", ""},
+		{"204", http.MethodGet, "/vazio", "br", ""},
+		{"HEAD", http.MethodHead, "/", "br", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", got)
+			}
+			if tt.method == http.MethodHead || rec.Code == http.StatusNoContent {
+				return
+			}
+
+			var body io.Reader = rec.Body
+			switch tt.wantEncoding {
+			case encodingBrotli:
+				body = brotli.NewReader(rec.Body)
+			case encodingGzip:
+				zr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = zr
+			}
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != payload {
+				t.Errorf("corpo = %q, want %q", got, payload)
+			}
+		})
+	}
+}
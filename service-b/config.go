@@ -0,0 +1,274 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/config"
+	"github.com/afga95/lab-go-otel-zipkin/shared/telemetry"
+	"github.com/afga95/lab-go-otel-zipkin/shared/validation"
+)
+
+// Configuração do Serviço B, lida uma única vez do ambiente por LoadConfig
+type Config struct {
+	Port               string
+	WeatherAPIKey      string
+	WeatherAPIKeys     []weightedAPIKey
+	WeatherAPIKeyFile  string
+	WeatherKeyCooldown time.Duration
+	WeatherAPIBaseURL  string
+	ViaCEPBaseURL      string
+
+	UpstreamTLSMinVersion   uint16
+	ValidateUpstream        bool
+	VerboseErrors           bool
+	UpstreamMaxRedirects    int
+	UpstreamMaxConnsPerHost int
+	UpstreamConnWaitTimeout time.Duration
+
+	MaxInFlight       int
+	MaxURILength      int
+	CriticalRoutes    []string
+	MaxInFlightPerCEP int
+	MaxHeaderBytes    int
+	ShutdownDelay     time.Duration
+	RouteTimeouts     map[string]time.Duration
+	EnableH2C         bool
+	Compression       []string // RESPONSE_COMPRESSION: algoritmos em ordem de preferência
+
+	AccessLogSampleRate float64
+	LogBudgetPerRequest int
+
+	BatchEmptyStatus  int
+	BatchMaxItems     int
+	BatchMaxBodyBytes int
+	BatchConcurrency  int
+	BatchTimeout      time.Duration
+	BatchDuplicates   string
+
+	RetryMaxAttempts int
+	RetryBudget      int
+	RetryAfterMax    time.Duration
+	RetryJitter      string
+
+	CacheBackend             string
+	RedisURL                 string
+	CacheMaxEntries          int
+	CEPCacheTTL              time.Duration
+	CachePreloadFile         string
+	CachePreloadWeather      bool
+	WeatherCacheTTL          time.Duration
+	WeatherNegativeCacheTTL  time.Duration
+	WeatherHedgeDelay        time.Duration
+	EmptyCity                string
+	WeatherExpectedCountries []string
+	WeatherCountryMismatch   string
+	WeatherEmbeddedError     string
+	WeatherLocalityFallback  []string // WEATHER_LOCALITY_FALLBACK: em ordem, após Cidade,UF
+	WeatherDirectPostalCode  bool
+	CEPPrefixFallback        bool
+	DefaultTempUnit          string
+	TempPrecision            int
+	GeneratedAt              string
+	CEPTestModeRanges        []cepRange
+	WeatherUpdateInterval    time.Duration
+
+	WeatherBreakerThreshold int
+	WeatherBreakerCooldown  time.Duration
+	ReadyzDegradedStatus    int
+	ReadinessChecks         []string // READINESS_CHECKS: viacep, weather, otlp
+	ReadinessCheckTimeout   time.Duration
+	ReadinessCacheTTL       time.Duration
+
+	ErrorBudgetThreshold float64
+	ErrorBudgetWindow    time.Duration
+	ErrorBudgetMinCalls  int
+
+	WeatherSoftFailCodes  []int
+	WeatherSoftFailStatus int
+	HistoricalFallback    bool
+
+	AdminToken      string
+	AdminErrorsSize int
+
+	ChaosEnabled bool
+	Chaos        chaosConfig
+
+	EnablePprof bool
+	PprofAddr   string
+
+	MetricsDisabled bool // METRICS_DISABLED: não exporta as métricas
+
+	Tracing TracingConfig
+}
+
+// Configuração da exportação de traces: a comum aos serviços mais o nome dos spans e o
+// aviso de descarte. Valores zero mantêm os defaults do SDK
+type TracingConfig struct {
+	telemetry.Config
+
+	DropLogInterval time.Duration // TRACE_DROP_LOG_INTERVAL: intervalo mínimo entre avisos de spans descartados
+	SpanNaming      string        // SPAN_NAMING: by_route ou by_uf (UF do CEP no nome do span)
+}
+
+// Lê e valida todas as variáveis de ambiente. Retorna os *config.Error de todas as
+// variáveis inválidas combinados com errors.Join
+func LoadConfig() (Config, error) {
+	var p envParser
+
+	cfg := Config{
+		Port:               p.Str("PORT", "8080"),
+		WeatherAPIKey:      p.Str("WEATHER_API_KEY", "ad43e5d744964ababd411426252107"),
+		WeatherAPIKeys:     p.weightedKeys("WEATHER_API_KEYS"),
+		WeatherAPIKeyFile:  p.Str("WEATHER_API_KEY_FILE", ""),
+		WeatherKeyCooldown: p.PositiveDuration("WEATHER_API_KEY_COOLDOWN", time.Minute),
+		WeatherAPIBaseURL:  p.Str("WEATHER_API_BASE_URL", "https://api.weatherapi.com"),
+		ViaCEPBaseURL:      p.Str("VIACEP_BASE_URL", "https://viacep.com.br"),
+
+		UpstreamTLSMinVersion:   tlsVersions[p.Choice("UPSTREAM_TLS_MIN_VERSION", "1.2", "1.2", "1.3")],
+		ValidateUpstream:        p.Bool("VALIDATE_UPSTREAM"),
+		VerboseErrors:           p.Bool("VERBOSE_ERRORS"),
+		UpstreamMaxRedirects:    p.NonNegativeInt("UPSTREAM_MAX_REDIRECTS", 3),
+		UpstreamMaxConnsPerHost: p.NonNegativeInt("UPSTREAM_MAX_CONNS_PER_HOST", 0),
+		UpstreamConnWaitTimeout: p.PositiveDuration("UPSTREAM_CONN_WAIT_TIMEOUT", time.Second),
+
+		MaxInFlight:       p.PositiveInt("MAX_INFLIGHT_REQUESTS", 100),
+		MaxURILength:      p.NonNegativeInt("MAX_URI_LENGTH", 2048),
+		CriticalRoutes:    p.ListOr("CRITICAL_ROUTES", "/admin/errors,/admin/config"),
+		MaxInFlightPerCEP: p.NonNegativeInt("MAX_INFLIGHT_PER_CEP", 10),
+		MaxHeaderBytes:    p.PositiveInt("MAX_HEADER_BYTES", 64<<10),
+		ShutdownDelay:     p.NonNegativeDuration("SHUTDOWN_DELAY", 0),
+		RouteTimeouts:     p.DurationMap("ROUTE_TIMEOUTS"),
+		EnableH2C:         p.Bool("ENABLE_H2C"),
+		Compression:       p.ChoiceList("RESPONSE_COMPRESSION", "br,gzip", encodingBrotli, encodingGzip),
+
+		AccessLogSampleRate: p.Rate("ACCESS_LOG_SAMPLE_RATE", 1),
+		LogBudgetPerRequest: p.NonNegativeInt("LOG_BUDGET_PER_REQUEST", 20),
+
+		BatchEmptyStatus:  p.OneOf("BATCH_EMPTY_STATUS", http.StatusNoContent, http.StatusNoContent, http.StatusBadRequest),
+		BatchMaxItems:     p.PositiveInt("BATCH_MAX_ITEMS", 50),
+		BatchMaxBodyBytes: p.PositiveInt("BATCH_MAX_BODY_BYTES", 64<<10),
+		BatchConcurrency:  p.PositiveInt("BATCH_CONCURRENCY", 4),
+		BatchTimeout:      p.PositiveDuration("BATCH_TIMEOUT", 10*time.Second),
+		BatchDuplicates:   p.Choice("BATCH_DUPLICATES", batchDuplicatesDedupe, batchDuplicatesDedupe, batchDuplicatesFetchEach),
+
+		RetryMaxAttempts: p.PositiveInt("RETRY_MAX_ATTEMPTS", 3),
+		RetryBudget:      p.NonNegativeInt("RETRY_BUDGET", 2),
+		RetryAfterMax:    p.NonNegativeDuration("RETRY_AFTER_MAX", 5*time.Second),
+		RetryJitter:      p.Choice("RETRY_JITTER", retryJitterNone, retryJitterNone, retryJitterFull, retryJitterEqual, retryJitterDecorrelated),
+
+		CacheBackend:             p.Choice("CACHE_BACKEND", cacheBackendMemory, cacheBackendMemory, cacheBackendRedis),
+		RedisURL:                 p.Str("REDIS_URL", "redis://localhost:6379/0"),
+		CacheMaxEntries:          p.PositiveInt("CACHE_MAX_ENTRIES", defaultCacheMaxEntries),
+		CEPCacheTTL:              p.NonNegativeDuration("CEP_CACHE_TTL", 24*time.Hour),
+		CachePreloadFile:         p.Str("CACHE_PRELOAD_FILE", ""),
+		CachePreloadWeather:      p.Bool("CACHE_PRELOAD_WEATHER"),
+		WeatherCacheTTL:          p.NonNegativeDuration("WEATHER_CACHE_TTL", 10*time.Minute),
+		WeatherNegativeCacheTTL:  p.NonNegativeDuration("WEATHER_NEGATIVE_CACHE_TTL", time.Minute),
+		WeatherHedgeDelay:        p.NonNegativeDuration("WEATHER_HEDGE_DELAY", 0),
+		EmptyCity:                p.Choice("EMPTY_CITY", emptyCityFallback, emptyCityFallback, emptyCityFlag),
+		WeatherExpectedCountries: p.ListOr("WEATHER_EXPECTED_COUNTRIES", "Brazil,Brasil"),
+		WeatherCountryMismatch:   p.Choice("WEATHER_COUNTRY_MISMATCH", countryMismatchRetry, countryMismatchRetry, countryMismatchFlag),
+		WeatherEmbeddedError:     p.Choice("WEATHER_EMBEDDED_ERROR", embeddedErrorFail, embeddedErrorFail, embeddedErrorFlag),
+		WeatherLocalityFallback:  p.ChoiceList("WEATHER_LOCALITY_FALLBACK", localityFallbackCapital, localityFallbackMunicipality, localityFallbackCapital),
+		WeatherDirectPostalCode:  p.Bool("WEATHER_DIRECT_POSTAL_CODE"),
+		CEPPrefixFallback:        p.Bool("CEP_PREFIX_FALLBACK"),
+		DefaultTempUnit:          p.Choice("DEFAULT_TEMP_UNIT", tempUnitCelsius, tempUnitCelsius, tempUnitFahrenheit, tempUnitKelvin),
+		TempPrecision:            p.IntWithMin("TEMP_PRECISION", -1, -1, "use -1 (mínimo necessário) ou o número de casas decimais"),
+		GeneratedAt:              p.Choice("GENERATED_AT", generatedAtRequest, generatedAtRequest, generatedAtAlways),
+		CEPTestModeRanges:        p.cepRanges("CEP_TEST_MODE_RANGES"),
+		WeatherUpdateInterval:    p.PositiveDuration("WEATHER_UPDATE_INTERVAL", 15*time.Minute),
+
+		WeatherBreakerThreshold: p.NonNegativeInt("WEATHER_BREAKER_THRESHOLD", 5),
+		WeatherBreakerCooldown:  p.PositiveDuration("WEATHER_BREAKER_COOLDOWN", 30*time.Second),
+		ReadyzDegradedStatus:    p.OneOf("READYZ_DEGRADED_STATUS", http.StatusOK, http.StatusOK, http.StatusServiceUnavailable),
+		ReadinessChecks:         p.ChoiceList("READINESS_CHECKS", readinessWeather, readinessViaCEP, readinessWeather, readinessOTLP),
+		ReadinessCheckTimeout:   p.PositiveDuration("READINESS_CHECK_TIMEOUT", 2*time.Second),
+		ReadinessCacheTTL:       p.NonNegativeDuration("READINESS_CACHE_TTL", 5*time.Second),
+
+		ErrorBudgetThreshold: p.Rate("UPSTREAM_ERROR_BUDGET_THRESHOLD", 0),
+		ErrorBudgetWindow:    p.PositiveDuration("UPSTREAM_ERROR_BUDGET_WINDOW", time.Minute),
+		ErrorBudgetMinCalls:  p.PositiveInt("UPSTREAM_ERROR_BUDGET_MIN_CALLS", 10),
+
+		WeatherSoftFailCodes:  p.IntList("WEATHER_SOFT_FAIL_CODES"),
+		WeatherSoftFailStatus: p.OneOf("WEATHER_SOFT_FAIL_STATUS", http.StatusBadGateway, http.StatusBadGateway, http.StatusServiceUnavailable),
+		HistoricalFallback:    p.Bool("HISTORICAL_FALLBACK"),
+
+		AdminToken:      p.Str("ADMIN_TOKEN", ""),
+		AdminErrorsSize: p.PositiveInt("ADMIN_ERRORS_SIZE", 50),
+
+		ChaosEnabled: p.Bool("CHAOS"),
+		Chaos: chaosConfig{
+			DelayRate: p.Rate("CHAOS_DELAY_RATE", 0),
+			Delay:     p.NonNegativeDuration("CHAOS_DELAY", 500*time.Millisecond),
+			ErrorRate: p.Rate("CHAOS_ERROR_RATE", 0),
+			Seed:      p.Int64("CHAOS_SEED", time.Now().UnixNano()),
+		},
+
+		EnablePprof: p.Bool("ENABLE_PPROF"),
+		PprofAddr:   p.Str("PPROF_ADDR", "localhost:6060"),
+
+		MetricsDisabled: p.Bool("METRICS_DISABLED"),
+
+		Tracing: TracingConfig{
+			Config: telemetry.ConfigFromEnv(&p.Parser),
+
+			DropLogInterval: p.PositiveDuration("TRACE_DROP_LOG_INTERVAL", time.Minute),
+			SpanNaming:      p.Choice("SPAN_NAMING", spanNamingByRoute, spanNamingByRoute, spanNamingByUF),
+		},
+	}
+
+	// A chave em arquivo (ex.: secret montado) tem precedência sobre WEATHER_API_KEY
+	if cfg.WeatherAPIKeyFile != "" {
+		key, err := readAPIKeyFile(cfg.WeatherAPIKeyFile)
+		if err != nil {
+			p.Fail("WEATHER_API_KEY_FILE", cfg.WeatherAPIKeyFile, err.Error())
+		} else {
+			cfg.WeatherAPIKey = key
+		}
+	}
+
+	return cfg, p.Err()
+}
+
+// Leitor de variáveis de ambiente com os formatos próprios do Serviço B
+type envParser struct {
+	config.Parser
+}
+
+// Faixas de CEP separadas por vírgulas, cada uma "inicio-fim" com 8 dígitos,
+// ex.: 00000000-00000999
+func (p *envParser) cepRanges(name string) []cepRange {
+	var ranges []cepRange
+	for _, item := range p.List(name) {
+		from, to, ok := strings.Cut(item, "-")
+		if !ok || !validation.IsValidCEP(from) || !validation.IsValidCEP(to) || from > to {
+			p.Fail(name, os.Getenv(name), "use faixas inicio-fim de 8 dígitos separadas por vírgula")
+			return nil
+		}
+		ranges = append(ranges, cepRange{From: from, To: to})
+	}
+	return ranges
+}
+
+// Chaves separadas por vírgulas, cada uma com peso opcional "chave:peso" (inteiro
+// positivo, default 1), ex.: chaveA:3,chaveB
+func (p *envParser) weightedKeys(name string) []weightedAPIKey {
+	var keys []weightedAPIKey
+	for _, item := range p.List(name) {
+		key, weight := item, 1
+		if k, w, ok := strings.Cut(item, ":"); ok {
+			n, err := strconv.Atoi(w)
+			if err != nil || n < 1 || k == "" {
+				p.Fail(name, redacted, "use chaves separadas por vírgula, cada uma com peso opcional chave:peso (inteiro positivo)")
+				return nil
+			}
+			key, weight = k, n
+		}
+		keys = append(keys, weightedAPIKey{Key: key, Weight: weight})
+	}
+	return keys
+}
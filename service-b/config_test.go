@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/config"
+	"github.com/afga95/lab-go-otel-zipkin/shared/telemetry"
+)
+
+func TestLoadConfigDefaults(t *testing.T) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if cfg.Port != "8080" {
+		t.Errorf("Port = %q, want 8080", cfg.Port)
+	}
+	if cfg.MaxInFlight != 100 || cfg.MaxURILength != 2048 || cfg.MaxHeaderBytes != 64<<10 {
+		t.Errorf("limites = %d/%d/%d, want 100/2048/65536", cfg.MaxInFlight, cfg.MaxURILength, cfg.MaxHeaderBytes)
+	}
+	if cfg.BatchEmptyStatus != http.StatusNoContent || cfg.BatchMaxItems != 50 {
+		t.Errorf("batch = %d/%d, want 204/50", cfg.BatchEmptyStatus, cfg.BatchMaxItems)
+	}
+	if !slices.Equal(cfg.Compression, []string{encodingBrotli, encodingGzip}) {
+		t.Errorf("Compression = %v, want [br gzip]", cfg.Compression)
+	}
+	if cfg.CacheBackend != cacheBackendMemory || cfg.WeatherCacheTTL != 10*time.Minute {
+		t.Errorf("cache = %s/%v, want memory/10m", cfg.CacheBackend, cfg.WeatherCacheTTL)
+	}
+	if cfg.ValidateUpstream || cfg.EnableH2C || cfg.ChaosEnabled || cfg.EnablePprof || cfg.MetricsDisabled {
+		t.Error("flags booleanas devem vir desligadas por padrão")
+	}
+	if cfg.Tracing.Exporter != telemetry.ExporterOTLP || cfg.Tracing.SampleRatio != 1 || cfg.Tracing.SpanNaming != spanNamingByRoute {
+		t.Errorf("Tracing = %+v, want otlp, ratio 1, spans por rota", cfg.Tracing)
+	}
+}
+
+// Cada variável inválida vira um *config.Error com o nome da variável
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"MAX_INFLIGHT_REQUESTS", "0"},
+		{"ENABLE_H2C", "sim"},
+		{"METRICS_DISABLED", "sim"},
+		{"VALIDATE_UPSTREAM", "yes"},
+		{"UPSTREAM_TLS_MIN_VERSION", "1.1"},
+		{"BATCH_EMPTY_STATUS", "200"},
+		{"WEATHER_CACHE_TTL", "10"},
+		{"ACCESS_LOG_SAMPLE_RATE", "2"},
+		{"RESPONSE_COMPRESSION", "zstd"},
+		{"ROUTE_TIMEOUTS", "/batch"},
+		{"WEATHER_SOFT_FAIL_CODES", "abc"},
+		{"CEP_TEST_MODE_RANGES", "00000999-00000000"},
+		{"WEATHER_API_KEYS", "chaveA:0"},
+		{"WEATHER_API_KEY_FILE", "/nao/existe"},
+		{"TRACE_EXPORTER", "zipkin"},
+		{"SPAN_NAMING", "cidade"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+
+			_, err := LoadConfig()
+			var cfgErr *config.Error
+			if !errors.As(err, &cfgErr) {
+				t.Fatalf("LoadConfig err = %v, want *config.Error", err)
+			}
+			if cfgErr.Var != tt.name {
+				t.Errorf("Var = %q, want %q", cfgErr.Var, tt.name)
+			}
+		})
+	}
+}
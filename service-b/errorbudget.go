@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var errorBudgetAlerts, _ = meter.Int64Counter("upstream.error_budget.alerts",
+	metric.WithDescription("Vezes em que a taxa de erro de um upstream ultrapassou o limite"),
+	metric.WithUnit("{alert}"),
+)
+
+// Orçamento de erros de um upstream: taxa de falhas numa janela móvel. Ao ultrapassar
+// threshold (com ao menos minCalls chamadas na janela) emite um único alerta, no span
+// e no contador upstream.error_budget.alerts; um novo alerta só sai depois que a taxa
+// volta abaixo do limite. Dá aviso antecipado da degradação, antes do breaker abrir
+type errorBudget struct {
+	mu        sync.Mutex
+	upstream  string
+	window    time.Duration
+	threshold float64
+	minCalls  int
+	calls     []errorBudgetCall
+	alerting  bool
+}
+
+type errorBudgetCall struct {
+	at     time.Time
+	failed bool
+}
+
+// threshold <= 0 desabilita o orçamento (nil, que não registra nada)
+func newErrorBudget(upstream string, threshold float64, window time.Duration, minCalls int) *errorBudget {
+	if threshold <= 0 {
+		return nil
+	}
+	return &errorBudget{upstream: upstream, window: window, threshold: threshold, minCalls: minCalls}
+}
+
+// Orçamentos por upstream (UPSTREAM_ERROR_BUDGET_*)
+var (
+	viaCEPErrorBudget     *errorBudget
+	weatherAPIErrorBudget *errorBudget
+)
+
+// Falha para o orçamento: erro de rede, 5xx ou 429. 404 e afins são respostas válidas, e
+// o limite local de conexões (UPSTREAM_MAX_CONNS_PER_HOST) não é falha do upstream
+func isUpstreamFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, errUpstreamSaturated)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// Registra o resultado de uma chamada. Chamadas interrompidas pelo contexto da
+// requisição (cliente desconectado, prazo) não contam
+func (b *errorBudget) record(ctx context.Context, resp *http.Response, err error) {
+	if b == nil || ctx.Err() != nil {
+		return
+	}
+	now := time.Now()
+
+	b.mu.Lock()
+	b.calls = append(b.calls, errorBudgetCall{at: now, failed: isUpstreamFailure(resp, err)})
+	cut := 0
+	for cut < len(b.calls) && now.Sub(b.calls[cut].at) > b.window {
+		cut++
+	}
+	b.calls = b.calls[cut:]
+
+	failed := 0
+	for _, c := range b.calls {
+		if c.failed {
+			failed++
+		}
+	}
+	calls := len(b.calls)
+	rate := float64(failed) / float64(calls)
+	exceeded := calls >= b.minCalls && rate >= b.threshold
+
+	alert, recovered := exceeded && !b.alerting, !exceeded && b.alerting
+	b.alerting = exceeded
+	b.mu.Unlock()
+
+	if !alert && !recovered {
+		return
+	}
+	attrs := trace.WithAttributes(
+		attribute.String("upstream", b.upstream),
+		attribute.Float64("upstream.error_rate", rate),
+		attribute.Float64("upstream.error_budget_threshold", b.threshold),
+		attribute.Int("upstream.window_calls", calls),
+	)
+	span := trace.SpanFromContext(ctx)
+	if alert {
+		span.AddEvent("upstream_error_budget_exceeded", attrs)
+		errorBudgetAlerts.Add(ctx, 1, metric.WithAttributes(attribute.String("upstream", b.upstream)))
+		return
+	}
+	span.AddEvent("upstream_error_budget_recovered", attrs)
+}
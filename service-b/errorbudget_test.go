@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIsUpstreamFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *http.Response
+		err  error
+		want bool
+	}{
+		{"erro de rede", nil, errors.New("connection refused"), true},
+		{"limite local de conexões", nil, errUpstreamSaturated, false},
+		{"200", &http.Response{StatusCode: http.StatusOK}, nil, false},
+		{"404", &http.Response{StatusCode: http.StatusNotFound}, nil, false},
+		{"429", &http.Response{StatusCode: http.StatusTooManyRequests}, nil, true},
+		{"503", &http.Response{StatusCode: http.StatusServiceUnavailable}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUpstreamFailure(tt.resp, tt.err); got != tt.want {
+				t.Errorf("isUpstreamFailure = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorBudgetRecord(t *testing.T) {
+	ok := &http.Response{StatusCode: http.StatusOK}
+	fail := &http.Response{StatusCode: http.StatusBadGateway}
+
+	tests := []struct {
+		name         string
+		calls        []*http.Response
+		wantAlerting bool
+	}{
+		{"abaixo do mínimo de chamadas", []*http.Response{fail, fail, fail}, false},
+		{"taxa abaixo do limite", []*http.Response{ok, ok, ok, fail}, false},
+		{"taxa no limite", []*http.Response{ok, ok, fail, fail}, true},
+		{"recupera abaixo do limite", []*http.Response{fail, fail, fail, fail, ok, ok, ok, ok, ok}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newErrorBudget("weatherapi", 0.5, time.Minute, 4)
+			for _, resp := range tt.calls {
+				b.record(context.Background(), resp, nil)
+			}
+			if b.alerting != tt.wantAlerting {
+				t.Errorf("alerting = %v, want %v", b.alerting, tt.wantAlerting)
+			}
+		})
+	}
+}
+
+// Chamadas antigas saem da janela e chamadas interrompidas pelo cliente não contam
+func TestErrorBudgetWindow(t *testing.T) {
+	fail := &http.Response{StatusCode: http.StatusBadGateway}
+	b := newErrorBudget("viacep", 0.5, time.Minute, 1)
+
+	b.record(context.Background(), fail, nil)
+	b.calls[0].at = time.Now().Add(-2 * time.Minute)
+	b.record(context.Background(), &http.Response{StatusCode: http.StatusOK}, nil)
+	if len(b.calls) != 1 || b.alerting {
+		t.Errorf("%d chamadas na janela, alerting %v; want 1, false", len(b.calls), b.alerting)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.record(ctx, nil, context.Canceled)
+	if len(b.calls) != 1 {
+		t.Errorf("chamada cancelada registrada: %d chamadas", len(b.calls))
+	}
+
+	if newErrorBudget("viacep", 0, time.Minute, 1) != nil {
+		t.Error("threshold 0 deve desabilitar o orçamento")
+	}
+}
+
+// Com o ViaCEP falhando, o evento de alerta sai uma única vez, na chamada que cruza o
+// limite; quando ele volta a responder, sai o evento de recuperação
+func TestWeatherHandlerErrorBudgetAlert(t *testing.T) {
+	defer func(b *errorBudget) { viaCEPErrorBudget = b }(viaCEPErrorBudget)
+	viaCEPErrorBudget = newErrorBudget(cepProviderViaCEP, 0.5, time.Minute, 2)
+
+	var failing atomic.Bool
+	failing.Store(true)
+	withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		mockViaCEP().ServeHTTP(w, r)
+	}), mockWeatherAPI(23.5))
+	sr := withSpanRecorder(t)
+
+	// Quantas vezes cada evento saiu nos spans gravados desde a última chamada
+	events := func() map[string]int {
+		counts := map[string]int{}
+		for _, s := range sr.Ended() {
+			for _, e := range s.Events() {
+				counts[e.Name]++
+			}
+		}
+		sr.Reset()
+		return counts
+	}
+
+	tests := []struct {
+		name          string
+		failing       bool
+		wantExceeded  int
+		wantRecovered int
+	}{
+		{"primeira falha, abaixo do mínimo", true, 0, 0},
+		{"segunda falha cruza o limite", true, 1, 0},
+		{"terceira falha não repete o alerta", true, 0, 0},
+		{"3 de 4", false, 0, 0},
+		{"3 de 5", false, 0, 0},
+		{"3 de 6, ainda no limite", false, 0, 0},
+		{"3 de 7, abaixo do limite", false, 0, 1},
+	}
+	for i, tt := range tests {
+		failing.Store(tt.failing)
+		rec := httptest.NewRecorder()
+		newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+
+		got := events()
+		if got["upstream_error_budget_exceeded"] != tt.wantExceeded || got["upstream_error_budget_recovered"] != tt.wantRecovered {
+			t.Errorf("chamada %d (%s): eventos = %v, want %d alerta(s) e %d recuperação(ões)",
+				i+1, tt.name, got, tt.wantExceeded, tt.wantRecovered)
+		}
+	}
+}
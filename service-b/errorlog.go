@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Erro devolvido a um cliente, guardado para triagem via /admin/errors
+type errorEvent struct {
+	Time     time.Time `json:"timestamp"`
+	TraceID  string    `json:"trace_id,omitempty"`
+	Status   int       `json:"status"`
+	Message  string    `json:"message"`
+	Upstream string    `json:"upstream,omitempty"`
+}
+
+// Buffer circular com os últimos erros (ADMIN_ERRORS_SIZE)
+type errorRing struct {
+	mu     sync.Mutex
+	events []errorEvent
+	next   int
+	full   bool
+}
+
+var recentErrors = newErrorRing(50)
+
+func newErrorRing(size int) *errorRing {
+	return &errorRing{events: make([]errorEvent, size)}
+}
+
+func (r *errorRing) add(e errorEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events[r.next] = e
+	r.next = (r.next + 1) % len(r.events)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Erros guardados, do mais recente para o mais antigo
+func (r *errorRing) recent() []errorEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.next
+	if r.full {
+		n = len(r.events)
+	}
+	out := make([]errorEvent, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, r.events[(r.next-i+len(r.events))%len(r.events)])
+	}
+	return out
+}
+
+func traceIDOf(span trace.Span) string {
+	if sc := span.SpanContext(); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
+
+// GET /admin/errors: últimos erros, exige "Authorization: Bearer <ADMIN_TOKEN>"
+func adminErrorsHandler(token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !adminAuthorized(w, r, token) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(recentErrors.recent())
+	}
+}
+
+// Confere "Authorization: Bearer <ADMIN_TOKEN>"; sem o token, responde 401
+func adminAuthorized(w http.ResponseWriter, r *http.Request, token string) bool {
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		writeError(w, trace.SpanFromContext(r.Context()), http.StatusUnauthorized, "unauthorized")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorRingRecent(t *testing.T) {
+	tests := []struct {
+		name  string
+		added int
+		want  []int // status dos eventos devolvidos, em ordem
+	}{
+		{"vazio", 0, []int{}},
+		{"parcial", 2, []int{401, 400}},
+		{"cheio", 3, []int{402, 401, 400}},
+		{"sobrescreve os mais antigos", 5, []int{404, 403, 402}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newErrorRing(3)
+			for i := 0; i < tt.added; i++ {
+				r.add(errorEvent{Status: 400 + i})
+			}
+			got := r.recent()
+			if len(got) != len(tt.want) {
+				t.Fatalf("recent = %v, want status %v", got, tt.want)
+			}
+			for i, e := range got {
+				if e.Status != tt.want[i] {
+					t.Errorf("recent[%d].Status = %d, want %d", i, e.Status, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// Erros devolvidos pelo handler aparecem em /admin/errors, do mais recente para o mais
+// antigo, com o trace de cada requisição
+func TestAdminErrorsHandler(t *testing.T) {
+	defer func(r *errorRing) { recentErrors = r }(recentErrors)
+	recentErrors = newErrorRing(10)
+
+	withMockUpstreams(t, mockViaCEP(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error":{"code":1006,"message":"No matching location found."}}`)
+	}))
+	sr := withSpanRecorder(t)
+	router := newWeatherRouter()
+
+	var traces []string
+	for _, path := range []string{"/123", "/01001000"} {
+		sr.Reset()
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		traces = append(traces, endedSpan(t, sr, "weather_handler").SpanContext().TraceID().String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/errors", nil)
+	req.Header.Set("Authorization", "Bearer segredo")
+	rec := httptest.NewRecorder()
+	adminErrorsHandler("segredo")(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	var events []errorEvent
+	if err := json.NewDecoder(rec.Body).Decode(&events); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("eventos = %+v, want 2", events)
+	}
+	newest, oldest := events[0], events[1]
+	if newest.Status != http.StatusNotFound || newest.Upstream != weatherProviderWeatherAPI || newest.TraceID != traces[1] {
+		t.Errorf("mais recente = %+v, want 404 da weatherapi no trace %s", newest, traces[1])
+	}
+	if oldest.Status != http.StatusUnprocessableEntity || oldest.Message != "invalid zipcode" || oldest.TraceID != traces[0] {
+		t.Errorf("mais antigo = %+v, want 422 invalid zipcode no trace %s", oldest, traces[0])
+	}
+	if newest.Time.Before(oldest.Time) {
+		t.Errorf("ordem: %v antes de %v", newest.Time, oldest.Time)
+	}
+}
+
+func TestAdminErrorsHandlerRequiresToken(t *testing.T) {
+	for _, auth := range []string{"", "Bearer outro", "segredo", "Basic segredo"} {
+		t.Run(auth, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/errors", nil)
+			if auth != "" {
+				req.Header.Set("Authorization", auth)
+			}
+			rec := httptest.NewRecorder()
+			adminErrorsHandler("segredo")(rec, req)
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want 401", rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Campos que podem ser pedidos via ?fields=, pelo nome da chave JSON
+var responseFields = map[string]func(TemperatureResponse) any{
+	"city":   func(r TemperatureResponse) any { return r.City },
+	"region": func(r TemperatureResponse) any { return r.Region },
+	"uf":     func(r TemperatureResponse) any { return r.UF },
+	"temp_C": func(r TemperatureResponse) any { return r.TempC },
+	"temp_F": func(r TemperatureResponse) any { return r.TempF },
+	"temp_K": func(r TemperatureResponse) any { return r.TempK },
+
+	"primary_unit": func(r TemperatureResponse) any { return r.PrimaryUnit },
+	"generated_at": func(r TemperatureResponse) any { return r.GeneratedAt },
+}
+
+// Interpreta ?fields=temp_C,temp_F. Retorna nil se o parâmetro não foi informado
+// e erro para campos desconhecidos
+func parseFields(param string) ([]string, error) {
+	if param == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	for _, f := range strings.Split(param, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if _, ok := responseFields[f]; !ok {
+			return nil, fmt.Errorf("unknown field: %s", f)
+		}
+		fields = append(fields, f)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty fields list")
+	}
+	return fields, nil
+}
+
+// Monta a resposta apenas com os campos pedidos
+func selectFields(resp TemperatureResponse, fields []string) map[string]any {
+	out := make(map[string]any, len(fields))
+	for _, f := range fields {
+		out[f] = responseFields[f](resp)
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		param   string
+		want    []string
+		wantErr bool
+	}{
+		{"ausente", "", nil, false},
+		{"um campo", "temp_C", []string{"temp_C"}, false},
+		{"vários com espaços", "city, temp_C ,temp_K", []string{"city", "temp_C", "temp_K"}, false},
+		{"campos vazios ignorados", ",city,", []string{"city"}, false},
+		{"diferencia maiúsculas", "temp_c", nil, true},
+		{"desconhecido", "city,lat", nil, true},
+		{"lista vazia", " , ", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFields(tt.param)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseFields(%q) = %v, want %v", tt.param, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	resp := TemperatureResponse{City: "São Paulo", UF: "SP", TempC: 25, TempF: 77, TempK: 298}
+	got := selectFields(resp, []string{"city", "temp_K"})
+	if len(got) != 2 || got["city"] != "São Paulo" || got["temp_K"] != Temperature(298) {
+		t.Errorf("selectFields = %v, want city e temp_K", got)
+	}
+}
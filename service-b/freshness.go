@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Intervalo de atualização das leituras da WeatherAPI; define por quanto tempo
+// uma leitura ainda é a mais recente
+var weatherUpdateInterval = 15 * time.Minute
+
+// Cache-Control da resposta de clima: max-age é o tempo que falta para a próxima
+// atualização da WeatherAPI, ex.: leitura de 8 min com intervalo de 15 min => max-age=420.
+// Sem o horário da leitura, ou já vencida, o cliente deve revalidar (max-age=0)
+func weatherCacheControl(lastUpdatedEpoch int, now time.Time) string {
+	if lastUpdatedEpoch <= 0 {
+		return "max-age=0"
+	}
+
+	age := now.Sub(time.Unix(int64(lastUpdatedEpoch), 0))
+	remaining := weatherUpdateInterval - max(age, 0)
+	if remaining <= 0 {
+		return "max-age=0"
+	}
+	return fmt.Sprintf("max-age=%d", int(remaining/time.Second))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWeatherCacheControl(t *testing.T) {
+	defer func(d time.Duration) { weatherUpdateInterval = d }(weatherUpdateInterval)
+	weatherUpdateInterval = 15 * time.Minute
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	epoch := func(ago time.Duration) int { return int(now.Add(-ago).Unix()) }
+
+	tests := []struct {
+		name  string
+		epoch int
+		want  string
+	}{
+		{"sem horário da leitura", 0, "max-age=0"},
+		{"epoch negativo", -1, "max-age=0"},
+		{"leitura recém-feita", epoch(0), "max-age=900"},
+		{"leitura de 8 min", epoch(8 * time.Minute), "max-age=420"},
+		{"leitura vencendo agora", epoch(15 * time.Minute), "max-age=0"},
+		{"leitura vencida", epoch(time.Hour), "max-age=0"},
+		{"leitura no futuro", epoch(-time.Minute), "max-age=900"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := weatherCacheControl(tt.epoch, now); got != tt.want {
+				t.Errorf("weatherCacheControl(%d) = %q, want %q", tt.epoch, got, tt.want)
+			}
+		})
+	}
+}
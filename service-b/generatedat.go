@@ -0,0 +1,24 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// Quando incluir generated_at nas respostas (GENERATED_AT)
+const (
+	generatedAtRequest = "request" // só com ?generated_at=true
+	generatedAtAlways  = "always"
+)
+
+var generatedAtMode = generatedAtRequest
+
+// Horário em que a resposta foi montada (RFC3339, UTC), para o cliente avaliar o quão
+// recente é o dado entregue em relação ao próprio relógio. Diferente do last_updated da
+// WeatherAPI, que é o horário da leitura. Vazio (campo omitido) se não foi pedido
+func generatedAt(r *http.Request, now time.Time) string {
+	if generatedAtMode != generatedAtAlways && r.URL.Query().Get("generated_at") != "true" {
+		return ""
+	}
+	return now.UTC().Format(time.RFC3339)
+}
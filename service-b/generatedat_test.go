@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// generated_at só com ?generated_at=true, salvo com GENERATED_AT=always; sempre em UTC
+func TestGeneratedAt(t *testing.T) {
+	defer func(m string) { generatedAtMode = m }(generatedAtMode)
+	now := time.Date(2026, 7, 15, 9, 30, 0, 0, time.FixedZone("BRT", -3*60*60))
+
+	tests := []struct {
+		name  string
+		mode  string
+		query string
+		want  string
+	}{
+		{"request sem parâmetro", generatedAtRequest, "", ""},
+		{"request com parâmetro", generatedAtRequest, "?generated_at=true", "2026-07-15T12:30:00Z"},
+		{"request com false", generatedAtRequest, "?generated_at=false", ""},
+		{"always sem parâmetro", generatedAtAlways, "", "2026-07-15T12:30:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generatedAtMode = tt.mode
+			if got := generatedAt(httptest.NewRequest(http.MethodGet, "/01001000"+tt.query, nil), now); got != tt.want {
+				t.Errorf("generatedAt = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Quando pedido, generated_at está presente em /{cep} (também com ?fields= e ?single=)
+// e é um RFC3339 com o horário em que a resposta foi montada; senão é omitido
+func TestWeatherHandlerGeneratedAt(t *testing.T) {
+	defer func(m string) { generatedAtMode = m }(generatedAtMode)
+	withMockUpstreams(t, mockViaCEP(), mockWeatherAPI(23.5))
+
+	tests := []struct {
+		name    string
+		mode    string
+		query   string
+		present bool
+	}{
+		{"padrão", generatedAtRequest, "", false},
+		{"pedido", generatedAtRequest, "?generated_at=true", true},
+		{"pedido com fields", generatedAtRequest, "?generated_at=true&fields=temp_C,generated_at", true},
+		{"always", generatedAtAlways, "", true},
+		{"always com single", generatedAtAlways, "?single=true", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generatedAtMode = tt.mode
+			before := time.Now().Truncate(time.Second)
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000"+tt.query, nil))
+			after := time.Now()
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body)
+			}
+
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			raw, ok := body["generated_at"]
+			if ok != tt.present {
+				t.Fatalf("generated_at presente = %v, want %v; body = %s", ok, tt.present, rec.Body)
+			}
+			if !tt.present {
+				return
+			}
+
+			s, _ := raw.(string)
+			got, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				t.Fatalf("generated_at = %v, não é RFC3339: %v", raw, err)
+			}
+			if got.Location() != time.UTC {
+				t.Errorf("generated_at = %q, want UTC", s)
+			}
+			if got.Before(before) || got.After(after) {
+				t.Errorf("generated_at = %v, want entre %v e %v", got, before, after)
+			}
+		})
+	}
+}
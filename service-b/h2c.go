@@ -0,0 +1,14 @@
+package main
+
+import (
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// Aceita HTTP/2 sem TLS (h2c), usado entre sidecars de alguns service meshes. Tanto o
+// upgrade via HTTP/1.1 quanto o prior knowledge são aceitos; HTTP/1.1 continua atendido
+func withH2C(handler http.Handler) http.Handler {
+	return h2c.NewHandler(handler, &http2.Server{})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/http2"
+)
+
+// Cliente HTTP/2 sem TLS, com prior knowledge
+func h2cClient() *http.Client {
+	return &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+}
+
+// Com ENABLE_H2C o servidor atende HTTP/2 sem TLS e continua atendendo HTTP/1.1; sem
+// ele, a requisição h2c é recusada
+func TestNewHandlerH2C(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+	}{
+		{"habilitado", true},
+		{"desabilitado", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.EnableH2C = tt.enabled
+			srv := httptest.NewServer(newHandler(cfg))
+			defer srv.Close()
+
+			resp, err := h2cClient().Get(srv.URL + "/health")
+			if !tt.enabled {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatalf("h2c aceito com ENABLE_H2C=false: %s", resp.Proto)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("h2c: %v", err)
+				}
+				resp.Body.Close()
+				if resp.ProtoMajor != 2 || resp.StatusCode != http.StatusOK {
+					t.Errorf("h2c: %s %d, want HTTP/2.0 200", resp.Proto, resp.StatusCode)
+				}
+			}
+
+			resp, err = http.Get(srv.URL + "/health")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.ProtoMajor != 1 || resp.StatusCode != http.StatusOK {
+				t.Errorf("HTTP/1.1: %s %d, want HTTP/1.1 200", resp.Proto, resp.StatusCode)
+			}
+		})
+	}
+}
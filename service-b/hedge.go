@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Atraso até enviar a requisição de hedge à WeatherAPI; 0 desabilita
+var weatherHedgeDelay time.Duration
+
+// Resultado de uma das requisições concorrentes do hedge
+type hedgeResult struct {
+	leg  int
+	resp *http.Response
+	err  error
+}
+
+// Executa a requisição e, se ela não responder em delay, envia uma segunda idêntica.
+// A primeira resposta bem-sucedida vence e a outra é cancelada. Corta a latência de
+// cauda ao custo de carga extra no upstream
+func doWithHedge(ctx context.Context, req *http.Request, delay time.Duration) (*http.Response, error) {
+	if delay <= 0 {
+		return doWithRetry(ctx, req)
+	}
+
+	span := trace.SpanFromContext(ctx)
+	results := make(chan hedgeResult, 2)
+	var cancels []context.CancelFunc
+
+	launch := func() {
+		legCtx, cancel := context.WithCancel(ctx)
+		leg := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := doWithRetry(legCtx, req)
+			results <- hedgeResult{leg: leg, resp: resp, err: err}
+		}()
+	}
+
+	launch()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	pending := 1
+	for {
+		select {
+		case <-timer.C:
+			span.AddEvent("hedge", trace.WithAttributes(attribute.Int64("hedge.delay_ms", delay.Milliseconds())))
+			launch()
+			pending++
+			continue
+		case res := <-results:
+			pending--
+			if res.err != nil && pending > 0 {
+				// Falhou, mas a outra requisição ainda pode responder
+				continue
+			}
+
+			// Cancela as demais e descarta o que ainda chegar delas
+			for leg, cancel := range cancels {
+				if leg != res.leg {
+					cancel()
+				}
+			}
+			go drainHedgeResults(results, pending)
+
+			span.SetAttributes(
+				attribute.Bool("hedge.fired", len(cancels) > 1),
+				attribute.Bool("hedge.won", res.leg > 0),
+			)
+			if res.err != nil {
+				cancels[res.leg]()
+				return nil, res.err
+			}
+
+			// O contexto da requisição vencedora só é cancelado ao fechar o body
+			res.resp.Body = &cancelOnClose{ReadCloser: res.resp.Body, cancel: cancels[res.leg]}
+			return res.resp, nil
+		}
+	}
+}
+
+func drainHedgeResults(results <-chan hedgeResult, pending int) {
+	for ; pending > 0; pending-- {
+		if res := <-results; res.resp != nil {
+			io.Copy(io.Discard, res.resp.Body)
+			res.resp.Body.Close()
+		}
+	}
+}
+
+// Body que cancela o contexto da requisição ao ser fechado
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Upstream em que a primeira requisição só termina quando é cancelada e as demais
+// respondem na hora
+type slowFirstUpstream struct {
+	mu        sync.Mutex
+	calls     []time.Duration // momento de cada chamada, desde start
+	start     time.Time
+	cancelled chan struct{}
+	firstWait time.Duration
+}
+
+func (u *slowFirstUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.mu.Lock()
+	u.calls = append(u.calls, time.Since(u.start))
+	n := len(u.calls)
+	u.mu.Unlock()
+
+	if n == 1 {
+		select {
+		case <-r.Context().Done():
+			close(u.cancelled)
+			return
+		case <-time.After(u.firstWait):
+		}
+	}
+	io.WriteString(w, "ok")
+}
+
+func (u *slowFirstUpstream) callTimes() []time.Duration {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]time.Duration(nil), u.calls...)
+}
+
+func TestDoWithHedge(t *testing.T) {
+	defer func(c *http.Client) { httpClient = c }(httpClient)
+	httpClient = &http.Client{Timeout: 5 * time.Second}
+
+	const delay = 50 * time.Millisecond
+
+	t.Run("resposta antes do atraso não dispara o hedge", func(t *testing.T) {
+		upstream := &slowFirstUpstream{start: time.Now(), cancelled: make(chan struct{}), firstWait: 0}
+		srv := httptest.NewServer(upstream)
+		defer srv.Close()
+
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		resp, err := doWithHedge(context.Background(), req, delay)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		time.Sleep(2 * delay)
+		if calls := upstream.callTimes(); len(calls) != 1 {
+			t.Errorf("chamadas = %d, want 1", len(calls))
+		}
+	})
+
+	t.Run("hedge após o atraso vence e cancela a lenta", func(t *testing.T) {
+		upstream := &slowFirstUpstream{start: time.Now(), cancelled: make(chan struct{}), firstWait: 5 * time.Second}
+		srv := httptest.NewServer(upstream)
+		defer srv.Close()
+
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		resp, err := doWithHedge(context.Background(), req, delay)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "ok" {
+			t.Errorf("body = %q, want ok", body)
+		}
+
+		calls := upstream.callTimes()
+		if len(calls) != 2 {
+			t.Fatalf("chamadas = %d, want 2", len(calls))
+		}
+		if calls[1] < delay {
+			t.Errorf("hedge enviado após %v, antes do atraso de %v", calls[1], delay)
+		}
+
+		select {
+		case <-upstream.cancelled:
+		case <-time.After(time.Second):
+			t.Error("requisição lenta não foi cancelada")
+		}
+	})
+
+	t.Run("atraso zero desabilita", func(t *testing.T) {
+		upstream := &slowFirstUpstream{start: time.Now(), cancelled: make(chan struct{}), firstWait: 2 * delay}
+		srv := httptest.NewServer(upstream)
+		defer srv.Close()
+
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		resp, err := doWithHedge(context.Background(), req, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if calls := upstream.callTimes(); len(calls) != 1 {
+			t.Errorf("chamadas = %d, want 1", len(calls))
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Origem da temperatura quando não é uma leitura da WeatherAPI
+const sourceHistoricalAverage = "historical_average"
+
+// Médias mensais de temperatura das capitais, por UF (normais climatológicas aproximadas)
+//
+//go:embed data/historical_averages.json
+var historicalAveragesJSON []byte
+
+type historicalAverage struct {
+	City         string      `json:"city"`
+	Region       string      `json:"region"`
+	MonthlyTempC [12]float64 `json:"monthly_temp_c"`
+}
+
+var historicalAverages = mustLoadHistoricalAverages(historicalAveragesJSON)
+
+func mustLoadHistoricalAverages(data []byte) map[string]historicalAverage {
+	var averages map[string]historicalAverage
+	if err := json.Unmarshal(data, &averages); err != nil {
+		panic("data/historical_averages.json: " + err.Error())
+	}
+	return averages
+}
+
+// Com a WeatherAPI indisponível, responde com a média histórica (HISTORICAL_FALLBACK)
+var historicalFallback bool
+
+// Clima substituto com a média histórica do mês para a UF do CEP, para manter a API
+// respondendo durante falhas da WeatherAPI em usos não críticos. A média é a da capital
+// da UF; a resposta leva source=historical_average. false se desabilitado ou sem dados
+func historicalWeather(span trace.Span, cepInfo *CEP, now time.Time) (*WeatherData, bool) {
+	if !historicalFallback {
+		return nil, false
+	}
+	avg, ok := historicalAverages[strings.ToUpper(cepInfo.Uf)]
+	if !ok {
+		return nil, false
+	}
+
+	var weather WeatherData
+	weather.Location.Name = cepInfo.Localidade
+	weather.Location.Region = avg.Region
+	weather.Location.Country = "Brazil"
+	weather.Current.TempC = avg.MonthlyTempC[now.Month()-1]
+	weather.Current.TempF = celsiusToFahrenheit(weather.Current.TempC)
+
+	span.SetAttributes(
+		attribute.String("weather.source", sourceHistoricalAverage),
+		attribute.String("weather.historical_reference", avg.City),
+		attribute.Int("weather.historical_month", int(now.Month())),
+	)
+	span.AddEvent("weather_historical_fallback")
+	return &weather, true
+}
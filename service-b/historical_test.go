@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// O dataset embutido cobre todas as UFs, com a capital como referência e 12 médias
+// plausíveis
+func TestHistoricalAveragesDataset(t *testing.T) {
+	if len(historicalAverages) != len(capitalByUF) {
+		t.Errorf("UFs no dataset = %d, want %d", len(historicalAverages), len(capitalByUF))
+	}
+	for uf, capital := range capitalByUF {
+		avg, ok := historicalAverages[uf]
+		if !ok {
+			t.Errorf("UF %s ausente do dataset", uf)
+			continue
+		}
+		if avg.City != capital {
+			t.Errorf("%s: city = %q, want %q", uf, avg.City, capital)
+		}
+		for month, temp := range avg.MonthlyTempC {
+			if temp < 5 || temp > 35 {
+				t.Errorf("%s, mês %d: %.1f °C fora do esperado", uf, month+1, temp)
+			}
+		}
+	}
+}
+
+func TestHistoricalWeather(t *testing.T) {
+	defer func(b bool) { historicalFallback = b }(historicalFallback)
+	july := time.Date(2025, time.July, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		enabled  bool
+		cep      CEP
+		wantOK   bool
+		wantTemp float64
+	}{
+		{"desabilitado", false, mockCEP, false, 0},
+		{"SP em julho", true, mockCEP, true, 16.7},
+		{"UF minúscula", true, CEP{Localidade: "Niterói", Uf: "rj"}, true, historicalAverages["RJ"].MonthlyTempC[6]},
+		{"sem UF", true, CEP{Localidade: "Vila Perdida"}, false, 0},
+		{"UF desconhecida", true, CEP{Localidade: "Vila Perdida", Uf: "XX"}, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			historicalFallback = tt.enabled
+			sr := withSpanRecorder(t)
+			_, span := tracer.Start(context.Background(), "handler")
+			weather, ok := historicalWeather(span, &tt.cep, july)
+			span.End()
+
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if weather.Current.TempC != tt.wantTemp || weather.Location.Name != tt.cep.Localidade {
+				t.Errorf("clima = %s a %.1f °C, want %s a %.1f °C", weather.Location.Name, weather.Current.TempC, tt.cep.Localidade, tt.wantTemp)
+			}
+			ended := endedSpan(t, sr, "handler")
+			if got := spanAttr(ended, "weather.source").AsString(); got != sourceHistoricalAverage {
+				t.Errorf("weather.source = %q, want %q", got, sourceHistoricalAverage)
+			}
+			if got := spanAttr(ended, "weather.historical_month").AsInt64(); got != 7 {
+				t.Errorf("weather.historical_month = %d, want 7", got)
+			}
+		})
+	}
+}
+
+// Com HISTORICAL_FALLBACK=true, uma falha da WeatherAPI ou um código de
+// WEATHER_SOFT_FAIL_CODES responde com a média do mês, sinalizada em source; sem ele,
+// os mesmos casos respondem 500 e 502
+func TestWeatherHandlerHistoricalFallback(t *testing.T) {
+	defer func(b bool, codes map[int]bool, status int) {
+		historicalFallback, weatherSoftFailCodes, weatherSoftFailStatus = b, codes, status
+	}(historicalFallback, weatherSoftFailCodes, weatherSoftFailStatus)
+	weatherSoftFailStatus = http.StatusBadGateway
+
+	failing := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, `{"error":{"code":9999,"message":"Internal application error."}}`)
+	})
+
+	tests := []struct {
+		name       string
+		weatherapi http.Handler
+		softFail   map[int]bool
+		enabled    bool
+		wantStatus int
+	}{
+		{"WeatherAPI fora", failing, nil, true, http.StatusOK},
+		{"WeatherAPI fora sem fallback", failing, nil, false, http.StatusInternalServerError},
+		{"código de falha branda", mockWeatherAPI(35), map[int]bool{1000: true}, true, http.StatusOK},
+		{"código de falha branda sem fallback", mockWeatherAPI(35), map[int]bool{1000: true}, false, http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			historicalFallback, weatherSoftFailCodes = tt.enabled, tt.softFail
+			withMockUpstreams(t, mockViaCEP(), tt.weatherapi)
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var body TemperatureResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			want := historicalAverages["SP"].MonthlyTempC[time.Now().Month()-1]
+			if body.Source != sourceHistoricalAverage || float64(body.TempC) != want || body.City != mockCEP.Localidade {
+				t.Errorf("resposta = %+v, want média histórica de %.1f °C em %s", body, want, mockCEP.Localidade)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dados do CEP que podem ser acrescentados à resposta via ?include=, já obtidos do
+// ViaCEP na resolução do CEP (sem chamada extra)
+var includeFields = map[string]func(resp *TemperatureResponse, cep *CEP){
+	"ddd":   func(resp *TemperatureResponse, cep *CEP) { resp.DDD = cep.Ddd },
+	"ibge":  func(resp *TemperatureResponse, cep *CEP) { resp.IBGE = cep.Ibge },
+	"siafi": func(resp *TemperatureResponse, cep *CEP) { resp.Siafi = cep.Siafi },
+}
+
+// Interpreta ?include=ddd,ibge. Retorna nil se o parâmetro não foi informado e erro
+// para itens desconhecidos
+func parseInclude(param string) ([]string, error) {
+	if param == "" {
+		return nil, nil
+	}
+
+	var include []string
+	for _, f := range strings.Split(param, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if f == "" {
+			continue
+		}
+		if _, ok := includeFields[f]; !ok {
+			return nil, fmt.Errorf("unknown include: %s", f)
+		}
+		include = append(include, f)
+	}
+	if len(include) == 0 {
+		return nil, fmt.Errorf("empty include list")
+	}
+	return include, nil
+}
+
+// Copia para a resposta os dados do CEP pedidos. Sem dados do ViaCEP (ex.: caminho
+// direto pela WeatherAPI) os campos ficam vazios e são omitidos
+func applyInclude(resp *TemperatureResponse, cep *CEP, include []string) {
+	if cep == nil {
+		return
+	}
+	for _, f := range include {
+		includeFields[f](resp, cep)
+	}
+}
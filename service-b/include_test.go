@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseInclude(t *testing.T) {
+	tests := []struct {
+		name    string
+		param   string
+		want    []string
+		wantErr bool
+	}{
+		{"ausente", "", nil, false},
+		{"um item", "ddd", []string{"ddd"}, false},
+		{"vários", "ddd,ibge,siafi", []string{"ddd", "ibge", "siafi"}, false},
+		{"maiúsculas e espaços", " DDD , Ibge", []string{"ddd", "ibge"}, false},
+		{"itens vazios ignorados", "ddd,,", []string{"ddd"}, false},
+		{"desconhecido", "ddd,cep", nil, true},
+		{"lista vazia", ",", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseInclude(tt.param)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseInclude(%q) = %v, want %v", tt.param, got, tt.want)
+			}
+		})
+	}
+}
+
+// ?include= acrescenta à resposta os dados do CEP já obtidos do ViaCEP, sem chamada
+// extra; itens desconhecidos ou combinados com ?fields=, ?int= ou ?single= respondem 400
+func TestWeatherHandlerInclude(t *testing.T) {
+	cep := mockCEP
+	cep.Siafi = "7107"
+	var calls atomic.Int32
+	withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		json.NewEncoder(w).Encode(cep)
+	}), mockWeatherAPI(23.5))
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		want       map[string]any // campos incluídos; os ausentes devem ser omitidos
+	}{
+		{"ddd e ibge", "?include=ddd,ibge", http.StatusOK, map[string]any{"ddd": "11", "ibge": "3550308"}},
+		{"siafi", "?include=siafi", http.StatusOK, map[string]any{"siafi": "7107"}},
+		{"sem include", "", http.StatusOK, map[string]any{}},
+		{"desconhecido", "?include=ddd,cep", http.StatusBadRequest, nil},
+		{"com fields", "?include=ddd&fields=temp_C", http.StatusBadRequest, nil},
+		{"com single", "?include=ddd&single=true", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls.Store(0)
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if n := calls.Load(); n != 1 {
+				t.Errorf("chamadas ao ViaCEP = %d, want 1", n)
+			}
+
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range []string{"ddd", "ibge", "siafi"} {
+				if body[key] != tt.want[key] {
+					t.Errorf("%s = %v, want %v", key, body[key], tt.want[key])
+				}
+			}
+			if body["temp_C"] != 23.5 {
+				t.Errorf("temp_C = %v, want 23.5", body["temp_C"])
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import "math"
+
+// Resposta com temperaturas inteiras (?int=true), para clientes que não interpretam
+// números com casas decimais. Arredonda para o inteiro mais próximo, com .5 para
+// longe do zero: 21.5 => 22, -0.5 => -1
+type IntegerTemperatureResponse struct {
+	City  string `json:"city"`
+	TempC int    `json:"temp_C"`
+	TempF int    `json:"temp_F"`
+	TempK int    `json:"temp_K"`
+
+	PrimaryUnit string `json:"primary_unit,omitempty"`
+	GeneratedAt string `json:"generated_at,omitempty"`
+}
+
+func newIntegerResponse(resp TemperatureResponse) IntegerTemperatureResponse {
+	return IntegerTemperatureResponse{
+		City:  resp.City,
+		TempC: int(math.Round(float64(resp.TempC))),
+		TempF: int(math.Round(float64(resp.TempF))),
+		TempK: int(math.Round(float64(resp.TempK))),
+
+		PrimaryUnit: resp.PrimaryUnit,
+		GeneratedAt: resp.GeneratedAt,
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// .5 arredonda para longe do zero, nos dois sinais
+func TestNewIntegerResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		temp Temperature
+		want int
+	}{
+		{"21.5 sobe", 21.5, 22},
+		{"22.5 sobe", 22.5, 23},
+		{"21.49 desce", 21.49, 21},
+		{"-0.5 desce", -0.5, -1},
+		{"-1.5 desce", -1.5, -2},
+		{"-1.49 sobe", -1.49, -1},
+		{"0.49 é zero", 0.49, 0},
+		{"exato", 25, 25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newIntegerResponse(TemperatureResponse{TempC: tt.temp, TempF: tt.temp, TempK: tt.temp})
+			if got.TempC != tt.want || got.TempF != tt.want || got.TempK != tt.want {
+				t.Errorf("newIntegerResponse(%v) = %d/%d/%d, want %d", float64(tt.temp), got.TempC, got.TempF, got.TempK, tt.want)
+			}
+		})
+	}
+}
+
+// ?int=true responde só inteiros, inclusive nas conversões (21.5 °C = 70.7 °F = 294.65 K),
+// e recusa combinações com fields e verbose
+func TestWeatherHandlerInteger(t *testing.T) {
+	withMockUpstreams(t, mockViaCEP(), mockWeatherAPI(21.5))
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"?int=true", http.StatusOK, `{"city":"Sao Paulo","temp_C":22,"temp_F":71,"temp_K":295}`},
+		{"?int=true&verbose=true", http.StatusBadRequest, "int cannot be combined with fields or verbose"},
+		{"?int=true&fields=city", http.StatusBadRequest, "int cannot be combined with fields or verbose"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want %s", rec.Body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/afga95/lab-go-otel-zipkin/service-a/server"
+	"github.com/afga95/lab-go-otel-zipkin/shared/types"
+)
+
+// Exporter em memória compartilhado pelos dois serviços. O Shutdown do
+// tracetest.InMemoryExporter descarta os spans; aqui eles ficam para a verificação
+type keepOnShutdown struct {
+	*tracetest.InMemoryExporter
+}
+
+func (keepOnShutdown) Shutdown(context.Context) error { return nil }
+
+// Sobe o Serviço B e o Serviço A em processo, com o ViaCEP e a WeatherAPI simulados, e
+// confere a resposta de ponta a ponta e que os spans dos dois formam um único trace
+func TestIntegrationServiceAToServiceB(t *testing.T) {
+	viacep := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ws/01001000/json/" {
+			w.Write([]byte(`{"erro": true}`))
+			return
+		}
+		json.NewEncoder(w).Encode(types.CEP{
+			Cep: "01001-000", Logradouro: "Praça da Sé", Bairro: "Sé",
+			Localidade: "São Paulo", Uf: "SP", Ibge: "3550308", Ddd: "11",
+		})
+	}))
+	defer viacep.Close()
+
+	var (
+		mu             sync.Mutex
+		weatherQueries []string
+	)
+	weatherapi := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		weatherQueries = append(weatherQueries, r.URL.Query().Get("q"))
+		mu.Unlock()
+		w.Write([]byte(`{
+			"location": {"name": "Sao Paulo", "region": "Sao Paulo", "country": "Brazil"},
+			"current": {"last_updated_epoch": ` + jsonInt(time.Now().Unix()) + `, "temp_c": 25, "condition": {"text": "Sol", "code": 1000}}
+		}`))
+	}))
+	defer weatherapi.Close()
+
+	exporter := keepOnShutdown{tracetest.NewInMemoryExporter()}
+	portB, portA := freePort(t), freePort(t)
+
+	// Configuração de cada serviço lida do ambiente, como em produção
+	t.Setenv("PORT", portB)
+	t.Setenv("VIACEP_BASE_URL", viacep.URL)
+	t.Setenv("WEATHER_API_BASE_URL", weatherapi.URL)
+	t.Setenv("WEATHER_API_KEY", "test-key")
+	t.Setenv("OTEL_BSP_SCHEDULE_DELAY", "10")
+	// Sem collector: a exportação de métricas no encerramento desiste logo
+	t.Setenv("OTEL_EXPORTER_OTLP_METRICS_TIMEOUT", "100")
+	cfgB, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfgB.Tracing.SpanExporter = exporter
+
+	t.Setenv("PORT", portA)
+	t.Setenv("SERVICE_B_URL", "http://localhost:"+portB)
+	cfgA, err := server.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfgA.Tracing.SpanExporter = exporter
+
+	// Ao final, encerra os dois serviços e espera os run retornarem: o Serviço B deixa
+	// shuttingDown marcado, que os demais testes do pacote não esperam
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 2)
+	defer func() {
+		cancel()
+		for i := 0; i < 2; i++ {
+			select {
+			case <-stopped:
+			case <-time.After(15 * time.Second):
+				t.Error("serviço não encerrou")
+			}
+		}
+		shuttingDown.Store(false)
+	}()
+	go func() { stopped <- run(ctx, cfgB) }()
+	waitHealthy(t, portB)
+	go func() { stopped <- server.Run(ctx, cfgA) }()
+	waitHealthy(t, portA)
+
+	resp, err := http.Post("http://localhost:"+portA+"/", "application/json", strings.NewReader(`{"cep":"01001000"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	var body types.TemperatureResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.City != "Sao Paulo" || body.UF != "SP" || body.TempC != 25 || body.TempF != 77 || body.TempK != 298 {
+		t.Errorf("resposta = %+v, want Sao Paulo/SP, 25 °C, 77 °F, 298 K", body)
+	}
+	mu.Lock()
+	if len(weatherQueries) != 1 || weatherQueries[0] != "São Paulo" {
+		t.Errorf("consultas à WeatherAPI = %q, want [São Paulo]", weatherQueries)
+	}
+	mu.Unlock()
+
+	// Os batch processors exportam a cada 10ms: espera os spans de servidor dos dois
+	// serviços (o do otelmux de cada um, com o resource do serviço) e os dos handlers.
+	// Os tracers de pacote (otel.Tracer) delegam ao primeiro provider global registrado,
+	// então no mesmo processo cep_handler sai com o resource do Serviço B
+	spans := waitSpans(t, exporter, func(spans tracetest.SpanStubs) bool {
+		return hasSpan(spans, "service-a", "/") && hasSpan(spans, "service-b", "/{cep}") &&
+			hasSpan(spans, "", "cep_handler") && hasSpan(spans, "", "call_service_b") &&
+			hasSpan(spans, "", "weather_handler")
+	})
+
+	traceID := spans[0].SpanContext.TraceID()
+	ids := make(map[string]bool, len(spans))
+	for _, s := range spans {
+		ids[s.SpanContext.SpanID().String()] = true
+	}
+	roots := 0
+	for _, s := range spans {
+		if s.SpanContext.TraceID() != traceID {
+			t.Errorf("span %q (%s) em outro trace: %s, want %s", s.Name, serviceName(s), s.SpanContext.TraceID(), traceID)
+		}
+		if !s.Parent.IsValid() {
+			roots++
+			continue
+		}
+		if !ids[s.Parent.SpanID().String()] {
+			t.Errorf("span %q (%s) com pai %s fora do trace", s.Name, serviceName(s), s.Parent.SpanID())
+		}
+	}
+	if roots != 1 {
+		t.Errorf("%d spans raiz, want 1", roots)
+	}
+}
+
+func jsonInt(n int64) string {
+	b, _ := json.Marshal(n)
+	return string(b)
+}
+
+func waitSpans(t *testing.T, exporter keepOnShutdown, done func(tracetest.SpanStubs) bool) tracetest.SpanStubs {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if spans := exporter.GetSpans(); done(spans) {
+			return spans
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	spans := exporter.GetSpans()
+	names := make([]string, len(spans))
+	for i, s := range spans {
+		names[i] = serviceName(s) + ":" + s.Name
+	}
+	t.Fatalf("spans esperados não exportados; recebidos: %v", names)
+	return nil
+}
+
+// Span com o nome e, se service não for vazio, o resource do serviço
+func hasSpan(spans tracetest.SpanStubs, service, name string) bool {
+	for _, s := range spans {
+		if s.Name == name && (service == "" || serviceName(s) == service) {
+			return true
+		}
+	}
+	return false
+}
+
+func serviceName(s tracetest.SpanStub) string {
+	for _, kv := range s.Resource.Attributes() {
+		if kv.Key == "service.name" {
+			return kv.Value.AsString()
+		}
+	}
+	return ""
+}
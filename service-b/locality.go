@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Localidades tentadas, em ordem, quando a WeatherAPI não reconhece a do CEP nem
+// "Cidade,UF" (WEATHER_LOCALITY_FALLBACK)
+const (
+	localityFallbackMunicipality = "municipality" // município do código IBGE do CEP
+	localityFallbackCapital      = "capital"      // capital da UF
+)
+
+var weatherLocalityFallback = []string{localityFallbackCapital}
+
+// URL base da API de localidades do IBGE; variável só para testes contra um mock
+var ibgeBaseURL = "https://servicodados.ibge.gov.br"
+
+// Nomes de municípios por código IBGE; não mudam, então o cache é só local
+var municipalityCache Cache[string] = newTTLCache[string](24*time.Hour, defaultCacheMaxEntries)
+
+// Resposta de /localidades/municipios/{id} da API de localidades do IBGE
+type ibgeMunicipality struct {
+	Nome string `json:"nome"`
+}
+
+// Nome do município do código IBGE informado pelo ViaCEP. Um CEP de distrito traz o
+// distrito em localidade, mas o código IBGE do município ao qual pertence. ok é false se
+// o código está vazio ou a consulta falhar
+func municipalityName(ctx context.Context, ibge string) (string, bool) {
+	ibge = strings.TrimSpace(ibge)
+	if ibge == "" {
+		return "", false
+	}
+	if name, ok := municipalityCache.Get(ctx, ibge); ok {
+		return name, true
+	}
+
+	ctx, span := tracer.Start(ctx, "get_municipality")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("api", "ibge"),
+		attribute.String("ibge", ibge),
+	)
+
+	name, err := fetchMunicipalityName(ctx, ibge)
+	if err != nil {
+		logf(ctx, "Erro ao buscar município %s no IBGE: %v", ibge, err)
+		span.RecordError(err)
+		return "", false
+	}
+	municipalityCache.Set(ctx, ibge, name)
+	return name, true
+}
+
+func fetchMunicipalityName(ctx context.Context, ibge string) (string, error) {
+	span := trace.SpanFromContext(ctx)
+	url := fmt.Sprintf("%s/api/v1/localidades/municipios/%s", ibgeBaseURL, ibge)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", fmt.Errorf("erro ao criar request: %w", err)
+	}
+
+	resp, err := doWithRetry(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("erro ao consultar município: %w", err)
+	}
+	defer resp.Body.Close()
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("erro na API do IBGE: status %d", resp.StatusCode)
+	}
+
+	// Código desconhecido responde 200 com um objeto vazio
+	var municipality ibgeMunicipality
+	if err := json.NewDecoder(resp.Body).Decode(&municipality); err != nil {
+		return "", fmt.Errorf("erro ao decodificar município: %w", err)
+	}
+	if strings.TrimSpace(municipality.Nome) == "" {
+		return "", fmt.Errorf("município %s não encontrado", ibge)
+	}
+	return municipality.Nome, nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// CEP de distrito: localidade que a WeatherAPI não reconhece, com o código IBGE do
+// município (Campinas) ao qual pertence
+var districtCEP = CEP{Cep: "13999-001", Localidade: "Distrito Perdido", Uf: "SP", Ibge: "3509502"}
+
+// Aponta a API do IBGE para handler, com o cache de municípios vazio; ambos restaurados
+// ao fim do teste
+func withMockIBGE(tb testing.TB, handler http.Handler) {
+	tb.Helper()
+	srv := httptest.NewServer(handler)
+	prevURL, prevCache := ibgeBaseURL, municipalityCache
+	tb.Cleanup(func() {
+		srv.Close()
+		ibgeBaseURL, municipalityCache = prevURL, prevCache
+	})
+	ibgeBaseURL = srv.URL
+	municipalityCache = newTTLCache[string](24*time.Hour, defaultCacheMaxEntries)
+}
+
+// Responde como a API do IBGE: Campinas para 3509502, objeto vazio para outros códigos
+func mockIBGE(calls *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if strings.HasSuffix(r.URL.Path, "/localidades/municipios/3509502") {
+			io.WriteString(w, `{"id":3509502,"nome":"Campinas"}`)
+			return
+		}
+		io.WriteString(w, `{}`)
+	})
+}
+
+// Os fallbacks entram após Cidade,UF na ordem de WEATHER_LOCALITY_FALLBACK; o município só
+// com código IBGE e a capital só se diferente da própria localidade
+func TestWeatherQueries(t *testing.T) {
+	defer func(f []string) { weatherLocalityFallback = f }(weatherLocalityFallback)
+
+	tests := []struct {
+		name          string
+		fallback      []string
+		cep           CEP
+		want          []string // formulações
+		wantFallbacks []string
+	}{
+		{"município e capital", []string{localityFallbackMunicipality, localityFallbackCapital}, districtCEP,
+			[]string{weatherQueryCity, weatherQueryCityUF, weatherQueryMunicipality, weatherQueryUFCapital},
+			[]string{"", "", localityFallbackMunicipality, localityFallbackCapital}},
+		{"capital e município", []string{localityFallbackCapital, localityFallbackMunicipality}, districtCEP,
+			[]string{weatherQueryCity, weatherQueryCityUF, weatherQueryUFCapital, weatherQueryMunicipality},
+			[]string{"", "", localityFallbackCapital, localityFallbackMunicipality}},
+		{"sem fallback", nil, districtCEP,
+			[]string{weatherQueryCity, weatherQueryCityUF}, []string{"", ""}},
+		{"sem código IBGE", []string{localityFallbackMunicipality}, unknownLocalityCEP,
+			[]string{weatherQueryCity, weatherQueryCityUF}, []string{"", ""}},
+		{"a própria capital", []string{localityFallbackCapital}, mockCEP,
+			[]string{weatherQueryCity, weatherQueryCityUF}, []string{"", ""}},
+		{"sem UF", []string{localityFallbackMunicipality, localityFallbackCapital}, CEP{Localidade: "Distrito Perdido", Ibge: "3509502"},
+			[]string{weatherQueryCity}, []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weatherLocalityFallback = tt.fallback
+			var formulations, fallbacks []string
+			for _, q := range weatherQueries(&tt.cep) {
+				formulations = append(formulations, q.Formulation)
+				fallbacks = append(fallbacks, q.Fallback)
+			}
+			if !slices.Equal(formulations, tt.want) {
+				t.Errorf("formulações = %q, want %q", formulations, tt.want)
+			}
+			if !slices.Equal(fallbacks, tt.wantFallbacks) {
+				t.Errorf("fallbacks = %q, want %q", fallbacks, tt.wantFallbacks)
+			}
+		})
+	}
+}
+
+// O nome do município vem do IBGE e fica em cache; código vazio ou desconhecido não resolve
+func TestMunicipalityName(t *testing.T) {
+	var calls atomic.Int32
+	withMockIBGE(t, mockIBGE(&calls))
+	withMockUpstreams(t, mockViaCEP(), mockWeatherAPI(21))
+
+	ctx := context.Background()
+	for i := range 2 {
+		if name, ok := municipalityName(ctx, "3509502"); !ok || name != "Campinas" {
+			t.Errorf("consulta %d: municipalityName = %q, %v; want Campinas", i+1, name, ok)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("chamadas ao IBGE = %d, want 1 (a segunda vem do cache)", n)
+	}
+
+	if name, ok := municipalityName(ctx, "9999999"); ok {
+		t.Errorf("código desconhecido: municipalityName = %q, want não resolvido", name)
+	}
+	calls.Store(0)
+	if name, ok := municipalityName(ctx, " "); ok || calls.Load() != 0 {
+		t.Errorf("código vazio: municipalityName = %q, %v com %d chamada(s), want não resolvido sem chamada", name, ok, calls.Load())
+	}
+}
+
+// Quando a localidade do CEP (e Cidade,UF) não é reconhecida, a consulta segue os fallbacks
+// configurados até um funcionar, registrado em weather.locality_fallback. O IBGE só é
+// consultado se chegar a vez do município, e uma falha nele passa ao próximo fallback
+func TestWeatherHandlerLocalityFallback(t *testing.T) {
+	defer func(f []string) { weatherLocalityFallback = f }(weatherLocalityFallback)
+
+	tests := []struct {
+		name         string
+		fallback     []string
+		known        []string // localidades reconhecidas pela WeatherAPI
+		ibgeDown     bool
+		wantQueries  []string
+		wantIBGE     int32
+		wantStatus   int
+		wantFallback string
+	}{
+		{"município", []string{localityFallbackMunicipality, localityFallbackCapital}, []string{"Campinas,SP", "São Paulo"}, false,
+			[]string{"Distrito Perdido", "Distrito Perdido,SP", "Campinas,SP"}, 1, http.StatusOK, localityFallbackMunicipality},
+		{"município falha, capital", []string{localityFallbackMunicipality, localityFallbackCapital}, []string{"São Paulo"}, false,
+			[]string{"Distrito Perdido", "Distrito Perdido,SP", "Campinas,SP", "São Paulo"}, 1, http.StatusOK, localityFallbackCapital},
+		{"IBGE indisponível, capital", []string{localityFallbackMunicipality, localityFallbackCapital}, []string{"Campinas,SP", "São Paulo"}, true,
+			[]string{"Distrito Perdido", "Distrito Perdido,SP", "São Paulo"}, 1, http.StatusOK, localityFallbackCapital},
+		{"capital antes do município", []string{localityFallbackCapital, localityFallbackMunicipality}, []string{"Campinas,SP", "São Paulo"}, false,
+			[]string{"Distrito Perdido", "Distrito Perdido,SP", "São Paulo"}, 0, http.StatusOK, localityFallbackCapital},
+		{"localidade própria", []string{localityFallbackMunicipality}, []string{"Distrito Perdido"}, false,
+			[]string{"Distrito Perdido"}, 0, http.StatusOK, ""},
+		{"nenhum funciona", []string{localityFallbackMunicipality, localityFallbackCapital}, nil, false,
+			[]string{"Distrito Perdido", "Distrito Perdido,SP", "Campinas,SP", "São Paulo"}, 1, http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weatherLocalityFallback = tt.fallback
+			var ibgeCalls atomic.Int32
+			ibge := mockIBGE(&ibgeCalls)
+			withMockIBGE(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.ibgeDown {
+					ibgeCalls.Add(1)
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				ibge.ServeHTTP(w, r)
+			}))
+
+			var mu sync.Mutex
+			var queries []string
+			withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(districtCEP)
+			}), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				q := r.URL.Query().Get("q")
+				mu.Lock()
+				queries = append(queries, q)
+				mu.Unlock()
+				if slices.Contains(tt.known, q) {
+					mockWeatherAPI(21).ServeHTTP(w, r)
+					return
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				io.WriteString(w, `{"error":{"code":1006,"message":"No matching location found."}}`)
+			}))
+			sr := withSpanRecorder(t)
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/13999001", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if !slices.Equal(queries, tt.wantQueries) {
+				t.Errorf("consultas = %q, want %q", queries, tt.wantQueries)
+			}
+			if n := ibgeCalls.Load(); n != tt.wantIBGE {
+				t.Errorf("chamadas ao IBGE = %d, want %d", n, tt.wantIBGE)
+			}
+			span := endedSpan(t, sr, "weather_handler")
+			if got := spanAttr(span, "weather.locality_fallback").AsString(); got != tt.wantFallback {
+				t.Errorf("weather.locality_fallback = %q, want %q", got, tt.wantFallback)
+			}
+		})
+	}
+}
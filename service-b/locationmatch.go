@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Qualidade da correspondência entre a localidade do CEP e a devolvida pela WeatherAPI,
+// em location.match na resposta detalhada
+const (
+	locationMatchExact    = "exact"    // mesmo nome, ignorando maiúsculas, acentos e pontuação
+	locationMatchPartial  = "partial"  // um nome contém o outro (ex.: distrito e município)
+	locationMatchMismatch = "mismatch" // nomes diferentes: a WeatherAPI resolveu outro lugar
+)
+
+// Compara a localidade consultada (ViaCEP) com location.name da WeatherAPI. Vazio quando
+// não houve consulta por nome (caminho direto pelo código postal)
+func locationMatch(localidade, weatherName string) string {
+	want, got := normalizeLocality(localidade), normalizeLocality(weatherName)
+	switch {
+	case want == "":
+		return ""
+	case want == got:
+		return locationMatchExact
+	case got != "" && (strings.Contains(" "+want+" ", " "+got+" ") || strings.Contains(" "+got+" ", " "+want+" ")):
+		return locationMatchPartial
+	}
+	return locationMatchMismatch
+}
+
+// Nome em minúsculas, sem acentos e com pontuação e espaços repetidos reduzidos a um
+// espaço: "Santa Bárbara d'Oeste" e "Santa Barbara D Oeste" ficam iguais
+func normalizeLocality(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Acento separado da letra pela decomposição
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNormalizeLocality(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{"vazio", "", ""},
+		{"acentos", "São Paulo", "sao paulo"},
+		{"cedilha e til", "Conceição do Araguaia", "conceicao do araguaia"},
+		{"apóstrofo", "Santa Bárbara d'Oeste", "santa barbara d oeste"},
+		{"hífen e espaços repetidos", "  Embu-Guaçu  ", "embu guacu"},
+		{"já normalizado", "santa barbara d oeste", "santa barbara d oeste"},
+		{"acento já decomposto", "Sa\u0303o Paulo", "sao paulo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeLocality(tt.s); got != tt.want {
+				t.Errorf("normalizeLocality(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocationMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		localidade  string
+		weatherName string
+		want        string
+	}{
+		{"sem consulta por nome", "", "Sao Paulo", ""},
+		{"mesmo nome", "São Paulo", "São Paulo", locationMatchExact},
+		{"sem acento", "São Paulo", "Sao Paulo", locationMatchExact},
+		{"pontuação diferente", "Santa Bárbara d'Oeste", "Santa Barbara D Oeste", locationMatchExact},
+		{"distrito no município", "Sousas", "Sousas Campinas", locationMatchPartial},
+		{"município contido", "Embu-Guaçu", "Embu", locationMatchPartial},
+		{"só prefixo da palavra", "Santos", "Santo", locationMatchMismatch},
+		{"outra cidade", "São Paulo", "Sao Luis", locationMatchMismatch},
+		{"sem nome da weatherapi", "São Paulo", "", locationMatchMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := locationMatch(tt.localidade, tt.weatherName); got != tt.want {
+				t.Errorf("locationMatch(%q, %q) = %q, want %q", tt.localidade, tt.weatherName, got, tt.want)
+			}
+		})
+	}
+}
+
+// Na resposta detalhada, location.match compara a localidade do ViaCEP com o nome da
+// WeatherAPI; na consulta direta pelo código postal não há localidade e o campo é omitido
+func TestWeatherHandlerLocationMatch(t *testing.T) {
+	defer func(b bool) { weatherDirectPostalCode = b }(weatherDirectPostalCode)
+
+	tests := []struct {
+		name        string
+		localidade  string
+		weatherName string
+		direct      bool
+		want        string
+	}{
+		{"sem acento", "São Paulo", "Sao Paulo", false, locationMatchExact},
+		{"distrito no município", "Sousas", "Sousas Campinas", false, locationMatchPartial},
+		{"outra cidade", "São Paulo", "Sao Luis", false, locationMatchMismatch},
+		{"código postal direto", "São Paulo", "Sao Luis", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weatherDirectPostalCode = tt.direct
+			cep := mockCEP
+			cep.Localidade = tt.localidade
+			withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(cep)
+			}), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var data WeatherData
+				data.Location.Name = tt.weatherName
+				data.Location.Country = "Brazil"
+				data.Current.LastUpdatedEpoch = int(time.Now().Unix())
+				data.Current.TempC = 21
+				json.NewEncoder(w).Encode(data)
+			}))
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000?verbose=true", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+
+			var body struct {
+				Location map[string]any `json:"location"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			got, ok := body.Location["match"]
+			if tt.want == "" {
+				if ok {
+					t.Errorf("location.match = %v, want omitido", got)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("location.match = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
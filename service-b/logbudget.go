@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Linhas de log que uma requisição ainda pode emitir. Compartilhado entre as goroutines
+// da requisição (ex.: itens do batch), por isso atômico
+type logBudget struct {
+	remaining atomic.Int64
+	truncated atomic.Bool
+}
+
+type logBudgetKey struct{}
+
+// Limita as linhas de log por requisição (LOG_BUDGET_PER_REQUEST, 0 desabilita), para
+// que uma requisição (ex.: um batch grande com muitas falhas) não gere log sem limite
+func logBudgetMiddleware(limit int) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			budget := &logBudget{}
+			budget.remaining.Store(int64(limit))
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), logBudgetKey{}, budget)))
+		})
+	}
+}
+
+// log.Printf descontando do orçamento da requisição em ctx. Esgotado o orçamento,
+// registra uma única linha "log truncated" (com o trace ID) e descarta as demais
+func logf(ctx context.Context, format string, args ...any) {
+	budget, ok := ctx.Value(logBudgetKey{}).(*logBudget)
+	if !ok || budget.remaining.Add(-1) >= 0 {
+		log.Printf(format, args...)
+		return
+	}
+	if budget.truncated.CompareAndSwap(false, true) {
+		span := trace.SpanFromContext(ctx)
+		span.SetAttributes(attribute.Bool("log.truncated", true))
+		log.Printf("log truncated: orçamento de log da requisição esgotado (trace %s)", traceIDOf(span))
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Esgotado o orçamento, logf registra uma única linha "log truncated" e marca o span
+func TestLogf(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+	sr := withSpanRecorder(t)
+
+	var ctx context.Context
+	logBudgetMiddleware(2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	ctx, span := tracer.Start(ctx, "handler")
+	for i := range 5 {
+		logf(ctx, "linha %d", i)
+	}
+	span.End()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "linha 0") || !strings.Contains(lines[1], "linha 1") ||
+		!strings.Contains(lines[2], "log truncated") {
+		t.Errorf("log = %q, want duas linhas e o aviso de truncamento", buf.String())
+	}
+	if !spanAttr(endedSpan(t, sr, "handler"), "log.truncated").AsBool() {
+		t.Error("log.truncated ausente no span")
+	}
+
+	// Sem o middleware não há orçamento
+	buf.Reset()
+	for i := range 5 {
+		logf(context.Background(), "linha %d", i)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 5 {
+		t.Errorf("linhas sem orçamento = %d, want 5", n)
+	}
+}
+
+// Num batch grande em que todos os itens falham, o log da requisição para no orçamento
+// mais a linha de truncamento, qualquer que seja o tamanho do batch
+func TestBatchLogBudget(t *testing.T) {
+	withBatchConfig(t)
+	withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}), mockWeatherAPI(23.5))
+
+	ceps := make([]string, 40)
+	for i := range ceps {
+		ceps[i] = fmt.Sprintf("0100%04d", i)
+	}
+	body, _ := json.Marshal(ceps)
+
+	tests := []struct {
+		name      string
+		limit     int
+		wantLines int
+	}{
+		{"orçamento de 5", 5, 6},
+		{"desabilitado", 0, 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			defer log.SetOutput(log.Writer())
+			log.SetOutput(&buf)
+
+			rec := httptest.NewRecorder()
+			logBudgetMiddleware(tt.limit)(http.HandlerFunc(batchHandler)).ServeHTTP(rec,
+				httptest.NewRequest(http.MethodPost, "/batch", bytes.NewReader(body)))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+			}
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != tt.wantLines {
+				t.Errorf("linhas de log = %d, want %d", len(lines), tt.wantLines)
+			}
+			if truncated := strings.Count(buf.String(), "log truncated"); truncated != min(tt.limit, 1) {
+				t.Errorf("linhas de truncamento = %d, want %d", truncated, min(tt.limit, 1))
+			}
+		})
+	}
+}
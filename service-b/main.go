@@ -3,148 +3,347 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
-	"regexp"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/afga95/lab-go-otel-zipkin/shared/telemetry"
+	"github.com/afga95/lab-go-otel-zipkin/shared/types"
+	"github.com/afga95/lab-go-otel-zipkin/shared/validation"
 	"github.com/gorilla/mux"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
 	"go.opentelemetry.io/otel/trace"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
-type CEP struct {
-	Cep         string `json:"cep"`
-	Logradouro  string `json:"logradouro"`
-	Complemento string `json:"complemento"`
-	Bairro      string `json:"bairro"`
-	Localidade  string `json:"localidade"`
-	Uf          string `json:"uf"`
-	Ibge        string `json:"ibge"`
-	Gia         string `json:"gia"`
-	Ddd         string `json:"ddd"`
-	Siafi       string `json:"siafi"`
-	Erro        bool   `json:"erro,omitempty"`
+// Tipos compartilhados entre os serviços (shared/types)
+type (
+	CEP                 = types.CEP
+	WeatherData         = types.WeatherData
+	WeatherAirQuality   = types.WeatherAirQuality
+	TemperatureResponse = types.TemperatureResponse
+	Temperature         = types.Temperature
+	ErrorResponse       = types.ErrorResponse
+)
+
+// Corpo de erro da WeatherAPI, ex.: {"error":{"code":2006,"message":"API key is invalid."}}
+type WeatherAPIErrorBody struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Erro retornado pela WeatherAPI com o código e a mensagem específicos
+type weatherAPIError struct {
+	StatusCode int
+	Code       int
+	Message    string
 }
 
-type WeatherData struct {
-	Location struct {
-		Name           string  `json:"name"`
-		Region         string  `json:"region"`
-		Country        string  `json:"country"`
-		Lat            float64 `json:"lat"`
-		Lon            float64 `json:"lon"`
-		TzID           string  `json:"tz_id"`
-		LocaltimeEpoch int     `json:"localtime_epoch"`
-		Localtime      string  `json:"localtime"`
-	} `json:"location"`
-	Current struct {
-		LastUpdatedEpoch int     `json:"last_updated_epoch"`
-		LastUpdated      string  `json:"last_updated"`
-		TempC            float64 `json:"temp_c"`
-		TempF            float64 `json:"temp_f"`
-		IsDay            int     `json:"is_day"`
-		Condition        struct {
-			Text string `json:"text"`
-			Icon string `json:"icon"`
-			Code int    `json:"code"`
-		} `json:"condition"`
-		WindMph    float64 `json:"wind_mph"`
-		WindKph    float64 `json:"wind_kph"`
-		WindDegree int     `json:"wind_degree"`
-		WindDir    string  `json:"wind_dir"`
-		PressureMb float64 `json:"pressure_mb"`
-		PressureIn float64 `json:"pressure_in"`
-		PrecipMm   float64 `json:"precip_mm"`
-		PrecipIn   float64 `json:"precip_in"`
-		Humidity   int     `json:"humidity"`
-		Cloud      int     `json:"cloud"`
-		FeelslikeC float64 `json:"feelslike_c"`
-		FeelslikeF float64 `json:"feelslike_f"`
-		WindchillC float64 `json:"windchill_c"`
-		WindchillF float64 `json:"windchill_f"`
-		HeatindexC float64 `json:"heatindex_c"`
-		HeatindexF float64 `json:"heatindex_f"`
-		DewpointC  float64 `json:"dewpoint_c"`
-		DewpointF  float64 `json:"dewpoint_f"`
-		VisKm      float64 `json:"vis_km"`
-		VisMiles   float64 `json:"vis_miles"`
-		Uv         float64 `json:"uv"`
-		GustMph    float64 `json:"gust_mph"`
-		GustKph    float64 `json:"gust_kph"`
-	} `json:"current"`
+func (e *weatherAPIError) Error() string {
+	return fmt.Sprintf("erro na API Weather: status %d, código %d: %s", e.StatusCode, e.Code, e.Message)
 }
 
-type TemperatureResponse struct {
-	City  string  `json:"city"`
-	TempC float64 `json:"temp_C"`
-	TempF float64 `json:"temp_F"`
-	TempK float64 `json:"temp_K"`
+// Falha de autenticação (chave ausente, inválida, desativada ou sem cota):
+// problema de configuração do operador, não da requisição do cliente
+func (e *weatherAPIError) isAuthFailure() bool {
+	switch e.Code {
+	case 1002, 2006, 2007, 2008, 2009:
+		return true
+	}
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// Localidade consultada não existe na WeatherAPI
+func (e *weatherAPIError) isLocationNotFound() bool {
+	return e.Code == 1006
 }
 
-type ErrorResponse struct {
-	Message string `json:"message"`
+// Provedores usados na resolução do CEP e do clima
+const (
+	cepProviderViaCEP         = "viacep"
+	weatherProviderWeatherAPI = "weatherapi"
+)
+
+// Provedor que serviu o clima, a partir da origem da resposta
+func weatherProviderFor(source string) string {
+	if source == sourceHistoricalAverage {
+		return sourceHistoricalAverage
+	}
+	return weatherProviderWeatherAPI
 }
 
+// Tratamento de um error embutido numa resposta 200 da WeatherAPI (WEATHER_EMBEDDED_ERROR)
+const (
+	embeddedErrorFail = "fail" // falha com o código e a mensagem do error
+	embeddedErrorFlag = "flag" // só registra no span e usa os dados recebidos
+)
+
+// Tratamento de location.name vazio na resposta da WeatherAPI (EMPTY_CITY)
+const (
+	emptyCityFallback = "fallback"
+	emptyCityFlag     = "flag"
+)
+
 var (
+	// Tracer do pacote. O tracer global delega para o provider configurado em
+	// initTracer, então é seguro usá-lo antes da inicialização (no-op até lá)
+	tracer = otel.Tracer("service-b")
+
 	httpClient    *http.Client
-	tracer        trace.Tracer
-	weatherAPIKey string
+	weatherAPIKey = &apiKeyStore{}
+
+	// URL base da WeatherAPI. Sempre HTTPS, pois a chave vai na query string;
+	// WEATHER_API_BASE_URL existe para testes contra um mock local
+	weatherAPIBaseURL = "https://api.weatherapi.com"
+
+	// URL base do ViaCEP; VIACEP_BASE_URL também existe só para testes contra um mock
+	viaCEPBaseURL = "https://viacep.com.br"
+
+	batchEmptyStatus  int
+	batchMaxItems     int
+	batchMaxBodyBytes int
+	batchConcurrency  int
+	batchTimeout      time.Duration
+
+	// Cache de endereços por CEP (só CEPs encontrados)
+	cepCache Cache[CEP] = newTTLCache[CEP](0, 0)
+
+	// Cache de clima por localidade: vários CEPs da mesma cidade compartilham a consulta
+	weatherCache Cache[WeatherData]
+
+	// Cache negativo: localidades que a WeatherAPI não encontrou
+	weatherNegativeCache Cache[*weatherAPIError]
+
+	emptyCityMode = emptyCityFallback
+
+	// Tratamento de um objeto error numa resposta 200 da WeatherAPI
+	weatherEmbeddedError = embeddedErrorFail
+
+	// Circuit breaker das chamadas à WeatherAPI (nil quando desabilitado)
+	weatherBreaker *circuitBreaker
+
+	// Códigos de condição da WeatherAPI tratados como falha, e o status devolvido
+	weatherSoftFailCodes  map[int]bool
+	weatherSoftFailStatus = http.StatusBadGateway
 )
 
 func main() {
+	cfg, err := LoadConfig()
+	if err != nil {
+		log.Fatalf("Configuração inválida: %v", err)
+	}
+
+	// Encerra o servidor de forma graciosa em SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, cfg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Inicializa tracing, cliente HTTP e rotas e atende requisições até ctx ser cancelado
+func run(ctx context.Context, cfg Config) error {
 	// Inicializar OpenTelemetry
-	if err := initTracer(); err != nil {
-		log.Fatalf("Erro ao inicializar tracer: %v", err)
+	res, err := newResource()
+	if err != nil {
+		return err
+	}
+
+	tp, err := initTracer(cfg.Tracing, res)
+	if err != nil {
+		return fmt.Errorf("erro ao inicializar tracer: %w", err)
+	}
+	defer tp.Shutdown(context.Background())
+
+	mp, err := initMeter(cfg, res)
+	if err != nil {
+		return fmt.Errorf("erro ao inicializar métricas: %w", err)
 	}
+	defer mp.Shutdown(context.Background())
 
-	// Configuração da porta do servidor
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Com CACHE_BACKEND=redis os caches ficam no Redis, compartilhados entre as réplicas
+	if cfg.CacheBackend == cacheBackendRedis {
+		client, err := newRedisClient(ctx, cfg.RedisURL)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		cacheBackend, redisClient = cacheBackendRedis, client
 	}
 
-	// Weather API Key
-	weatherAPIKey = os.Getenv("WEATHER_API_KEY")
-	if weatherAPIKey == "" {
-		weatherAPIKey = "ad43e5d744964ababd411426252107"
+	// WEATHER_API_KEYS (várias chaves em round-robin) tem precedência sobre WEATHER_API_KEY,
+	// exceto quando a chave vem de WEATHER_API_KEY_FILE
+	if len(cfg.WeatherAPIKeys) > 0 && cfg.WeatherAPIKeyFile == "" {
+		weatherAPIKey.SetKeys(cfg.WeatherAPIKeys)
+	} else {
+		weatherAPIKey.Set(cfg.WeatherAPIKey)
+	}
+	weatherAPIKey.file = cfg.WeatherAPIKeyFile
+	weatherAPIKey.cooldown = cfg.WeatherKeyCooldown
+	watchAPIKeyReload(ctx, weatherAPIKey)
+	batchEmptyStatus = cfg.BatchEmptyStatus
+	batchMaxItems = cfg.BatchMaxItems
+	batchMaxBodyBytes = cfg.BatchMaxBodyBytes
+	batchConcurrency = cfg.BatchConcurrency
+	batchTimeout = cfg.BatchTimeout
+	batchDuplicates = cfg.BatchDuplicates
+	retryMaxAttempts = cfg.RetryMaxAttempts
+	defaultRetryBudget = cfg.RetryBudget
+	retryAfterMax = cfg.RetryAfterMax
+	retryJitter = cfg.RetryJitter
+	weatherAPIBaseURL = strings.TrimSuffix(cfg.WeatherAPIBaseURL, "/")
+	viaCEPBaseURL = strings.TrimSuffix(cfg.ViaCEPBaseURL, "/")
+	cacheMaxEntries = cfg.CacheMaxEntries
+	cepCache = newCache[CEP]("cep", cfg.CEPCacheTTL)
+	weatherCache = newCache[WeatherData]("weather", cfg.WeatherCacheTTL)
+	weatherNegativeCache = newCache[*weatherAPIError]("weather_negative", cfg.WeatherNegativeCacheTTL)
+	weatherHedgeDelay = cfg.WeatherHedgeDelay
+	emptyCityMode = cfg.EmptyCity
+	validateUpstream = cfg.ValidateUpstream
+	verboseErrors = cfg.VerboseErrors
+	spanNaming = cfg.Tracing.SpanNaming
+	historicalFallback = cfg.HistoricalFallback
+	weatherDirectPostalCode = cfg.WeatherDirectPostalCode
+	cepPrefixFallback = cfg.CEPPrefixFallback
+	viaCEPErrorBudget = newErrorBudget(cepProviderViaCEP, cfg.ErrorBudgetThreshold, cfg.ErrorBudgetWindow, cfg.ErrorBudgetMinCalls)
+	weatherAPIErrorBudget = newErrorBudget(weatherProviderWeatherAPI, cfg.ErrorBudgetThreshold, cfg.ErrorBudgetWindow, cfg.ErrorBudgetMinCalls)
+	weatherExpectedCountries = cfg.WeatherExpectedCountries
+	weatherCountryMismatch = cfg.WeatherCountryMismatch
+	weatherEmbeddedError = cfg.WeatherEmbeddedError
+	weatherLocalityFallback = cfg.WeatherLocalityFallback
+	defaultTempUnit = cfg.DefaultTempUnit
+	types.SetTemperaturePrecision(cfg.TempPrecision)
+	generatedAtMode = cfg.GeneratedAt
+	cepTestModeRanges = cfg.CEPTestModeRanges
+	weatherUpdateInterval = cfg.WeatherUpdateInterval
+	weatherBreaker = newCircuitBreaker(cfg.WeatherBreakerThreshold, cfg.WeatherBreakerCooldown)
+	readyzDegradedStatus = cfg.ReadyzDegradedStatus
+	readinessChecks = cfg.ReadinessChecks
+	readinessCheckTimeout = cfg.ReadinessCheckTimeout
+	readinessCacheTTL = cfg.ReadinessCacheTTL
+	if cfg.Tracing.Exporter == telemetry.ExporterOTLP {
+		readinessOTLPEndpoint = cfg.Tracing.OTLPEndpoint
+	}
+	weatherSoftFailStatus = cfg.WeatherSoftFailStatus
+	recentErrors = newErrorRing(cfg.AdminErrorsSize)
+	weatherSoftFailCodes = make(map[int]bool, len(cfg.WeatherSoftFailCodes))
+	for _, code := range cfg.WeatherSoftFailCodes {
+		weatherSoftFailCodes[code] = true
 	}
 
-	// Cliente HTTP com instrumentação OpenTelemetry
+	// Cliente HTTP com instrumentação OpenTelemetry, TLS mínimo (UPSTREAM_TLS_MIN_VERSION),
+	// limite de redirecionamentos (UPSTREAM_MAX_REDIRECTS) e de conexões simultâneas por
+	// host (UPSTREAM_MAX_CONNS_PER_HOST)
+	upstreamTransport := newHostLimitTransport(newUpstreamTransport(cfg.UpstreamTLSMinVersion), cfg.UpstreamMaxConnsPerHost, cfg.UpstreamConnWaitTimeout)
 	httpClient = &http.Client{
-		Timeout:   10 * time.Second,
-		Transport: otelhttp.NewTransport(http.DefaultTransport),
+		Timeout:       10 * time.Second,
+		Transport:     otelhttp.NewTransport(upstreamTransport),
+		CheckRedirect: upstreamCheckRedirect(cfg.UpstreamMaxRedirects),
 	}
 
-	// Tracer
-	tracer = otel.Tracer("service-b")
+	handler := newHandler(cfg)
 
+	// Log de inicialização
+	log.Printf("Serviço B iniciando na porta %s", cfg.Port)
+	log.Printf("Weather API Key configurada: %v (%d chave(s))", weatherAPIKey.Get() != "", len(weatherAPIKey.All()))
+	if cfg.WeatherAPIKeyFile != "" {
+		log.Printf("Weather API Key lida de %s (SIGHUP recarrega)", cfg.WeatherAPIKeyFile)
+	}
+	log.Printf("Máximo de requisições simultâneas: %d", cfg.MaxInFlight)
+	if len(cfg.RouteTimeouts) > 0 {
+		log.Printf("Prazos por rota: %v", cfg.RouteTimeouts)
+	}
+	log.Printf("Amostragem do log de acesso: %.2f (erros sempre registrados)", cfg.AccessLogSampleRate)
+	if cfg.ChaosEnabled {
+		log.Printf("CHAOS habilitado: atraso %.2f (%s), erro %.2f", cfg.Chaos.DelayRate, cfg.Chaos.Delay, cfg.Chaos.ErrorRate)
+	}
+	log.Printf("Endpoints disponíveis:")
+	log.Printf("  GET /{cep}  - Consultar clima por CEP")
+	log.Printf("  GET /{cep}/temp - Temperatura em texto puro")
+	log.Printf("  POST /batch - Consultar clima para vários CEPs")
+	log.Printf("  GET /search - Buscar CEPs por endereço")
+	log.Printf("  GET /openapi.json - Contrato OpenAPI")
+	log.Printf("  GET /health - Health check")
+	log.Printf("  GET /livez  - Liveness probe")
+	log.Printf("  GET /readyz - Readiness probe")
+
+	if cfg.EnablePprof {
+		telemetry.StartPprofServer(cfg.PprofAddr)
+	}
+
+	// Aquece os caches com os CEPs mais consultados, sem atrasar o início do servidor
+	if cfg.CachePreloadFile != "" {
+		preloadCaches(ctx, cfg.CachePreloadFile, cfg.CachePreloadWeather)
+	}
+
+	if cfg.EnableH2C {
+		log.Printf("HTTP/2 cleartext (h2c) habilitado")
+	}
+
+	server := newServer(cfg, handler)
+	return serve(ctx, server, cfg.ShutdownDelay)
+}
+
+// Rotas e middlewares do serviço, sem o listener: o pprof (ENABLE_PPROF) nunca entra
+// aqui, fica no seu próprio listener
+func newHandler(cfg Config) http.Handler {
 	// Configuração das rotas
 	r := mux.NewRouter()
-	r.Use(otelmux.Middleware("service-b"))
+	r.Use(accessLogMiddleware(cfg.AccessLogSampleRate))
+	r.Use(compressionMiddleware(cfg.Compression))
+	r.Use(maxInFlightMiddleware(cfg.MaxInFlight, cfg.CriticalRoutes))
+	r.Use(otelmux.Middleware("service-b", otelmux.WithSpanNameFormatter(routeSpanName)))
+	r.Use(payloadSizeMiddleware)
+	r.Use(logBudgetMiddleware(cfg.LogBudgetPerRequest))
+	r.Use(deadlineMiddleware)
+	r.Use(routeTimeoutMiddleware(cfg.RouteTimeouts))
+	r.Use(retryBudgetMiddleware)
+	if cfg.ChaosEnabled {
+		r.Use(chaosMiddleware(cfg.Chaos))
+	}
 
-	// Rota principal para consulta de CEP e clima
-	r.HandleFunc("/{cep}", weatherHandler).Methods("GET")
+	// Últimos erros e configuração efetiva para triagem; só registrados com ADMIN_TOKEN configurado
+	if cfg.AdminToken != "" {
+		r.HandleFunc("/admin/errors", adminErrorsHandler(cfg.AdminToken)).Methods("GET")
+		r.HandleFunc("/admin/config", adminConfigHandler(cfg.AdminToken, cfg)).Methods("GET")
+	}
 
-	// Rota de health check
-	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
-	}).Methods("GET")
+	// Consulta de vários CEPs em uma única requisição
+	r.HandleFunc("/batch", batchHandler).Methods("POST")
+
+	// Contrato OpenAPI 3; registrado antes de /{cep}, que casaria com qualquer caminho
+	r.HandleFunc("/openapi.json", openAPIHandler).Methods("GET")
+
+	// Busca de CEPs por endereço (UF, cidade e logradouro); também antes de /{cep}
+	r.HandleFunc("/search", searchHandler).Methods("GET")
+
+	// Rota principal para consulta de CEP e clima. HEAD responde o mesmo status do GET,
+	// sem corpo (o net/http descarta o corpo); CEP inválido responde 422 sem chamar os upstreams.
+	// Consultas simultâneas ao mesmo CEP acima de MAX_INFLIGHT_PER_CEP respondem 429
+	// O limite por CEP é compartilhado com /{cep}/temp
+	cepLimit := perCEPLimitMiddleware(cfg.MaxInFlightPerCEP)
+	r.Handle("/{cep}", cepLimit(http.HandlerFunc(weatherHandler))).Methods("GET", "HEAD")
+
+	// Só a temperatura como text/plain, na unidade de ?unit= (clientes de shell e IoT)
+	r.Handle("/{cep}/temp", cepLimit(http.HandlerFunc(tempTextHandler))).Methods("GET")
 
 	// Rota raiz com informações da API
 	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -154,34 +353,81 @@ func main() {
 			"version":     "1.0.0",
 			"description": "Serviço B - Responsável pela orquestração de CEP e clima",
 			"endpoints": map[string]string{
-				"weather": "GET /{cep}",
+				"weather": "GET|HEAD /{cep}",
+				"temp":    "GET /{cep}/temp?unit=",
+				"batch":   "POST /batch",
+				"search":  "GET /search?uf=&city=&street=",
+				"openapi": "GET /openapi.json",
 				"health":  "GET /health",
+				"livez":   "GET /livez",
+				"readyz":  "GET /readyz",
 			},
 		})
 	}).Methods("GET")
 
-	// Log de inicialização
-	log.Printf("Serviço B iniciando na porta %s", port)
-	log.Printf("Weather API Key configurada: %v", weatherAPIKey != "")
-	log.Printf("Endpoints disponíveis:")
-	log.Printf("  GET /{cep}  - Consultar clima por CEP")
-	log.Printf("  GET /health - Health check")
+	// HTTP/2 sem TLS (ENABLE_H2C) para meshes que falam h2c entre sidecars
+	// OPTIONS responde 204 com Allow para qualquer rota conhecida, health checks inclusive
+	// URIs acima de MAX_URI_LENGTH param antes de qualquer rota (414)
+	var handler http.Handler = maxURILengthMiddleware(cfg.MaxURILength)(optionsMiddleware(r)(healthCheckMiddleware(r)))
+	if cfg.EnableH2C {
+		handler = withH2C(handler)
+	}
+	return handler
+}
 
-	// Inicia o servidor
-	server := &http.Server{
-		Addr:         ":" + port,
-		Handler:      r,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+// Servidor HTTP do serviço. ReadHeaderTimeout limita o tempo para receber os headers
+// (slowloris) e MaxHeaderBytes o seu tamanho (431 acima dele)
+func newServer(cfg Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      15 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
 	}
+}
+
+// Atende no servidor até ctx ser cancelado e então encerra aguardando as requisições em andamento
+func serve(ctx context.Context, server *http.Server, shutdownDelay time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
 
-	log.Fatal(server.ListenAndServe())
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		// Antes de fechar as conexões, o /readyz passa a responder 503 e o servidor segue
+		// atendendo por SHUTDOWN_DELAY, para o load balancer tirar a instância do pool
+		shuttingDown.Store(true)
+		if shutdownDelay > 0 {
+			log.Printf("Aguardando %s antes de encerrar (readyz: 503)...", shutdownDelay)
+			time.Sleep(shutdownDelay)
+		}
+
+		log.Printf("Encerrando o servidor...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	}
 }
 
-// Inicializa o OpenTelemetry tracer
-func initTracer() error {
-	// Configuração do resource
+// Nome do span do servidor: usa sempre o template da rota (ex.: /{cep}) e nunca o
+// path concreto, evitando um nome de span distinto para cada CEP consultado
+func routeSpanName(routeName string, r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil && tpl != "" {
+			return tpl
+		}
+	}
+	return routeName
+}
+
+// Resource do serviço, compartilhado por traces e métricas
+func newResource() (*resource.Resource, error) {
 	res, err := resource.New(context.Background(),
 		resource.WithAttributes(
 			semconv.ServiceNameKey.String("service-b"),
@@ -189,49 +435,47 @@ func initTracer() error {
 		),
 	)
 	if err != nil {
-		return fmt.Errorf("erro ao criar resource: %w", err)
+		return nil, fmt.Errorf("erro ao criar resource: %w", err)
 	}
+	return res, nil
+}
 
-	// Endpoint do collector
-	otlpEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-	if otlpEndpoint == "" {
-		otlpEndpoint = "localhost:4317"
+// Inicializa o OpenTelemetry tracer
+func initTracer(cfg TracingConfig, res *resource.Resource) (*sdktrace.TracerProvider, error) {
+	exporter, err := telemetry.NewTraceExporter(cfg.Config)
+	if err != nil {
+		return nil, err
 	}
-
-	// Configuração do exporter OTLP usando grpc.NewClient
-	otlpEndpoint = strings.TrimPrefix(otlpEndpoint, "http://")
-	conn, err := grpc.NewClient(
-		otlpEndpoint,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	secondary, err := telemetry.NewSecondaryTraceExporter(cfg.Config)
 	if err != nil {
-		return fmt.Errorf("erro ao conectar com OTLP endpoint: %w", err)
+		return nil, err
 	}
 
-	exporter, err := otlptracegrpc.New(context.Background(), otlptracegrpc.WithGRPCConn(conn))
+	// Amostragem: sempre para as regiões em TRACE_TARGET_REGIONS, taxa TRACE_SAMPLE_RATIO para o resto
+	sampler, err := telemetry.NewRegionSampler(cfg.TargetRegions, cfg.SampleRatio)
 	if err != nil {
-		return fmt.Errorf("erro ao criar exporter: %w", err)
+		return nil, err
 	}
 
-	// Configuração do trace provider
-	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exporter),
+	// Configuração do trace provider. Cada collector tem o seu batch processor (e a sua
+	// fila): o secundário recebe os mesmos spans sem atrasar o principal. Spans
+	// descartados por fila cheia são contados em trace.export.spans_dropped
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSpanProcessor(baggageSpanProcessor{keys: []string{cityBaggageKey}}),
+		sdktrace.WithSpanProcessor(newGuardedBatcher(collectorPrimary, telemetry.WithAttributeFilter(telemetry.WithTruncationMarking(exporter), cfg.Config), cfg)),
 		sdktrace.WithResource(res),
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
-	)
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithRawSpanLimits(telemetry.SpanLimits(cfg.Config)),
+	}
+	if secondary != nil {
+		opts = append(opts, sdktrace.WithSpanProcessor(newGuardedBatcher(collectorSecondary, telemetry.WithAttributeFilter(telemetry.WithTruncationMarking(secondary), cfg.Config), cfg)))
+	}
+	tp := sdktrace.NewTracerProvider(opts...)
 
 	otel.SetTracerProvider(tp)
 	otel.SetTextMapPropagator(propagation.TraceContext{})
 
-	return nil
-}
-
-// Validação de CEP
-func isValidCEP(cep string) bool {
-	cep = strings.ReplaceAll(cep, "-", "")
-	cep = strings.TrimSpace(cep)
-	matched, _ := regexp.MatchString(`^\d{8}$`, cep)
-	return matched
+	return tp, nil
 }
 
 // Busca informações do CEP com tracing
@@ -241,12 +485,48 @@ func getCEPInfo(ctx context.Context, cep string) (*CEP, error) {
 
 	span.SetAttributes(
 		attribute.String("cep", cep),
-		attribute.String("api", "viacep"),
+		attribute.String("api", cepProviderViaCEP),
 	)
 
 	// Remove traços para padronizar
 	cep = strings.ReplaceAll(cep, "-", "")
-	url := fmt.Sprintf("https://viacep.com.br/ws/%s/json/", cep)
+
+	// Endereços de um CEP quase nunca mudam: consulta o cache (aquecido pelo preload)
+	if cached, ok := cepCache.Get(ctx, cep); ok {
+		span.SetAttributes(
+			attribute.Bool("cep.cache_hit", true),
+			attribute.Bool("cep.found", true),
+			attribute.String("localidade", cached.Localidade),
+			attribute.String("uf", cached.Uf),
+		)
+		return &cached, nil
+	}
+	span.SetAttributes(attribute.Bool("cep.cache_hit", false))
+
+	// Requisições simultâneas para o mesmo CEP compartilham uma única chamada ao ViaCEP
+	cepData, joined, err := coalesce(ctx, &cepLookups, cep, func() (*CEP, error) {
+		return fetchCEPInfo(ctx, cep)
+	})
+	if joined {
+		span.SetAttributes(attribute.Bool("cep.coalesced", true))
+	}
+	if err != nil {
+		return nil, err
+	}
+	cepCache.Set(ctx, cep, *cepData)
+
+	// Cópia própria para cada chamador, já que o resultado é compartilhado
+	result := *cepData
+	return &result, nil
+}
+
+// ViaCEP respondeu 400: o CEP chegou à API fora do formato esperado (erro do cliente)
+var errUpstreamInvalidCEP = errors.New("ViaCEP rejeitou o CEP (400)")
+
+// Consulta o ViaCEP, registrando os atributos no span ativo em ctx
+func fetchCEPInfo(ctx context.Context, cep string) (*CEP, error) {
+	span := trace.SpanFromContext(ctx)
+	url := fmt.Sprintf("%s/ws/%s/json/", viaCEPBaseURL, cep)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -254,7 +534,8 @@ func getCEPInfo(ctx context.Context, cep string) (*CEP, error) {
 		return nil, fmt.Errorf("erro ao criar request: %w", err)
 	}
 
-	resp, err := httpClient.Do(req)
+	resp, err := doWithRetry(ctx, req)
+	viaCEPErrorBudget.record(ctx, resp, err)
 	if err != nil {
 		span.RecordError(err)
 		return nil, fmt.Errorf("erro ao consultar CEP: %w", err)
@@ -263,6 +544,18 @@ func getCEPInfo(ctx context.Context, cep string) (*CEP, error) {
 
 	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		err := fmt.Errorf("erro na API ViaCEP: %w", errUpstreamRateLimited)
+		span.RecordError(err)
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusBadRequest {
+		err := fmt.Errorf("erro na API ViaCEP: %w", errUpstreamInvalidCEP)
+		span.RecordError(err)
+		return nil, err
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		err := fmt.Errorf("erro na API ViaCEP: status %d", resp.StatusCode)
 		span.RecordError(err)
@@ -270,23 +563,21 @@ func getCEPInfo(ctx context.Context, cep string) (*CEP, error) {
 	}
 
 	var cepData CEP
-	if err := json.NewDecoder(resp.Body).Decode(&cepData); err != nil {
+	if err := decodeUpstream(span, cepProviderViaCEP, resp.Body, &cepData); err != nil {
 		span.RecordError(err)
 		return nil, fmt.Errorf("erro ao decodificar resposta do CEP: %w", err)
 	}
 
 	// ViaCEP retorna erro=true quando CEP não é encontrado
 	if cepData.Erro {
-		err := fmt.Errorf("CEP não encontrado")
 		span.SetAttributes(attribute.Bool("cep.found", false))
-		return nil, err
+		return nil, errCEPNotFound
 	}
 
 	// Verifica se a localidade foi encontrada
 	if cepData.Localidade == "" {
-		err := fmt.Errorf("CEP não encontrado")
 		span.SetAttributes(attribute.Bool("cep.found", false))
-		return nil, err
+		return nil, errCEPNotFound
 	}
 
 	span.SetAttributes(
@@ -299,44 +590,126 @@ func getCEPInfo(ctx context.Context, cep string) (*CEP, error) {
 }
 
 // Busca informações climáticas com tracing
-func getWeatherInfo(ctx context.Context, localidade string) (*WeatherData, error) {
+func getWeatherInfo(ctx context.Context, localidade string, withAQI bool) (*WeatherData, error) {
 	ctx, span := tracer.Start(ctx, "get_weather_info")
 	defer span.End()
 
 	span.SetAttributes(
 		attribute.String("localidade", localidade),
-		attribute.String("api", "weatherapi"),
+		attribute.String("api", weatherProviderWeatherAPI),
+		attribute.Bool("weather.aqi", withAQI),
 	)
 
+	// Clima muda devagar e é o mesmo para toda a cidade: consulta o cache por localidade.
+	// Respostas com qualidade do ar ficam numa chave própria
+	cacheKey := weatherCacheKey(localidade)
+	positiveKey := cacheKey
+	if withAQI {
+		positiveKey += "|aqi"
+	}
+	if cached, ok := weatherCache.Get(ctx, positiveKey); ok {
+		span.SetAttributes(
+			attribute.Bool("weather.cache_hit", true),
+			attribute.String("weather.location", cached.Location.Name),
+			attribute.Float64("weather.temp_c", cached.Current.TempC),
+		)
+		return &cached, nil
+	}
+	span.SetAttributes(attribute.Bool("weather.cache_hit", false))
+
+	// Localidades que a WeatherAPI não reconhece falham de novo: devolve a falha em cache
+	if cachedErr, ok := weatherNegativeCache.Get(ctx, cacheKey); ok {
+		span.SetAttributes(attribute.Bool("weather.negative_cache_hit", true))
+		span.RecordError(cachedErr)
+		return nil, cachedErr
+	}
+
+	// Requisições simultâneas para a mesma localidade compartilham uma única chamada à WeatherAPI
+	weatherData, joined, err := coalesce(ctx, &weatherLookups, positiveKey, func() (WeatherData, error) {
+		return fetchWeatherInfo(ctx, localidade, cacheKey, positiveKey, withAQI)
+	})
+	if joined {
+		span.SetAttributes(attribute.Bool("weather.coalesced", true))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &weatherData, nil
+}
+
+// Consulta a WeatherAPI, registrando os atributos no span ativo em ctx e alimentando os caches
+func fetchWeatherInfo(ctx context.Context, localidade, cacheKey, positiveKey string, withAQI bool) (WeatherData, error) {
+	span := trace.SpanFromContext(ctx)
+	var weatherData WeatherData
+
 	// Codifica a localidade para a URL
 	cidadeEncoded := url.QueryEscape(localidade)
-	urlWeatherAPI := fmt.Sprintf("http://api.weatherapi.com/v1/current.json?key=%s&q=%s&lang=pt", weatherAPIKey, cidadeEncoded)
+	// A chave entra em cada tentativa, pelo hook do apiKeyStore
+	urlWeatherAPI := fmt.Sprintf("%s/v1/current.json?q=%s&lang=pt", weatherAPIBaseURL, cidadeEncoded)
+	if withAQI {
+		urlWeatherAPI += "&aqi=yes"
+	}
 
 	req, err := http.NewRequestWithContext(ctx, "GET", urlWeatherAPI, nil)
 	if err != nil {
 		span.RecordError(err)
-		return nil, fmt.Errorf("erro ao criar request: %w", err)
+		return weatherData, fmt.Errorf("erro ao criar request: %w", err)
+	}
+
+	// Com o breaker aberto, falha rápido sem chamar a WeatherAPI
+	span.SetAttributes(attribute.String("weather.breaker_state", weatherBreaker.State()))
+	if !weatherBreaker.allow() {
+		span.RecordError(errBreakerOpen)
+		return weatherData, errBreakerOpen
 	}
 
-	resp, err := httpClient.Do(req)
+	resp, err := doWithHedge(withAttemptHook(ctx, weatherAPIKey.attemptHook(span)), req, weatherHedgeDelay)
+	if ctx.Err() != nil {
+		weatherBreaker.abort()
+	} else {
+		weatherBreaker.record(!isRetryable(resp, err))
+	}
+	weatherAPIErrorBudget.record(ctx, resp, err)
 	if err != nil {
+		// A URL contém a chave da API: remove antes de registrar o erro
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = redactAPIKey(urlErr.URL)
+		}
 		span.RecordError(err)
-		return nil, fmt.Errorf("erro ao consultar clima: %w", err)
+		return weatherData, fmt.Errorf("erro ao consultar clima: %w", err)
 	}
 	defer resp.Body.Close()
 
 	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
 
 	if resp.StatusCode != http.StatusOK {
-		err := fmt.Errorf("erro na API Weather: status %d", resp.StatusCode)
-		span.RecordError(err)
-		return nil, err
+		err := parseWeatherAPIError(resp)
+		recordWeatherAPIError(ctx, span, cacheKey, err)
+		return weatherData, err
 	}
 
-	var weatherData WeatherData
-	if err := json.NewDecoder(resp.Body).Decode(&weatherData); err != nil {
+	if err := decodeUpstream(span, weatherProviderWeatherAPI, resp.Body, &weatherData); err != nil {
 		span.RecordError(err)
-		return nil, fmt.Errorf("erro ao decodificar resposta do clima: %w", err)
+		return weatherData, fmt.Errorf("erro ao decodificar resposta do clima: %w", err)
+	}
+
+	// Em casos raros a WeatherAPI responde 200 com o objeto error e sem location/current:
+	// sem o tratamento, a resposta sairia com temperatura zero (WEATHER_EMBEDDED_ERROR)
+	if weatherData.Error != nil {
+		span.SetAttributes(attribute.Bool("weather.embedded_error", true))
+		if weatherEmbeddedError == embeddedErrorFail {
+			err := &weatherAPIError{
+				StatusCode: resp.StatusCode,
+				Code:       weatherData.Error.Code,
+				Message:    redactAPIKey(weatherData.Error.Message),
+			}
+			recordWeatherAPIError(ctx, span, cacheKey, err)
+			return WeatherData{}, err
+		}
+		span.AddEvent("weather_embedded_error", trace.WithAttributes(
+			attribute.Int("weather.error_code", weatherData.Error.Code),
+		))
 	}
 
 	span.SetAttributes(
@@ -345,7 +718,53 @@ func getWeatherInfo(ctx context.Context, localidade string) (*WeatherData, error
 		attribute.String("weather.condition", weatherData.Current.Condition.Text),
 	)
 
-	return &weatherData, nil
+	weatherCache.Set(ctx, positiveKey, weatherData)
+
+	return weatherData, nil
+}
+
+// Chave do cache de clima: localidade normalizada
+func weatherCacheKey(localidade string) string {
+	return strings.ToLower(strings.TrimSpace(localidade))
+}
+
+// Registra no span o erro da WeatherAPI. Só "localidade não encontrada" vai para o cache
+// negativo: falhas transitórias (5xx, rede, autenticação) não são cacheadas para não
+// mascarar uma indisponibilidade
+func recordWeatherAPIError(ctx context.Context, span trace.Span, cacheKey string, err *weatherAPIError) {
+	span.SetAttributes(
+		attribute.Int("weather.error_code", err.Code),
+		attribute.String("weather.error_message", err.Message),
+	)
+	span.RecordError(err)
+
+	if err.isLocationNotFound() {
+		weatherNegativeCache.Set(ctx, cacheKey, err)
+	}
+}
+
+// Interpreta o corpo de erro da WeatherAPI; se não for o JSON esperado, mantém apenas o status
+func parseWeatherAPIError(resp *http.Response) *weatherAPIError {
+	apiErr := &weatherAPIError{
+		StatusCode: resp.StatusCode,
+		Message:    http.StatusText(resp.StatusCode),
+	}
+
+	var body WeatherAPIErrorBody
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body); err == nil && body.Error.Code != 0 {
+		apiErr.Code = body.Error.Code
+		apiErr.Message = redactAPIKey(body.Error.Message)
+	}
+
+	return apiErr
+}
+
+// Remove a chave da WeatherAPI de textos que serão registrados em logs ou spans
+func redactAPIKey(s string) string {
+	for _, key := range weatherAPIKey.All() {
+		s = strings.ReplaceAll(s, key, "REDACTED")
+	}
+	return s
 }
 
 // Conversões de temperatura
@@ -357,70 +776,331 @@ func celsiusToKelvin(c float64) float64 {
 	return c + 273
 }
 
-// Handler principal para consulta de CEP e clima
-func weatherHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+// Erro de consulta com o status HTTP e a mensagem devolvidos ao cliente. Upstream é o
+// serviço externo que causou a falha (viacep, weatherapi), vazio para erros de validação
+type lookupError struct {
+	Status   int
+	Message  string
+	Upstream string
 
-	// Inicia span para o handler
-	ctx, span := tracer.Start(ctx, "weather_handler")
-	defer span.End()
+	// Código e detalhe do problema de validação, expostos com VERBOSE_ERRORS=true
+	Code    string
+	Details string
+}
 
-	// Configura headers de resposta
-	w.Header().Set("Content-Type", "application/json")
+// Resultado da consulta: a resposta e os dados de origem, usados no modo verbose
+type lookupResult struct {
+	Response TemperatureResponse
+	CEP      *CEP
+	Weather  *WeatherData
 
-	// Extrai o CEP da URL
-	vars := mux.Vars(r)
-	cep := vars["cep"]
+	// Outras localidades encontradas pela busca por prefixo (CEP_PREFIX_FALLBACK),
+	// da mais próxima para a mais distante; vazio fora dela
+	Alternatives []CEP
+}
 
+// Consulta o CEP e o clima e monta a resposta com as temperaturas. O código postal é
+// resolvido pelo resolver do país (default: BR, via ViaCEP). Os atributos são
+// registrados no span ativo em ctx (handler da rota ou item do batch)
+func lookupTemperature(ctx context.Context, country, cep string, withAQI bool) (*lookupResult, *lookupError) {
+	span := trace.SpanFromContext(ctx)
+
+	// Entrada malformada (bytes de controle, UTF-8 inválido) é 400; um CEP legível mas
+	// fora do formato continua 422. O valor bruto não vai para o span
+	if validation.IsMalformed(cep) {
+		span.SetAttributes(attribute.String("validation", "malformed_zipcode"))
+		return nil, &lookupError{Status: http.StatusBadRequest, Message: "malformed zipcode", Code: validation.CodeMalformed,
+			Details: "zipcode contains control characters or invalid UTF-8"}
+	}
 	span.SetAttributes(attribute.String("cep", cep))
 
+	resolver, ok := resolverFor(country)
+	if !ok {
+		span.SetAttributes(attribute.String("validation", "unsupported_country"))
+		return nil, &lookupError{Status: http.StatusUnprocessableEntity, Message: "unsupported country", Code: "UNSUPPORTED_COUNTRY",
+			Details: fmt.Sprintf("country %q is not supported", country)}
+	}
+
 	// Validação 1: Formato do CEP (422 - invalid zipcode)
-	if !isValidCEP(cep) {
+	if !resolver.ValidFormat(cep) {
 		span.SetAttributes(attribute.String("validation", "invalid_zipcode"))
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		json.NewEncoder(w).Encode(ErrorResponse{Message: "invalid zipcode"})
-		return
+		code, details := validation.DescribeCEP(cep)
+		return nil, &lookupError{Status: http.StatusUnprocessableEntity, Message: "invalid zipcode", Code: code, Details: details}
+	}
+	span.AddEvent("validation_passed")
+
+	// Faixas de teste (CEP_TEST_MODE_RANGES) respondem com dados fixos, sem chamar os upstreams
+	if result, ok := testModeResult(cep); ok {
+		span.SetAttributes(attribute.Bool("cep.test_range", true))
+		return result, nil
+	}
+
+	// Caminho direto (WEATHER_DIRECT_POSTAL_CODE): a WeatherAPI resolve o código postal
+	// sem o ViaCEP; se falhar, segue o fluxo CEP → cidade
+	if weatherInfo, ok := getWeatherByPostalCode(ctx, cep, withAQI); ok {
+		span.SetAttributes(attribute.String("lookup.path", lookupPathDirect))
+		return newLookupResult(span, &CEP{Cep: cep}, weatherInfo, false, "")
 	}
+	span.SetAttributes(attribute.String("lookup.path", lookupPathAddress))
 
-	// Busca informações do CEP
-	cepInfo, err := getCEPInfo(ctx, cep)
+	// Busca informações do CEP. Se ele não existir, a busca por prefixo
+	// (CEP_PREFIX_FALLBACK) usa a localidade mais próxima, com baixa confiança
+	cepInfo, err := resolver.Resolve(ctx, cep)
+	var prefixMatch bool
+	var alternatives []CEP
+	if errors.Is(err, errCEPNotFound) {
+		if candidates := resolveByPrefix(ctx, resolver, cep); len(candidates) > 0 {
+			span.SetAttributes(
+				attribute.Bool("cep.prefix_fallback", true),
+				attribute.String("cep.prefix_match", candidates[0].Cep),
+			)
+			cepInfo, alternatives, err = &candidates[0], candidates[1:], nil
+			prefixMatch = true
+		}
+	}
 	if err != nil {
+		// Cliente desconectado ou prazo esgotado: não é um CEP inexistente
+		if ctxErr := contextLookupError(ctx, span, resolver.Name()); ctxErr != nil {
+			return nil, ctxErr
+		}
+
 		// Validação 2: CEP não encontrado (404 - can not find zipcode)
-		log.Printf("Erro ao buscar CEP %s: %v", cep, err)
+		logf(ctx, "Erro ao buscar CEP %s: %v", cep, err)
 		span.RecordError(err)
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(ErrorResponse{Message: "can not find zipcode"})
-		return
+
+		// Rate limit do provedor que persistiu após as retentativas: indisponibilidade, não CEP inexistente
+		if errors.Is(err, errUpstreamRateLimited) || errors.Is(err, errUpstreamSaturated) {
+			return nil, &lookupError{Status: http.StatusServiceUnavailable, Message: "zipcode service unavailable", Upstream: resolver.Name()}
+		}
+		// Formato rejeitado pelo provedor: problema na entrada, não CEP inexistente
+		if errors.Is(err, errUpstreamInvalidCEP) {
+			span.SetAttributes(attribute.String("validation", "invalid_zipcode_upstream"))
+			return nil, &lookupError{Status: http.StatusUnprocessableEntity, Message: "invalid zipcode", Upstream: resolver.Name(),
+				Code: validation.CodeInvalidFormat, Details: "zipcode rejected by the zipcode service"}
+		}
+		return nil, &lookupError{Status: http.StatusNotFound, Message: "can not find zipcode", Upstream: resolver.Name()}
 	}
+	span.SetAttributes(attribute.String("cep.provider", resolver.Name()))
+	span.AddEvent("cep_resolved")
+	nameSpanByUF(span, cepInfo.Uf)
+
+	// A cidade segue no baggage: os spans seguintes (clima, conversões) a recebem como
+	// atributo. O propagador é só TraceContext, então o baggage não vai para a WeatherAPI
+	ctx = withCityBaggage(ctx, cepInfo.Localidade)
 
-	// Busca informações climáticas
-	weatherInfo, err := getWeatherInfo(ctx, cepInfo.Localidade)
+	// Busca informações climáticas, escalando a formulação da localidade se necessário
+	var source string
+	weatherInfo, lowConfidence, err := getWeatherForAddress(ctx, cepInfo, withAQI)
 	if err != nil {
-		log.Printf("Erro ao buscar clima para %s: %v", cepInfo.Localidade, err)
+		if ctxErr := contextLookupError(ctx, span, weatherProviderWeatherAPI); ctxErr != nil {
+			return nil, ctxErr
+		}
+
+		logf(ctx, "Erro ao buscar clima para %s: %v", cepInfo.Localidade, err)
 		span.RecordError(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Message: "weather service unavailable"})
-		return
+
+		var apiErr *weatherAPIError
+		switch {
+		case errors.As(err, &apiErr) && apiErr.isAuthFailure():
+			// Chave inválida ou sem cota: erro de configuração, não expõe detalhes ao cliente
+			logf(ctx, "WeatherAPI recusou a chave configurada (código %d): verifique WEATHER_API_KEY", apiErr.Code)
+			span.SetAttributes(attribute.String("error", "weather_api_misconfigured"))
+			return nil, &lookupError{Status: http.StatusInternalServerError, Message: "internal server error", Upstream: weatherProviderWeatherAPI}
+		case errors.As(err, &apiErr) && apiErr.isLocationNotFound():
+			return nil, &lookupError{Status: http.StatusNotFound, Message: "can not find zipcode", Upstream: weatherProviderWeatherAPI}
+		case errors.Is(err, errUpstreamSaturated):
+			return nil, &lookupError{Status: http.StatusServiceUnavailable, Message: "weather service unavailable", Upstream: weatherProviderWeatherAPI}
+		}
+
+		// WeatherAPI indisponível: média histórica do mês, se HISTORICAL_FALLBACK=true
+		historical, ok := historicalWeather(span, cepInfo, time.Now())
+		if !ok {
+			return nil, &lookupError{Status: http.StatusInternalServerError, Message: "weather service unavailable", Upstream: weatherProviderWeatherAPI}
+		}
+		weatherInfo, source = historical, sourceHistoricalAverage
+	}
+
+	result, lookupErr := newLookupResult(span, cepInfo, weatherInfo, lowConfidence || prefixMatch, source)
+	if result != nil {
+		result.Alternatives = alternatives
+	}
+	return result, lookupErr
+}
+
+// Monta a resposta a partir do clima obtido (pelo endereço do CEP ou direto pelo código
+// postal), aplicando WEATHER_SOFT_FAIL_CODES e EMPTY_CITY
+func newLookupResult(span trace.Span, cepInfo *CEP, weatherInfo *WeatherData, lowConfidence bool, source string) (*lookupResult, *lookupError) {
+	span.AddEvent("weather_resolved")
+
+	// Códigos de condição configurados em WEATHER_SOFT_FAIL_CODES (ex.: sem dados)
+	// não produzem uma resposta normal
+	if code := weatherInfo.Current.Condition.Code; weatherSoftFailCodes[code] {
+		span.SetAttributes(
+			attribute.Bool("weather.soft_failure", true),
+			attribute.Int("weather.condition_code", code),
+		)
+		historical, ok := historicalWeather(span, cepInfo, time.Now())
+		if !ok {
+			return nil, &lookupError{Status: weatherSoftFailStatus, Message: "weather data unavailable", Upstream: weatherProviderWeatherAPI}
+		}
+		weatherInfo, source = historical, sourceHistoricalAverage
 	}
 
+	// Só com a origem final conhecida: no fallback, quem serviu foi a média histórica
+	span.SetAttributes(attribute.String("weather.provider", weatherProviderFor(source)))
+
 	// Prepara resposta com todas as temperaturas conforme especificação
 	tempC := weatherInfo.Current.TempC
 	response := TemperatureResponse{
-		City:  weatherInfo.Location.Name,
-		TempC: tempC,
-		TempF: celsiusToFahrenheit(tempC),
-		TempK: celsiusToKelvin(tempC),
+		City:   weatherInfo.Location.Name,
+		Region: weatherInfo.Location.Region,
+		UF:     cepInfo.Uf,
+		TempC:  Temperature(tempC),
+		TempF:  Temperature(celsiusToFahrenheit(tempC)),
+		TempK:  Temperature(celsiusToKelvin(tempC)),
+
+		LowConfidence: lowConfidence,
+		Source:        source,
+	}
+
+	// NaN e infinito não têm representação em JSON: a falha acontece aqui, antes de o
+	// handler enviar o status 200
+	if !isFinite(tempC, weatherInfo.Current.FeelslikeC, float64(response.TempF), float64(response.TempK)) {
+		span.SetAttributes(attribute.String("weather.invalid_temperature", fmt.Sprint(tempC)))
+		return nil, &lookupError{Status: http.StatusBadGateway, Message: "invalid weather data", Upstream: weatherProviderFor(source)}
+	}
+
+	// A WeatherAPI às vezes responde 200 com location.name vazio: usa a localidade do
+	// ViaCEP (EMPTY_CITY=fallback) ou só sinaliza no span (EMPTY_CITY=flag)
+	if strings.TrimSpace(response.City) == "" {
+		span.SetAttributes(attribute.Bool("response.city_empty", true))
+		if emptyCityMode == emptyCityFallback {
+			response.City = cepInfo.Localidade
+			span.SetAttributes(attribute.Bool("response.city_fallback", true))
+		}
 	}
 
 	// Adiciona informações ao span
 	span.SetAttributes(
 		attribute.String("response.city", response.City),
-		attribute.Float64("response.temp_c", response.TempC),
-		attribute.Float64("response.temp_f", response.TempF),
-		attribute.Float64("response.temp_k", response.TempK),
+		attribute.Float64("response.temp_c", float64(response.TempC)),
+		attribute.Float64("response.temp_f", float64(response.TempF)),
+		attribute.Float64("response.temp_k", float64(response.TempK)),
 	)
 
-	// Sucesso: 200 com as temperaturas
+	return &lookupResult{Response: response, CEP: cepInfo, Weather: weatherInfo}, nil
+}
+
+// Indica se todos os valores são finitos (nem NaN nem infinito)
+func isFinite(values ...float64) bool {
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// Handler principal para consulta de CEP e clima
+func weatherHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Inicia span para o handler
+	ctx, span := tracer.Start(ctx, "weather_handler")
+	defer span.End()
+
+	// Configura headers de resposta
+	w.Header().Set("Content-Type", "application/json")
+
+	// Extrai o CEP da URL
+	vars := mux.Vars(r)
+	cep := vars["cep"]
+	query := r.URL.Query()
+
+	// Campos pedidos via ?fields= são validados antes de chamar os upstreams
+	fields, err := parseFields(query.Get("fields"))
+	if err != nil {
+		writeError(w, span, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Temperaturas inteiras (?int=true) são um formato próprio: não combinam com
+	// ?fields= nem com ?verbose=true
+	integer := query.Get("int") == "true"
+	if integer && (fields != nil || query.Get("verbose") == "true") {
+		writeError(w, span, http.StatusBadRequest, "int cannot be combined with fields or verbose")
+		return
+	}
+
+	// Temperatura única (?single=true) na unidade de DEFAULT_TEMP_UNIT também é um
+	// formato próprio
+	single := query.Get("single") == "true"
+	if single && (fields != nil || integer || query.Get("verbose") == "true") {
+		writeError(w, span, http.StatusBadRequest, "single cannot be combined with fields, int or verbose")
+		return
+	}
+
+	// Unidade preferida (?primary=F) só marca a resposta: as três temperaturas seguem
+	// presentes. Não se aplica a ?single=true, que já traz uma única unidade
+	primary, err := parsePrimaryUnit(query.Get("primary"))
+	if err != nil {
+		writeError(w, span, http.StatusBadRequest, err.Error())
+		return
+	}
+	if single && primary != "" {
+		writeError(w, span, http.StatusBadRequest, "single cannot be combined with primary")
+		return
+	}
+
+	// Dados do CEP (?include=ddd,ibge) entram na resposta completa ou detalhada
+	include, err := parseInclude(query.Get("include"))
+	if err != nil {
+		writeError(w, span, http.StatusBadRequest, err.Error())
+		return
+	}
+	if include != nil && (fields != nil || integer || single) {
+		writeError(w, span, http.StatusBadRequest, "include cannot be combined with fields, int or single")
+		return
+	}
+
+	// Qualidade do ar (?aqi=true) só aparece na resposta detalhada
+	verbose := fields == nil && query.Get("verbose") == "true"
+	withAQI := verbose && query.Get("aqi") == "true"
+
+	result, lookupErr := lookupTemperature(ctx, query.Get("country"), cep, withAQI)
+	recordLookupOutcome(ctx, span, lookupOutcome(lookupErr))
+	if lookupErr != nil && lookupErr.Status == statusClientClosedRequest {
+		writeClientClosed(w, span)
+		return
+	}
+	if lookupErr != nil {
+		writeLookupError(w, span, lookupErr)
+		return
+	}
+
+	// Sucesso: 200 com as temperaturas (só os campos de ?fields=, ou a resposta
+	// detalhada com ?verbose=true), cacheável até a próxima leitura da WeatherAPI
+	cacheControl := weatherCacheControl(result.Weather.Current.LastUpdatedEpoch, time.Now())
+	w.Header().Set("Cache-Control", cacheControl)
+	span.SetAttributes(attribute.String("http.response.cache_control", cacheControl))
+	applyInclude(&result.Response, result.CEP, include)
+	result.Response.GeneratedAt = generatedAt(r, time.Now())
+	if primary != "" {
+		result.Response.PrimaryUnit = primary
+		span.SetAttributes(attribute.String("response.primary_unit", primary))
+	}
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	switch {
+	case fields != nil:
+		json.NewEncoder(w).Encode(selectFields(result.Response, fields))
+	case verbose:
+		json.NewEncoder(w).Encode(newVerboseResponse(result))
+	case integer:
+		json.NewEncoder(w).Encode(newIntegerResponse(result.Response))
+	case single:
+		json.NewEncoder(w).Encode(newSingleResponse(result.Response, defaultTempUnit))
+	default:
+		json.NewEncoder(w).Encode(result.Response)
+	}
+	span.AddEvent("response_written")
 }
@@ -0,0 +1,1212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+)
+
+// Endereço devolvido pelo ViaCEP simulado para qualquer CEP
+var mockCEP = CEP{Cep: "01001-000", Logradouro: "Praça da Sé", Bairro: "Sé", Localidade: "São Paulo", Uf: "SP", Ibge: "3550308", Ddd: "11"}
+
+// Responde como o ViaCEP, com mockCEP para qualquer CEP
+func mockViaCEP() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(mockCEP)
+	})
+}
+
+// Responde como a WeatherAPI, com a temperatura tempC para qualquer localidade
+func mockWeatherAPI(tempC float64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data WeatherData
+		data.Location.Name = "Sao Paulo"
+		data.Location.Region = "Sao Paulo"
+		data.Location.Country = "Brazil"
+		data.Current.LastUpdatedEpoch = int(time.Now().Unix())
+		data.Current.TempC = tempC
+		data.Current.Condition.Text = "Sol"
+		data.Current.Condition.Code = 1000
+		json.NewEncoder(w).Encode(data)
+	})
+}
+
+// Aponta o ViaCEP e a WeatherAPI para os handlers simulados, com um cliente HTTP sem
+// instrumentação e os caches desabilitados. Restaura o estado anterior ao fim do teste
+func withMockUpstreams(tb testing.TB, viacep, weatherapi http.Handler) {
+	tb.Helper()
+	viacepSrv := httptest.NewServer(viacep)
+	weatherSrv := httptest.NewServer(weatherapi)
+
+	prevClient, prevViaCEP, prevWeather := httpClient, viaCEPBaseURL, weatherAPIBaseURL
+	prevCEPCache, prevWeatherCache, prevNegative := cepCache, weatherCache, weatherNegativeCache
+	tb.Cleanup(func() {
+		viacepSrv.Close()
+		weatherSrv.Close()
+		httpClient, viaCEPBaseURL, weatherAPIBaseURL = prevClient, prevViaCEP, prevWeather
+		cepCache, weatherCache, weatherNegativeCache = prevCEPCache, prevWeatherCache, prevNegative
+	})
+
+	httpClient = &http.Client{Timeout: 5 * time.Second}
+	viaCEPBaseURL, weatherAPIBaseURL = viacepSrv.URL, weatherSrv.URL
+	cepCache = newCache[CEP]("cep", 0)
+	weatherCache = newCache[WeatherData]("weather", 0)
+	weatherNegativeCache = newCache[*weatherAPIError]("weather_negative", 0)
+}
+
+// Troca o tracer do pacote por um que grava os spans, restaurado ao fim do teste. O
+// provider global só delega para o primeiro registrado, por isso não é usado aqui
+func withSpanRecorder(tb testing.TB) *tracetest.SpanRecorder {
+	tb.Helper()
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	prev := tracer
+	tracer = tp.Tracer("service-b")
+	tb.Cleanup(func() {
+		tracer = prev
+		tp.Shutdown(context.Background())
+	})
+	return sr
+}
+
+// Span encerrado com o nome dado; falha o teste se não houver
+func endedSpan(tb testing.TB, sr *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	tb.Helper()
+	for _, s := range sr.Ended() {
+		if s.Name() == name {
+			return s
+		}
+	}
+	tb.Fatalf("span %q não encontrado", name)
+	return nil
+}
+
+// Valor do atributo key no span; vazio se ausente
+func spanAttr(s sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range s.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+// Roteador só com /{cep}, como em run, para que mux.Vars funcione no handler
+func newWeatherRouter() http.Handler {
+	r := mux.NewRouter()
+	r.HandleFunc("/{cep}", weatherHandler).Methods("GET", "HEAD")
+	return r
+}
+
+// O span do servidor leva o template da rota, nunca o CEP consultado, para que o número
+// de nomes de span não cresça com os CEPs
+func TestRouteSpanName(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer tp.Shutdown(context.Background())
+
+	r := mux.NewRouter()
+	r.Use(otelmux.Middleware("service-b", otelmux.WithTracerProvider(tp), otelmux.WithSpanNameFormatter(routeSpanName)))
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	r.HandleFunc("/health", ok)
+	r.HandleFunc("/batch/{country}", ok)
+	r.HandleFunc("/{cep}", ok)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/01001000", "/{cep}"},
+		{"/99999999", "/{cep}"},
+		{"/batch/br", "/batch/{country}"},
+		{"/health", "/health"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			exporter.Reset()
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 || spans[0].Name != tt.want {
+				t.Errorf("spans = %v, want um span %q", spanNames(spans), tt.want)
+			}
+		})
+	}
+
+	// Fora de uma rota, vale o nome passado ao otelmux
+	if got := routeSpanName("service-b", httptest.NewRequest(http.MethodGet, "/01001000", nil)); got != "service-b" {
+		t.Errorf("routeSpanName sem rota = %q, want service-b", got)
+	}
+}
+
+func spanNames(spans tracetest.SpanStubs) []string {
+	names := make([]string, len(spans))
+	for i, s := range spans {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// RoundTripper a partir de uma função, para interceptar as chamadas do httpClient
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// Sem WEATHER_API_BASE_URL, a WeatherAPI é chamada por HTTPS, já que a chave vai na URL
+func TestWeatherAPIURLDefaultsToHTTPS(t *testing.T) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(cfg.WeatherAPIBaseURL, "https://") {
+		t.Errorf("WEATHER_API_BASE_URL padrão = %q, want https://", cfg.WeatherAPIBaseURL)
+	}
+
+	defaultBase := weatherAPIBaseURL
+	withMockUpstreams(t, mockViaCEP(), mockWeatherAPI(25))
+	weatherAPIBaseURL = defaultBase
+	defer func(k *apiKeyStore) { weatherAPIKey = k }(weatherAPIKey)
+	weatherAPIKey = &apiKeyStore{}
+	weatherAPIKey.Set("segredo123")
+
+	var weatherURL string
+	mockHost := strings.TrimPrefix(viaCEPBaseURL, "http://")
+	httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == mockHost {
+			return http.DefaultTransport.RoundTrip(req)
+		}
+		weatherURL = req.URL.String()
+		rec := httptest.NewRecorder()
+		mockWeatherAPI(25).ServeHTTP(rec, req)
+		return rec.Result(), nil
+	})}
+
+	rec := httptest.NewRecorder()
+	newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body)
+	}
+	if !strings.HasPrefix(weatherURL, "https://api.weatherapi.com/v1/current.json?") {
+		t.Errorf("URL da WeatherAPI = %q, want https://api.weatherapi.com", weatherURL)
+	}
+}
+
+// Corpos de erro documentados pela WeatherAPI, com a chave embutida na mensagem para
+// verificar a remoção
+func TestParseWeatherAPIError(t *testing.T) {
+	defer func(k *apiKeyStore) { weatherAPIKey = k }(weatherAPIKey)
+	weatherAPIKey = &apiKeyStore{}
+	weatherAPIKey.Set("segredo123")
+
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		wantCode int
+		wantMsg  string
+		auth     bool
+		notFound bool
+	}{
+		{"chave ausente", 401, `{"error":{"code":1002,"message":"API key not provided."}}`, 1002, "API key not provided.", true, false},
+		{"chave inválida", 401, `{"error":{"code":2006,"message":"API key segredo123 is invalid."}}`, 2006, "API key REDACTED is invalid.", true, false},
+		{"cota excedida", 403, `{"error":{"code":2007,"message":"API key has exceeded calls per month quota."}}`, 2007, "API key has exceeded calls per month quota.", true, false},
+		{"chave desativada", 403, `{"error":{"code":2008,"message":"API key has been disabled."}}`, 2008, "API key has been disabled.", true, false},
+		{"q ausente", 400, `{"error":{"code":1003,"message":"Parameter q not provided."}}`, 1003, "Parameter q not provided.", false, false},
+		{"localidade não encontrada", 400, `{"error":{"code":1006,"message":"No matching location found."}}`, 1006, "No matching location found.", false, true},
+		{"corpo não JSON", 502, `<html>Bad Gateway</html>`, 0, "Bad Gateway", false, false},
+		{"401 sem corpo", 401, ``, 0, "Unauthorized", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body))}
+			err := parseWeatherAPIError(resp)
+
+			if err.StatusCode != tt.status || err.Code != tt.wantCode || err.Message != tt.wantMsg {
+				t.Errorf("parseWeatherAPIError = %d, %d, %q, want %d, %d, %q",
+					err.StatusCode, err.Code, err.Message, tt.status, tt.wantCode, tt.wantMsg)
+			}
+			if err.isAuthFailure() != tt.auth {
+				t.Errorf("isAuthFailure = %v, want %v", err.isAuthFailure(), tt.auth)
+			}
+			if err.isLocationNotFound() != tt.notFound {
+				t.Errorf("isLocationNotFound = %v, want %v", err.isLocationNotFound(), tt.notFound)
+			}
+			if strings.Contains(err.Error(), "segredo123") {
+				t.Errorf("Error() expõe a chave: %s", err)
+			}
+		})
+	}
+}
+
+// Falha de autenticação vira 500 sem detalhes (erro do operador); localidade não
+// encontrada vira 404
+func TestWeatherHandlerWeatherAPIErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantStatus int
+	}{
+		{"chave inválida", 401, `{"error":{"code":2006,"message":"API key is invalid."}}`, http.StatusInternalServerError},
+		{"cota excedida", 403, `{"error":{"code":2007,"message":"API key has exceeded calls per month quota."}}`, http.StatusInternalServerError},
+		{"localidade não encontrada", 400, `{"error":{"code":1006,"message":"No matching location found."}}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withMockUpstreams(t, mockViaCEP(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if strings.Contains(rec.Body.String(), "API key") {
+				t.Errorf("resposta expõe a mensagem da WeatherAPI: %s", rec.Body)
+			}
+		})
+	}
+}
+
+// Uma resposta 200 com o objeto error da WeatherAPI é uma falha com o código recebido,
+// não uma temperatura zero; com WEATHER_EMBEDDED_ERROR=flag, só fica registrada no span e
+// os dados recebidos são usados
+func TestWeatherHandlerEmbeddedError(t *testing.T) {
+	defer func(mode string) { weatherEmbeddedError = mode }(weatherEmbeddedError)
+
+	withData := func(errJSON string) string {
+		return fmt.Sprintf(`{"location":{"name":"Sao Paulo","region":"Sao Paulo","country":"Brazil"},`+
+			`"current":{"last_updated_epoch":%d,"temp_c":21,"condition":{"text":"Sol","code":1000}},"error":%s}`,
+			time.Now().Unix(), errJSON)
+	}
+
+	tests := []struct {
+		name       string
+		mode       string
+		body       string
+		wantStatus int
+		wantCode   int64 // weather.error_code no span get_weather_info; 0: ausente
+		wantEvent  bool  // evento weather_embedded_error
+	}{
+		{"chave inválida", embeddedErrorFail, `{"error":{"code":2006,"message":"API key is invalid."}}`, http.StatusInternalServerError, 2006, false},
+		{"localidade não encontrada", embeddedErrorFail, `{"error":{"code":1006,"message":"No matching location found."}}`, http.StatusNotFound, 1006, false},
+		{"com dados, fail", embeddedErrorFail, withData(`{"code":9999,"message":"Internal application error."}`), http.StatusInternalServerError, 9999, false},
+		{"com dados, flag", embeddedErrorFlag, withData(`{"code":9999,"message":"Internal application error."}`), http.StatusOK, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weatherEmbeddedError = tt.mode
+			withMockUpstreams(t, mockViaCEP(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, tt.body)
+			}))
+			sr := withSpanRecorder(t)
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if strings.Contains(rec.Body.String(), "temp_C\":0") {
+				t.Errorf("resposta com temperatura zero: %s", rec.Body)
+			}
+			if tt.wantStatus == http.StatusOK {
+				var resp TemperatureResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.TempC != 21 {
+					t.Errorf("temp_C = %v (%v), want 21", resp.TempC, err)
+				}
+			}
+
+			span := endedSpan(t, sr, "get_weather_info")
+			if !spanAttr(span, "weather.embedded_error").AsBool() {
+				t.Error("weather.embedded_error ausente no span")
+			}
+			if got := spanAttr(span, "weather.error_code").AsInt64(); got != tt.wantCode {
+				t.Errorf("weather.error_code = %d, want %d", got, tt.wantCode)
+			}
+			hasEvent := slices.ContainsFunc(span.Events(), func(ev sdktrace.Event) bool { return ev.Name == "weather_embedded_error" })
+			if hasEvent != tt.wantEvent {
+				t.Errorf("evento weather_embedded_error = %v, want %v", hasEvent, tt.wantEvent)
+			}
+		})
+	}
+}
+
+// Temperaturas que não cabem no JSON (aqui, °F infinito) viram 502 em vez de um 200 com
+// o corpo truncado
+func TestWeatherHandlerNonFiniteTemperature(t *testing.T) {
+	withMockUpstreams(t, mockViaCEP(), mockWeatherAPI(math.MaxFloat64))
+
+	rec := httptest.NewRecorder()
+	newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want 502: %s", rec.Code, rec.Body)
+	}
+	var body ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Message != "invalid weather data" {
+		t.Errorf("body = %+v (%v), want invalid weather data", body, err)
+	}
+}
+
+// WeatherAPI respondendo 200 com location.name vazio: EMPTY_CITY=fallback usa a localidade
+// do ViaCEP, EMPTY_CITY=flag mantém a cidade vazia; os dois marcam o span
+func TestWeatherHandlerEmptyCity(t *testing.T) {
+	defer func(m string) { emptyCityMode = m }(emptyCityMode)
+
+	tests := []struct {
+		mode         string
+		wantCity     string
+		wantFallback bool
+	}{
+		{emptyCityFallback, mockCEP.Localidade, true},
+		{emptyCityFlag, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			emptyCityMode = tt.mode
+			withMockUpstreams(t, mockViaCEP(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, `{"location":{"name":"  ","country":"Brazil"},"current":{"temp_c":23.5,"condition":{"text":"Sol","code":1000}}}`)
+			}))
+			sr := withSpanRecorder(t)
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			var body TemperatureResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(body.City) != tt.wantCity {
+				t.Errorf("city = %q, want %q", body.City, tt.wantCity)
+			}
+
+			span := endedSpan(t, sr, "weather_handler")
+			if !spanAttr(span, "response.city_empty").AsBool() {
+				t.Error("response.city_empty ausente no span")
+			}
+			if got := spanAttr(span, "response.city_fallback").AsBool(); got != tt.wantFallback {
+				t.Errorf("response.city_fallback = %v, want %v", got, tt.wantFallback)
+			}
+		})
+	}
+}
+
+// Condições em WEATHER_SOFT_FAIL_CODES não viram um 200 normal: respondem com
+// WEATHER_SOFT_FAIL_STATUS e marcam o span; as demais seguem normalmente
+func TestWeatherHandlerSoftFailCodes(t *testing.T) {
+	defer func(codes map[int]bool, status int, fallback bool) {
+		weatherSoftFailCodes, weatherSoftFailStatus, historicalFallback = codes, status, fallback
+	}(weatherSoftFailCodes, weatherSoftFailStatus, historicalFallback)
+	weatherSoftFailCodes = map[int]bool{1000: true}
+	historicalFallback = false
+
+	tests := []struct {
+		name       string
+		condition  int
+		status     int
+		wantStatus int
+	}{
+		{"código configurado, 502", 1000, http.StatusBadGateway, http.StatusBadGateway},
+		{"código configurado, 503", 1000, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+		{"outro código", 1003, http.StatusBadGateway, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weatherSoftFailStatus = tt.status
+			withMockUpstreams(t, mockViaCEP(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, fmt.Sprintf(`{"location":{"name":"Sao Paulo","country":"Brazil"},"current":{"temp_c":23.5,"condition":{"text":"x","code":%d}}}`, tt.condition))
+			}))
+			sr := withSpanRecorder(t)
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			span := endedSpan(t, sr, "weather_handler")
+			softFail := tt.wantStatus != http.StatusOK
+			if got := spanAttr(span, "weather.soft_failure").AsBool(); got != softFail {
+				t.Errorf("weather.soft_failure = %v, want %v", got, softFail)
+			}
+			if softFail {
+				if got := spanAttr(span, "weather.condition_code").AsInt64(); got != int64(tt.condition) {
+					t.Errorf("weather.condition_code = %d, want %d", got, tt.condition)
+				}
+				if !strings.Contains(rec.Body.String(), "weather data unavailable") {
+					t.Errorf("body = %s, want weather data unavailable", rec.Body)
+				}
+			}
+		})
+	}
+}
+
+// weather.provider no span do handler nomeia quem serviu a resposta: a WeatherAPI ou,
+// com ela fora do ar, a média histórica
+func TestWeatherProviderAttribute(t *testing.T) {
+	defer func(b bool) { historicalFallback = b }(historicalFallback)
+	historicalFallback = true
+
+	tests := []struct {
+		name       string
+		weatherapi http.Handler
+		want       string
+	}{
+		{"WeatherAPI responde", mockWeatherAPI(23.5), weatherProviderWeatherAPI},
+		{"WeatherAPI falha", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"error":{"code":9999,"message":"Internal application error."}}`)
+		}), sourceHistoricalAverage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withMockUpstreams(t, mockViaCEP(), tt.weatherapi)
+			sr := withSpanRecorder(t)
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+
+			span := endedSpan(t, sr, "weather_handler")
+			if got := spanAttr(span, "weather.provider").AsString(); got != tt.want {
+				t.Errorf("weather.provider = %q, want %q", got, tt.want)
+			}
+			if got := spanAttr(span, "cep.provider").AsString(); got != cepProviderViaCEP {
+				t.Errorf("cep.provider = %q, want %q", got, cepProviderViaCEP)
+			}
+		})
+	}
+}
+
+// Com ou sem ENABLE_PPROF, /debug/pprof/ nunca é servido na porta pública: o pprof
+// fica no listener de PPROF_ADDR
+func TestPprofNotOnPublicHandler(t *testing.T) {
+	for _, enabled := range []string{"false", "true"} {
+		t.Run("ENABLE_PPROF="+enabled, func(t *testing.T) {
+			t.Setenv("ENABLE_PPROF", enabled)
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			handler := newHandler(cfg)
+			for _, path := range []string{"/debug/pprof/", "/debug/pprof/cmdline", "/debug/pprof/heap"} {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+				if rec.Code != http.StatusNotFound {
+					t.Errorf("GET %s: status = %d, want 404", path, rec.Code)
+				}
+			}
+		})
+	}
+}
+
+// CEPs distintos da mesma localidade compartilham a entrada do cache de clima: só o
+// primeiro chama a WeatherAPI; outra cidade tem a sua própria entrada
+func TestWeatherCacheSharedByLocality(t *testing.T) {
+	cities := map[string]string{"01001000": "São Paulo", "01310100": "São Paulo", "20040020": "Rio de Janeiro"}
+	viacep := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cep := strings.Split(strings.Trim(r.URL.Path, "/"), "/")[1]
+		json.NewEncoder(w).Encode(CEP{Cep: cep, Localidade: cities[cep], Uf: "SP"})
+	})
+	var weatherCalls []string
+	weatherapi := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		weatherCalls = append(weatherCalls, r.URL.Query().Get("q"))
+		mockWeatherAPI(23.5).ServeHTTP(w, r)
+	})
+	withMockUpstreams(t, viacep, weatherapi)
+	weatherCache = newCache[WeatherData]("weather", time.Hour)
+	sr := withSpanRecorder(t)
+
+	tests := []struct {
+		cep      string
+		wantHit  bool
+		wantCall int
+	}{
+		{"01001000", false, 1},
+		{"01310100", true, 1},
+		{"20040020", false, 2},
+	}
+
+	handler := newWeatherRouter()
+	for _, tt := range tests {
+		sr.Reset()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+tt.cep, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", tt.cep, rec.Code, rec.Body)
+		}
+
+		span := endedSpan(t, sr, "get_weather_info")
+		if got := spanAttr(span, "weather.cache_hit").AsBool(); got != tt.wantHit {
+			t.Errorf("%s: weather.cache_hit = %v, want %v", tt.cep, got, tt.wantHit)
+		}
+		if len(weatherCalls) != tt.wantCall {
+			t.Errorf("%s: chamadas à WeatherAPI = %v, want %d", tt.cep, weatherCalls, tt.wantCall)
+		}
+	}
+}
+
+// "Localidade não encontrada" fica no cache negativo até expirar e é devolvida sem chamar
+// a WeatherAPI; outras falhas não são cacheadas
+func TestWeatherNegativeCache(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantCached bool
+	}{
+		{"localidade não encontrada", 400, `{"error":{"code":1006,"message":"No matching location found."}}`, true},
+		{"chave inválida", 401, `{"error":{"code":2006,"message":"API key is invalid."}}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			withMockUpstreams(t, mockViaCEP(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			weatherNegativeCache = newCache[*weatherAPIError]("weather_negative", 50*time.Millisecond)
+			sr := withSpanRecorder(t)
+			handler := newWeatherRouter()
+
+			lookup := func() (upstreamCalls int, negativeHit bool) {
+				sr.Reset()
+				before := calls
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+				if rec.Code < 400 {
+					t.Fatalf("status = %d, want erro: %s", rec.Code, rec.Body)
+				}
+				return calls - before, spanAttr(endedSpan(t, sr, "get_weather_info"), "weather.negative_cache_hit").AsBool()
+			}
+
+			if n, hit := lookup(); n == 0 || hit {
+				t.Fatalf("primeira consulta: chamadas = %d, negative_cache_hit = %v", n, hit)
+			}
+			n, hit := lookup()
+			if hit != tt.wantCached || (n == 0) != tt.wantCached {
+				t.Errorf("segunda consulta: chamadas = %d, negative_cache_hit = %v, want cache %v", n, hit, tt.wantCached)
+			}
+
+			time.Sleep(60 * time.Millisecond)
+			if n, hit := lookup(); n == 0 || hit {
+				t.Errorf("após o ttl: chamadas = %d, negative_cache_hit = %v, want nova chamada", n, hit)
+			}
+		})
+	}
+}
+
+// Headers acima de MAX_HEADER_BYTES são recusados com 431 antes de chegar ao handler. O
+// net/http soma 4 KiB de folga ao limite, por isso o header grande tem 16 KiB
+func TestServerMaxHeaderBytes(t *testing.T) {
+	server := newServer(Config{MaxHeaderBytes: 1 << 10}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ln)
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		size int
+		want int
+	}{
+		{"header pequeno", 100, http.StatusOK},
+		{"header acima do limite", 16 << 10, http.StatusRequestHeaderFieldsTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "http://"+ln.Addr().String()+"/", nil)
+			req.Header.Set("X-Grande", strings.Repeat("a", tt.size))
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+// O CEP chega ao ViaCEP com os zeros à esquerda, com ou sem traço na entrada
+func TestWeatherHandlerKeepsLeadingZeros(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/01001000", "/ws/01001000/json/"},
+		{"/01001-000", "/ws/01001000/json/"},
+		{"/00100000", "/ws/00100000/json/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var got string
+			withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.URL.Path
+				mockViaCEP().ServeHTTP(w, r)
+			}), mockWeatherAPI(23.5))
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			if got != tt.want {
+				t.Errorf("URL do ViaCEP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Bytes de controle e UTF-8 inválido no path (ex.: %00) são 400 "malformed zipcode";
+// entradas legíveis fora do formato continuam 422. Nenhuma chama os upstreams nem leva
+// o valor bruto ao span
+func TestWeatherHandlerMalformedCEP(t *testing.T) {
+	var calls atomic.Int32
+	withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		mockViaCEP().ServeHTTP(w, r)
+	}), mockWeatherAPI(23.5))
+	sr := withSpanRecorder(t)
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantMsg    string
+	}{
+		{"/0100%001000", http.StatusBadRequest, "malformed zipcode"},
+		{"/01001000%0A", http.StatusBadRequest, "malformed zipcode"},
+		{"/%1B%5B2J", http.StatusBadRequest, "malformed zipcode"},
+		{"/0100%FF1000", http.StatusBadRequest, "malformed zipcode"},
+		{"/abcdefgh", http.StatusUnprocessableEntity, "invalid zipcode"},
+		{"/0100100", http.StatusUnprocessableEntity, "invalid zipcode"},
+		{"/01001%20000", http.StatusUnprocessableEntity, "invalid zipcode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			calls.Store(0)
+			sr.Reset()
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			var body ErrorResponse
+			json.NewDecoder(rec.Body).Decode(&body)
+			if rec.Code != tt.wantStatus || body.Message != tt.wantMsg {
+				t.Errorf("resposta = %d %q, want %d %q", rec.Code, body.Message, tt.wantStatus, tt.wantMsg)
+			}
+			if n := calls.Load(); n != 0 {
+				t.Errorf("chamadas ao ViaCEP = %d, want 0", n)
+			}
+			span := endedSpan(t, sr, "weather_handler")
+			if tt.wantStatus == http.StatusBadRequest && spanAttr(span, "cep").Type() != attribute.INVALID {
+				t.Errorf("cep malformado no span: %q", spanAttr(span, "cep").AsString())
+			}
+		})
+	}
+}
+
+// 400 do ViaCEP é o CEP rejeitado pelo formato: responde 422, não 404, e não consulta
+// a WeatherAPI. Outros status de erro seguem como CEP não encontrado
+func TestWeatherHandlerViaCEPBadRequest(t *testing.T) {
+	tests := []struct {
+		name           string
+		viacepStatus   int
+		wantStatus     int
+		wantMsg        string
+		wantValidation string
+	}{
+		{"400", http.StatusBadRequest, http.StatusUnprocessableEntity, "invalid zipcode", "invalid_zipcode_upstream"},
+		{"500", http.StatusInternalServerError, http.StatusNotFound, "can not find zipcode", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var weatherCalls atomic.Int32
+			withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.viacepStatus)
+				io.WriteString(w, "<h1>Bad Request</h1>")
+			}), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				weatherCalls.Add(1)
+				mockWeatherAPI(23.5).ServeHTTP(w, r)
+			}))
+			sr := withSpanRecorder(t)
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+
+			var body ErrorResponse
+			json.NewDecoder(rec.Body).Decode(&body)
+			if rec.Code != tt.wantStatus || body.Message != tt.wantMsg {
+				t.Errorf("resposta = %d %q, want %d %q", rec.Code, body.Message, tt.wantStatus, tt.wantMsg)
+			}
+			if n := weatherCalls.Load(); n != 0 {
+				t.Errorf("chamadas à WeatherAPI = %d, want 0", n)
+			}
+			span := endedSpan(t, sr, "weather_handler")
+			if got := spanAttr(span, "validation").AsString(); got != tt.wantValidation {
+				t.Errorf("validation = %q, want %q", got, tt.wantValidation)
+			}
+			if got := spanAttr(span, "error.upstream").AsString(); got != cepProviderViaCEP {
+				t.Errorf("error.upstream = %q, want %q", got, cepProviderViaCEP)
+			}
+		})
+	}
+}
+
+// Com VERBOSE_ERRORS=true cada falha de validação traz o próprio código e detalhe; sem
+// ele, só a mensagem
+func TestWeatherHandlerVerboseErrors(t *testing.T) {
+	defer func(v bool) { verboseErrors = v }(verboseErrors)
+	withMockUpstreams(t, mockViaCEP(), mockWeatherAPI(23.5))
+
+	tests := []struct {
+		name        string
+		verbose     bool
+		path        string
+		wantStatus  int
+		wantCode    string
+		wantDetails string
+	}{
+		{"malformado", true, "/0100%001000", http.StatusBadRequest, "MALFORMED", "zipcode contains control characters or invalid UTF-8"},
+		{"caractere inválido", true, "/0100100a", http.StatusUnprocessableEntity, "INVALID_CHARACTERS", "expected only digits, got 'a' at position 8"},
+		{"sete dígitos", true, "/0100100", http.StatusUnprocessableEntity, "INVALID_FORMAT", "expected 8 digits, got 7"},
+		{"país não suportado", true, "/01001000?country=US", http.StatusUnprocessableEntity, "UNSUPPORTED_COUNTRY", `country "US" is not supported`},
+		{"desligado", false, "/0100100", http.StatusUnprocessableEntity, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verboseErrors = tt.verbose
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			var body ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != tt.wantStatus || body.Code != tt.wantCode || body.Details != tt.wantDetails {
+				t.Errorf("resposta = %d %+v, want %d com code %q e details %q", rec.Code, body, tt.wantStatus, tt.wantCode, tt.wantDetails)
+			}
+		})
+	}
+}
+
+// Temperaturas muito pequenas saem em notação decimal em todas as respostas (simples,
+// verbose e batch), nunca como 1e-05
+func TestWeatherHandlerNoScientificNotation(t *testing.T) {
+	withBatchConfig(t)
+	withMockUpstreams(t, mockViaCEP(), mockWeatherAPI(0.00001))
+
+	tests := []struct {
+		name string
+		req  *http.Request
+	}{
+		{"simples", httptest.NewRequest(http.MethodGet, "/01001000", nil)},
+		{"verbose", httptest.NewRequest(http.MethodGet, "/01001000?verbose=true", nil)},
+		{"batch", httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`["01001000"]`))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			if tt.req.Method == http.MethodPost {
+				batchHandler(rec, tt.req)
+			} else {
+				newWeatherRouter().ServeHTTP(rec, tt.req)
+			}
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body)
+			}
+			body := rec.Body.String()
+			if !strings.Contains(body, `"temp_C":0.00001`) || strings.Contains(body, "e-05") {
+				t.Errorf("body = %s, want temp_C 0.00001 em notação decimal", body)
+			}
+		})
+	}
+}
+
+// region vem de location.region da WeatherAPI e uf do CEP; sem os dados, os campos são
+// omitidos em vez de virem vazios
+func TestWeatherHandlerRegionAndUF(t *testing.T) {
+	tests := []struct {
+		name       string
+		cep        CEP
+		region     string
+		wantRegion any
+		wantUF     any
+	}{
+		{"preenchidos", mockCEP, "Sao Paulo", "Sao Paulo", "SP"},
+		{"ausentes", CEP{Cep: "01001-000", Localidade: "São Paulo"}, "", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(tt.cep)
+			}), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var data WeatherData
+				data.Location.Name = "Sao Paulo"
+				data.Location.Region = tt.region
+				data.Location.Country = "Brazil"
+				data.Current.LastUpdatedEpoch = int(time.Now().Unix())
+				data.Current.TempC = 20
+				json.NewEncoder(w).Encode(data)
+			}))
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body)
+			}
+
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body["region"] != tt.wantRegion || body["uf"] != tt.wantUF {
+				t.Errorf("region = %v, uf = %v, want %v e %v", body["region"], body["uf"], tt.wantRegion, tt.wantUF)
+			}
+		})
+	}
+}
+
+// Sem initTracer o tracer do pacote é o global (no-op até um provider ser registrado):
+// os handlers respondem normalmente, sem panic
+func TestHandlersWithoutTracerInit(t *testing.T) {
+	if tracer == nil {
+		t.Fatal("tracer do pacote é nil antes de initTracer")
+	}
+	withBatchConfig(t)
+	withMockUpstreams(t, mockViaCEP(), mockWeatherAPI(22))
+
+	tests := []struct {
+		name       string
+		handler    http.Handler
+		req        *http.Request
+		wantStatus int
+	}{
+		{"/{cep}", newWeatherRouter(), httptest.NewRequest(http.MethodGet, "/01001000", nil), http.StatusOK},
+		{"/{cep} inválido", newWeatherRouter(), httptest.NewRequest(http.MethodGet, "/123", nil), http.StatusUnprocessableEntity},
+		{"/batch", http.HandlerFunc(batchHandler), httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`["01001000"]`)), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, tt.req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+// Collector gRPC simulado que conta os spans recebidos
+type countingTraceService struct {
+	coltracepb.UnimplementedTraceServiceServer
+	spans atomic.Int32
+}
+
+func (s *countingTraceService) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			s.spans.Add(int32(len(ss.Spans)))
+		}
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+// Com OTEL_EXPORTER_OTLP_ENDPOINT_SECONDARY, o provider tem um batch processor para cada
+// collector e os dois recebem os mesmos spans; sem ele, só o principal
+func TestInitTracerSecondaryCollector(t *testing.T) {
+	secondary := &countingTraceService{}
+	grpcServer := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(grpcServer, secondary)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go grpcServer.Serve(l)
+	defer grpcServer.Stop()
+
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	res, err := newResource()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		secondary     string
+		wantSecondary int32
+	}{
+		{"só o principal", "", 0},
+		{"principal e secundário", l.Addr().String(), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secondary.spans.Store(0)
+			primary := tracetest.NewInMemoryExporter()
+
+			var cfg TracingConfig
+			cfg.SpanExporter = primary
+			cfg.SecondaryOTLPEndpoint = tt.secondary
+			cfg.SampleRatio = 1
+			cfg.ExportTimeout = 2 * time.Second
+			tp, err := initTracer(cfg, res)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, span := tp.Tracer("test").Start(context.Background(), "span")
+			span.End()
+			if err := tp.ForceFlush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			defer tp.Shutdown(context.Background())
+
+			if got := len(primary.GetSpans()); got != 1 {
+				t.Errorf("spans no principal = %d, want 1", got)
+			}
+			if got := secondary.spans.Load(); got != tt.wantSecondary {
+				t.Errorf("spans no secundário = %d, want %d", got, tt.wantSecondary)
+			}
+		})
+	}
+}
+
+// As fases do handler aparecem como eventos do span, na ordem em que acontecem
+func TestWeatherHandlerPhaseEvents(t *testing.T) {
+	withMockUpstreams(t, mockViaCEP(), mockWeatherAPI(23.5))
+	sr := withSpanRecorder(t)
+
+	rec := httptest.NewRecorder()
+	newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	var got []string
+	var last time.Time
+	for _, ev := range endedSpan(t, sr, "weather_handler").Events() {
+		got = append(got, ev.Name)
+		if ev.Time.Before(last) {
+			t.Errorf("evento %s registrado antes do anterior", ev.Name)
+		}
+		last = ev.Time
+	}
+	want := []string{"validation_passed", "cep_resolved", "weather_resolved", "response_written"}
+	if !slices.Equal(got, want) {
+		t.Errorf("eventos = %v, want %v", got, want)
+	}
+}
+
+// HEAD responde o mesmo status do GET, sem corpo; CEP fora do formato responde 422 sem
+// chamar os upstreams
+func TestWeatherHandlerHead(t *testing.T) {
+	var upstreamCalls atomic.Int32
+	count := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upstreamCalls.Add(1)
+			h.ServeHTTP(w, r)
+		})
+	}
+	withMockUpstreams(t, count(mockViaCEP()), count(mockWeatherAPI(23.5)))
+	srv := httptest.NewServer(newWeatherRouter())
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		path      string
+		want      int
+		wantCalls bool
+	}{
+		{"CEP válido", "/01001000", http.StatusOK, true},
+		{"CEP com sete dígitos", "/0100100", http.StatusUnprocessableEntity, false},
+		{"CEP com letras", "/0100100a", http.StatusUnprocessableEntity, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			get, err := http.Get(srv.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			get.Body.Close()
+
+			upstreamCalls.Store(0)
+			head, err := http.Head(srv.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(head.Body)
+			head.Body.Close()
+
+			if head.StatusCode != tt.want || get.StatusCode != tt.want {
+				t.Errorf("HEAD = %d, GET = %d, want %d", head.StatusCode, get.StatusCode, tt.want)
+			}
+			if len(body) != 0 {
+				t.Errorf("HEAD com corpo: %q", body)
+			}
+			if got := upstreamCalls.Load() > 0; got != tt.wantCalls {
+				t.Errorf("HEAD chamou os upstreams = %v, want %v", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// run atende até o contexto ser cancelado e então encerra sem erro
+func TestRunStopsOnCancel(t *testing.T) {
+	// run troca o estado do pacote pelo da configuração; restaura o que outros testes usam
+	defer func(c *http.Client, cepC Cache[CEP], weatherC Cache[WeatherData], negC Cache[*weatherAPIError], b *circuitBreaker) {
+		httpClient, cepCache, weatherCache, weatherNegativeCache, weatherBreaker = c, cepC, weatherC, negC, b
+	}(httpClient, cepCache, weatherCache, weatherNegativeCache, weatherBreaker)
+	// O encerramento deixa o /readyz em 503
+	defer shuttingDown.Store(false)
+
+	port := freePort(t)
+	t.Setenv("PORT", port)
+	t.Setenv("TRACE_EXPORTER", "file")
+	t.Setenv("TRACE_FILE_PATH", t.TempDir()+"/traces.jsonl")
+	// Sem collector: a exportação de métricas no encerramento desiste logo
+	t.Setenv("OTEL_EXPORTER_OTLP_METRICS_TIMEOUT", "100")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- run(ctx, cfg) }()
+	waitHealthy(t, port)
+
+	cancel()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("run = %v, want nil", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("run não retornou após o cancelamento")
+	}
+}
+
+// Porta livre para o servidor; fechada antes do retorno para o serviço reabri-la
+func freePort(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	return port
+}
+
+func waitHealthy(t *testing.T, port string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if resp, err := http.Get("http://localhost:" + port + "/health"); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("serviço na porta %s não respondeu /health", port)
+}
+
+// Resultado dos benchmarks, para que o compilador não elimine as chamadas
+var benchTemp float64
+
+func BenchmarkCelsiusToFahrenheit(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchTemp = celsiusToFahrenheit(float64(i % 50))
+	}
+}
+
+func BenchmarkCelsiusToKelvin(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchTemp = celsiusToKelvin(float64(i % 50))
+	}
+}
+
+// Caminho completo do GET /{cep}: ViaCEP e WeatherAPI simulados, com e sem os caches
+func BenchmarkWeatherHandler(b *testing.B) {
+	withMockUpstreams(b, mockViaCEP(), mockWeatherAPI(23.5))
+	handler := newWeatherRouter()
+
+	benchmarks := []struct {
+		name   string
+		cached bool
+	}{
+		{"upstreams", false},
+		{"cache", true},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			ttl := time.Duration(0)
+			if bm.cached {
+				ttl = time.Hour
+			}
+			cepCache = newCache[CEP]("cep", ttl)
+			weatherCache = newCache[WeatherData]("weather", ttl)
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+				if rec.Code != http.StatusOK {
+					b.Fatalf("status = %d: %s", rec.Code, rec.Body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Instrumentos do Serviço B. O meter global delega para o provider configurado em
+// initMeter, então os instrumentos podem ser criados na inicialização do pacote
+var meter = otel.Meter("service-b")
+
+var lookupOutcomes = newLookupOutcomes(meter)
+
+func newLookupOutcomes(m metric.Meter) metric.Int64Counter {
+	c, _ := m.Int64Counter("weather.lookup.outcomes",
+		metric.WithDescription("Consultas por CEP por classe de resultado"),
+		metric.WithUnit("{request}"),
+	)
+	return c
+}
+
+// Classes de resultado da consulta, separando erros do cliente de falhas nossas
+const (
+	outcomeSuccess       = "success"
+	outcomeInvalidFormat = "invalid_format"
+	outcomeNotFound      = "not_found"
+	outcomeUpstreamError = "upstream_error"
+	outcomeCancelled     = "cancelled"
+)
+
+// Classe de resultado a partir do erro da consulta (nil: sucesso)
+func lookupOutcome(err *lookupError) string {
+	switch {
+	case err == nil:
+		return outcomeSuccess
+	case err.Status == http.StatusUnprocessableEntity || err.Status == http.StatusBadRequest:
+		return outcomeInvalidFormat
+	case err.Status == http.StatusNotFound:
+		return outcomeNotFound
+	case err.Status == statusClientClosedRequest:
+		return outcomeCancelled
+	default:
+		return outcomeUpstreamError
+	}
+}
+
+// Registra a classe de resultado no span e no contador
+func recordLookupOutcome(ctx context.Context, span trace.Span, outcome string) {
+	span.SetAttributes(attribute.String("lookup.outcome", outcome))
+	lookupOutcomes.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcome)))
+}
+
+// Exportação de métricas via OTLP para o mesmo collector dos traces. O intervalo
+// segue OTEL_METRIC_EXPORT_INTERVAL (default do SDK: 60s). Com METRICS_DISABLED=true
+// ou TRACE_EXPORTER=file (sem collector) o provider não tem leitor: nenhuma conexão é
+// aberta e os instrumentos não exportam
+func initMeter(cfg Config, res *resource.Resource) (*sdkmetric.MeterProvider, error) {
+	if cfg.MetricsDisabled || cfg.Tracing.Exporter == telemetry.ExporterFile {
+		return sdkmetric.NewMeterProvider(sdkmetric.WithResource(res)), nil
+	}
+
+	exporter, err := otlpmetricgrpc.New(context.Background(),
+		otlpmetricgrpc.WithEndpoint(strings.TrimPrefix(cfg.Tracing.OTLPEndpoint, "http://")),
+		otlpmetricgrpc.WithInsecure(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar exporter de métricas: %w", err)
+	}
+
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+		sdkmetric.WithResource(res),
+	)
+	otel.SetMeterProvider(mp)
+
+	return mp, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// Troca o contador de resultados por um ligado a um leitor manual, restaurado ao fim do
+// teste. Como no tracer, o meter global só delega para o primeiro provider registrado
+func withOutcomeReader(tb testing.TB) *sdkmetric.ManualReader {
+	tb.Helper()
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	prev := lookupOutcomes
+	lookupOutcomes = newLookupOutcomes(mp.Meter("service-b"))
+	tb.Cleanup(func() {
+		lookupOutcomes = prev
+		mp.Shutdown(context.Background())
+	})
+	return reader
+}
+
+// Valor do contador weather.lookup.outcomes por atributo outcome
+func outcomeCounts(tb testing.TB, reader *sdkmetric.ManualReader) map[string]int64 {
+	tb.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		tb.Fatal(err)
+	}
+	counts := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if m.Name != "weather.lookup.outcomes" || !ok {
+				continue
+			}
+			for _, dp := range sum.DataPoints {
+				outcome, _ := dp.Attributes.Value(attribute.Key("outcome"))
+				counts[outcome.AsString()] += dp.Value
+			}
+		}
+	}
+	return counts
+}
+
+// Cada caminho do handler incrementa o contador com a sua classe de resultado, a mesma
+// do atributo lookup.outcome do span
+func TestWeatherHandlerOutcomeCounter(t *testing.T) {
+	withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "99999999"):
+			w.Write([]byte(`{"erro": true}`))
+		case strings.Contains(r.URL.Path, "20040020"):
+			json.NewEncoder(w).Encode(CEP{Cep: "20040-020", Localidade: "Rio de Janeiro", Uf: "RJ"})
+		default:
+			mockViaCEP().ServeHTTP(w, r)
+		}
+	}), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Query().Get("q"), "Rio de Janeiro") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		mockWeatherAPI(20).ServeHTTP(w, r)
+	}))
+	sr := withSpanRecorder(t)
+
+	tests := []struct {
+		name    string
+		path    string
+		outcome string
+	}{
+		{"sucesso", "/01001000", outcomeSuccess},
+		{"formato inválido", "/123", outcomeInvalidFormat},
+		{"não encontrado", "/99999999", outcomeNotFound},
+		{"falha no upstream", "/20040020", outcomeUpstreamError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := withOutcomeReader(t)
+			sr.Reset()
+			newWeatherRouter().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			counts := outcomeCounts(t, reader)
+			if len(counts) != 1 || counts[tt.outcome] != 1 {
+				t.Errorf("contador = %v, want {%s: 1}", counts, tt.outcome)
+			}
+			if got := spanAttr(endedSpan(t, sr, "weather_handler"), "lookup.outcome").AsString(); got != tt.outcome {
+				t.Errorf("lookup.outcome = %q, want %q", got, tt.outcome)
+			}
+		})
+	}
+}
+
+// Com METRICS_DISABLED ou TRACE_EXPORTER=file nenhuma conexão é aberta com o collector,
+// mesmo ao forçar a exportação
+func TestInitMeterSkipsExporter(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	var accepts atomic.Int32
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepts.Add(1)
+			conn.Close()
+		}
+	}()
+
+	tests := []struct {
+		name     string
+		disabled bool
+		exporter string
+		wantConn bool
+	}{
+		{"METRICS_DISABLED", true, telemetry.ExporterOTLP, false},
+		{"TRACE_EXPORTER=file", false, telemetry.ExporterFile, false},
+		{"OTLP", false, telemetry.ExporterOTLP, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accepts.Store(0)
+			var cfg Config
+			cfg.MetricsDisabled = tt.disabled
+			cfg.Tracing.Exporter = tt.exporter
+			cfg.Tracing.OTLPEndpoint = l.Addr().String()
+
+			mp, err := initMeter(cfg, resource.Empty())
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			mp.Meter("test").Int64Counter("test.counter")
+			mp.ForceFlush(ctx)
+			mp.Shutdown(ctx)
+
+			if got := accepts.Load() > 0; got != tt.wantConn {
+				t.Errorf("conexão com o collector = %v, want %v", got, tt.wantConn)
+			}
+		})
+	}
+}
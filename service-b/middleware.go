@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Limita o número de requisições simultâneas (load shedding). Quando a capacidade
+// está esgotada, responde 503 com Retry-After em vez de degradar todas as requisições.
+// Rotas críticas (CRITICAL_ROUTES, ex.: /admin/errors) nunca são descartadas nem ocupam
+// a capacidade do tráfego de usuários
+func maxInFlightMiddleware(limit int, critical []string) mux.MiddlewareFunc {
+	sem := make(chan struct{}, limit)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requestPriority(r, critical) == priorityCritical {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+				next.ServeHTTP(w, r)
+			default:
+				w.Header().Set("Retry-After", "1")
+				writeError(w, trace.SpanFromContext(r.Context()), http.StatusServiceUnavailable, "server overloaded")
+			}
+		})
+	}
+}
+
+// Classes de prioridade no load shedding
+const (
+	priorityCritical = "critical"
+	priorityUser     = "user"
+)
+
+// Prioridade pela rota registrada no mux (template, ex.: /admin/config). Os health
+// checks nem chegam aqui: healthCheckMiddleware os responde antes do roteador
+func requestPriority(r *http.Request, critical []string) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return priorityUser
+	}
+	tpl, err := route.GetPathTemplate()
+	if err == nil && slices.Contains(critical, tpl) {
+		return priorityCritical
+	}
+	return priorityUser
+}
+
+// Header com o tempo restante do prazo do chamador, em ms (enviado pelo Serviço A)
+const deadlineHeader = "X-Request-Deadline"
+
+// Aplica ao contexto da requisição o prazo informado em X-Request-Deadline, para que
+// as chamadas aos upstreams (e as retentativas) não ultrapassem o prazo do chamador.
+// Valores inválidos são ignorados
+func deadlineMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ms, err := strconv.ParseInt(r.Header.Get(deadlineHeader), 10, 64)
+		if err != nil || ms < 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.Int64("request.deadline_ms", ms))
+		ctx, cancel := context.WithTimeout(r.Context(), time.Duration(ms)*time.Millisecond)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Aplica o prazo configurado para a rota (ROUTE_TIMEOUTS), pelo template registrado no
+// mux (ex.: /{cep}, /batch). Rotas sem prazo configurado seguem sem limite próprio
+func routeTimeoutMiddleware(timeouts map[string]time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			tpl, err := route.GetPathTemplate()
+			timeout, ok := timeouts[tpl]
+			if err != nil || !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.Int64("request.route_timeout_ms", timeout.Milliseconds()))
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Responde os health checks (/health, /livez, /readyz) antes do roteador, sem passar
+// pelo tracing e pelos demais middlewares, para que probes não gerem spans nem sejam limitadas
+func healthCheckMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			switch r.URL.Path {
+			case "/health", "/livez":
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+				return
+			case "/readyz":
+				readyzHandler(w, r)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Registra no span do handler o tamanho declarado da requisição e os bytes escritos na
+// resposta, para análise de banda (batch, modo verbose). Fica depois do otelmux, então a
+// resposta é contada antes da compressão
+func payloadSizeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// httpsnoop preserva as interfaces do ResponseWriter (ex.: Flusher do SSE)
+		m := httpsnoop.CaptureMetrics(next, w, r)
+		trace.SpanFromContext(r.Context()).SetAttributes(
+			attribute.Int64("http.request_content_length", r.ContentLength),
+			attribute.Int64("http.response_size", m.Written),
+		)
+	})
+}
+
+// Responde 414 para URIs (caminho e query) acima de limit bytes (MAX_URI_LENGTH), antes
+// do roteamento e para qualquer rota. Complementa a validação do CEP: um caminho absurdo
+// não chega a ser casado nem vai para o span. limit 0 desabilita
+func maxURILengthMiddleware(limit int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(r.RequestURI) > limit {
+				span := trace.SpanFromContext(r.Context())
+				span.SetAttributes(attribute.Int("http.request_uri_length", len(r.RequestURI)))
+				w.Header().Set("Content-Type", "application/json")
+				writeError(w, span, http.StatusRequestURITooLong, "uri too long")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Encerramento em andamento: o /readyz responde 503 durante o SHUTDOWN_DELAY
+var shuttingDown atomic.Bool
+
+// Status do /readyz com o breaker da WeatherAPI aberto: 200 (só sinaliza degraded) ou 503
+var readyzDegradedStatus = http.StatusOK
+
+// Readiness conforme as verificações de READINESS_CHECKS (por padrão, só o breaker da
+// WeatherAPI); ver readinessStatus. O resultado de cada uma vai em checks
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if shuttingDown.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]any{"status": "shutting_down"})
+		return
+	}
+
+	checks := runReadinessChecks(r.Context())
+	status, code, degraded := readinessStatus(checks)
+
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":          status,
+		"degraded":        degraded,
+		"weather_breaker": weatherBreaker.State(),
+		"checks":          checks,
+	})
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMaxURILengthMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		uri      string
+		wantCode int
+	}{
+		{"desabilitado", 0, "/" + strings.Repeat("1", 100), http.StatusOK},
+		{"dentro do limite", 20, "/01001000", http.StatusOK},
+		{"no limite", 9, "/01001000", http.StatusOK},
+		{"caminho longo", 20, "/" + strings.Repeat("1", 20), http.StatusRequestURITooLong},
+		{"query conta", 20, "/01001000?fields=temp_C", http.StatusRequestURITooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true })
+
+			rec := httptest.NewRecorder()
+			maxURILengthMiddleware(tt.limit)(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.uri, nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if called != (tt.wantCode == http.StatusOK) {
+				t.Errorf("handler chamado = %v", called)
+			}
+		})
+	}
+}
+
+// No handler completo, uma URI acima de MAX_URI_LENGTH responde 414 em qualquer rota,
+// inclusive as que não existem, sem chegar aos upstreams
+func TestNewHandlerMaxURILength(t *testing.T) {
+	var calls atomic.Int32
+	withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		mockViaCEP().ServeHTTP(w, r)
+	}), mockWeatherAPI(22))
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.MaxURILength = 64
+	handler := newHandler(cfg)
+	long := strings.Repeat("x", 64)
+
+	tests := []struct {
+		name     string
+		method   string
+		uri      string
+		wantCode int
+	}{
+		{"/{cep} curto", http.MethodGet, "/01001000", http.StatusOK},
+		{"/{cep} com query longa", http.MethodGet, "/01001000?fields=" + long, http.StatusRequestURITooLong},
+		{"/health", http.MethodGet, "/health?x=" + long, http.StatusRequestURITooLong},
+		{"/batch", http.MethodPost, "/batch?x=" + long, http.StatusRequestURITooLong},
+		{"rota inexistente", http.MethodGet, "/a/" + long, http.StatusRequestURITooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls.Store(0)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.uri, strings.NewReader(`["01001000"]`)))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantCode != http.StatusRequestURITooLong {
+				return
+			}
+			if n := calls.Load(); n != 0 {
+				t.Errorf("chamadas ao ViaCEP = %d, want 0", n)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["message"] != "uri too long" {
+				t.Errorf("corpo = %s, want mensagem uri too long", rec.Body)
+			}
+		})
+	}
+}
+
+// Com a capacidade tomada, a requisição seguinte é descartada com 503 e Retry-After; ao
+// liberar uma vaga, o serviço volta a atender
+func TestMaxInFlightMiddlewareShedsAndRecovers(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := maxInFlightMiddleware(2, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/lenta" {
+			started <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	done := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/lenta", nil))
+			done <- rec.Code
+		}()
+		<-started
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/rapida", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("com a capacidade esgotada: status = %d, want 503", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("503 sem Retry-After")
+	}
+
+	release <- struct{}{}
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("requisição em andamento: status = %d, want 200", code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/rapida", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("após liberar uma vaga: status = %d, want 200", rec.Code)
+	}
+
+	close(release)
+	<-done
+}
+
+// Probes são respondidas antes do roteador: não geram spans nem passam pelo limite de
+// requisições simultâneas, mesmo com ele esgotado
+func TestHealthCheckMiddlewareBypassesRouter(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer tp.Shutdown(context.Background())
+
+	r := mux.NewRouter()
+	r.Use(otelmux.Middleware("service-b", otelmux.WithTracerProvider(tp)))
+	r.Use(maxInFlightMiddleware(1, nil))
+	release := make(chan struct{})
+	started := make(chan struct{})
+	r.HandleFunc("/{cep}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["cep"] == "lenta" {
+			close(started)
+			<-release
+		}
+	})
+	handler := healthCheckMiddleware(r)
+
+	// Ocupa a única vaga do limite
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/lenta", nil))
+		close(done)
+	}()
+	<-started
+
+	for _, path := range []string{"/health", "/livez", "/readyz"} {
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+			if rec.Code != http.StatusOK {
+				t.Errorf("%s %s: status = %d, want 200", method, path, rec.Code)
+			}
+		}
+	}
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Errorf("probes geraram spans: %v", spanNames(spans))
+	}
+
+	close(release)
+	<-done
+
+	// As demais rotas seguem instrumentadas
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/01001000", nil))
+	if spans := exporter.GetSpans(); len(spans) != 2 {
+		t.Errorf("spans = %v, want os das duas requisições ao roteador", spanNames(spans))
+	}
+}
+
+// Com o tráfego de usuários sendo descartado, as rotas críticas (admin) e os health
+// checks continuam respondendo; as críticas também não ocupam a capacidade
+func TestMaxInFlightMiddlewareCriticalRoutes(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(maxInFlightMiddleware(1, []string{"/admin/errors", "/admin/config"}))
+	release := make(chan struct{})
+	started := make(chan struct{})
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	r.HandleFunc("/admin/errors", ok)
+	r.HandleFunc("/admin/config", ok)
+	r.HandleFunc("/{cep}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["cep"] == "lenta" {
+			close(started)
+			<-release
+		}
+	})
+	handler := healthCheckMiddleware(r)
+
+	// Ocupa a única vaga do tráfego de usuários
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/lenta", nil))
+		close(done)
+	}()
+	<-started
+	defer func() {
+		close(release)
+		<-done
+	}()
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/01001000", http.StatusServiceUnavailable},
+		{"/admin/errors", http.StatusOK},
+		{"/admin/config", http.StatusOK},
+		{"/health", http.StatusOK},
+		{"/readyz", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRequestPriority(t *testing.T) {
+	critical := []string{"/admin/config"}
+	r := mux.NewRouter()
+	var got string
+	record := func(w http.ResponseWriter, r *http.Request) { got = requestPriority(r, critical) }
+	r.HandleFunc("/admin/config", record)
+	r.HandleFunc("/{cep}", record)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/admin/config", priorityCritical},
+		{"/01001000", priorityUser},
+		// O template é comparado, não o caminho: um CEP com o nome da rota não é crítico
+		{"/admin", priorityUser},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got = ""
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if got != tt.want {
+				t.Errorf("requestPriority(%s) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	// Fora do roteador não há rota: vale a prioridade dos usuários
+	if got := requestPriority(httptest.NewRequest(http.MethodGet, "/admin/config", nil), critical); got != priorityUser {
+		t.Errorf("requestPriority sem rota = %q, want %q", got, priorityUser)
+	}
+}
+
+// X-Request-Deadline válido vira o prazo do contexto; ausente ou inválido, o contexto
+// segue sem prazo
+func TestDeadlineMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration // 0: sem prazo
+	}{
+		{"ausente", "", 0},
+		{"inválido", "logo", 0},
+		{"negativo", "-5", 0},
+		{"prazo", "1500", 1500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deadline time.Time
+			var ok bool
+			handler := deadlineMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				deadline, ok = r.Context().Deadline()
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/01001000", nil)
+			if tt.header != "" {
+				req.Header.Set(deadlineHeader, tt.header)
+			}
+			start := time.Now()
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if tt.want == 0 {
+				if ok {
+					t.Errorf("prazo = %v, want nenhum", deadline)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("contexto sem prazo")
+			}
+			if got := deadline.Sub(start); got < tt.want-100*time.Millisecond || got > tt.want+100*time.Millisecond {
+				t.Errorf("prazo em %s, want ~%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// Com um prazo propagado curto e a WeatherAPI lenta, o Serviço B desiste dentro do
+// prazo do chamador com 504, sem esperar a resposta do upstream
+func TestWeatherHandlerPropagatedDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	withMockUpstreams(t, mockViaCEP(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/01001000", nil)
+	req.Header.Set(deadlineHeader, "100")
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	deadlineMiddleware(newWeatherRouter()).ServeHTTP(rec, req)
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("resposta após %s, prazo propagado era 100ms", elapsed)
+	}
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want 504; body = %s", rec.Code, rec.Body)
+	}
+}
+
+// Cada rota recebe o prazo configurado para o seu template; rotas fora do mapa seguem
+// sem prazo próprio
+func TestRouteTimeoutMiddleware(t *testing.T) {
+	timeouts := map[string]time.Duration{"/{cep}": 200 * time.Millisecond, "/batch": 2 * time.Second}
+
+	var deadline time.Time
+	var ok bool
+	record := func(w http.ResponseWriter, r *http.Request) { deadline, ok = r.Context().Deadline() }
+	r := mux.NewRouter()
+	r.Use(routeTimeoutMiddleware(timeouts))
+	r.HandleFunc("/batch", record).Methods("POST")
+	r.HandleFunc("/openapi.json", record).Methods("GET")
+	r.HandleFunc("/{cep}", record).Methods("GET")
+
+	tests := []struct {
+		method string
+		path   string
+		want   time.Duration // 0: sem prazo
+	}{
+		{http.MethodGet, "/01001000", 200 * time.Millisecond},
+		{http.MethodPost, "/batch", 2 * time.Second},
+		{http.MethodGet, "/openapi.json", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			ok = false
+			start := time.Now()
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+
+			if tt.want == 0 {
+				if ok {
+					t.Errorf("prazo = %v, want nenhum", deadline)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("contexto sem prazo")
+			}
+			if got := deadline.Sub(start); got < tt.want-50*time.Millisecond || got > tt.want+50*time.Millisecond {
+				t.Errorf("prazo em %s, want ~%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// Com ROUTE_TIMEOUTS para /{cep} e a WeatherAPI lenta, a consulta termina em 504 dentro
+// do prazo da rota
+func TestNewHandlerRouteTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	withMockUpstreams(t, mockViaCEP(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.RouteTimeouts = map[string]time.Duration{"/{cep}": 100 * time.Millisecond}
+
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	newHandler(cfg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("resposta após %s, prazo da rota era 100ms", elapsed)
+	}
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want 504; body = %s", rec.Code, rec.Body)
+	}
+}
+
+// O span do servidor registra o tamanho declarado da requisição e exatamente os bytes do
+// corpo da resposta, também no batch e no modo verbose
+func TestPayloadSizeMiddleware(t *testing.T) {
+	withBatchConfig(t)
+	withMockUpstreams(t, mockViaCEP(), mockWeatherAPI(23.5))
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer tp.Shutdown(context.Background())
+
+	r := mux.NewRouter()
+	r.Use(otelmux.Middleware("service-b", otelmux.WithTracerProvider(tp), otelmux.WithSpanNameFormatter(routeSpanName)))
+	r.Use(payloadSizeMiddleware)
+	r.HandleFunc("/batch", batchHandler).Methods("POST")
+	r.HandleFunc("/{cep}", weatherHandler).Methods("GET", "HEAD")
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+	}{
+		{"/{cep}", http.MethodGet, "/01001000", ""},
+		{"/{cep} verbose", http.MethodGet, "/01001000?verbose=true", ""},
+		{"/{cep} inválido", http.MethodGet, "/123", ""},
+		{"/batch", http.MethodPost, "/batch", `["01001000","01310100","123"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter.Reset()
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			if rec.Body.Len() == 0 {
+				t.Fatalf("resposta sem corpo, status %d", rec.Code)
+			}
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("spans = %v, want só o do servidor", spanNames(spans))
+			}
+			attrs := make(map[attribute.Key]attribute.Value)
+			for _, kv := range spans[0].Attributes {
+				attrs[kv.Key] = kv.Value
+			}
+			if got := attrs["http.response_size"].AsInt64(); got != int64(rec.Body.Len()) {
+				t.Errorf("http.response_size = %d, want %d (tamanho do corpo)", got, rec.Body.Len())
+			}
+			if got := attrs["http.request_content_length"].AsInt64(); got != int64(len(tt.body)) {
+				t.Errorf("http.request_content_length = %d, want %d", got, len(tt.body))
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Contrato OpenAPI 3 do Serviço B, mantido à mão junto das rotas em run().
+// Ao mudar uma rota, parâmetro ou resposta, atualize também este documento
+var openAPISpec = map[string]any{
+	"openapi": "3.0.3",
+	"info": map[string]any{
+		"title":       "CEP Weather API",
+		"version":     "1.0.0",
+		"description": "Serviço B - temperatura atual da cidade de um CEP",
+	},
+	"paths": map[string]any{
+		"/{cep}": map[string]any{
+			"get": map[string]any{
+				"summary": "Temperatura atual da cidade do CEP",
+				"parameters": []any{
+					pathParam("cep", "CEP com 8 dígitos, com ou sem traço", "01001000"),
+					queryParam("country", "País do código postal (default: BR)", "string"),
+					queryParam("verbose", "Inclui localização e condição atual", "boolean"),
+					queryParam("aqi", "Com verbose=true, inclui a qualidade do ar", "boolean"),
+					queryParam("fields", "Campos da resposta separados por vírgula, ex.: city,temp_C", "string"),
+					queryParam("int", "Temperaturas arredondadas para inteiros; não combina com fields/verbose", "boolean"),
+					queryParam("include", "Dados do CEP acrescentados à resposta, separados por vírgula: ddd, ibge, siafi; não combina com fields/int/single", "string"),
+					queryParam("generated_at", "Inclui generated_at (RFC3339) com o horário em que a resposta foi montada; sempre presente com GENERATED_AT=always", "boolean"),
+					queryParam("primary", "Unidade preferida (C, F ou K), informada em primary_unit sem remover as demais; não combina com single", "string"),
+					queryParam("single", "Uma única temperatura (temp e unit) na unidade de DEFAULT_TEMP_UNIT; não combina com fields/int/verbose", "boolean"),
+				},
+				"responses": map[string]any{
+					"200": jsonResponse("Temperaturas da cidade", "TemperatureResponse"),
+					"400": errorResponse("Parâmetro fields inválido ou malformed zipcode (caracteres de controle, UTF-8 inválido)"),
+					"404": errorResponse("can not find zipcode"),
+					"422": errorResponse("invalid zipcode"),
+					"429": errorResponse("too many concurrent lookups for this zipcode (MAX_INFLIGHT_PER_CEP)"),
+					"500": errorResponse("Falha na WeatherAPI"),
+					"502": errorResponse("Condição em WEATHER_SOFT_FAIL_CODES (weather data unavailable) ou temperatura fora do JSON (invalid weather data)"),
+					"503": errorResponse("server overloaded ou zipcode service unavailable (rate limit do ViaCEP)"),
+				},
+			},
+		},
+		"/batch": map[string]any{
+			"post": map[string]any{
+				"summary": "Temperatura para vários CEPs",
+				"parameters": []any{
+					queryParam("country", "País dos códigos postais (default: BR)", "string"),
+				},
+				"requestBody": map[string]any{
+					"required": true,
+					"content": map[string]any{
+						"application/json": map[string]any{
+							"schema": map[string]any{
+								"type":  "array",
+								"items": map[string]any{"type": "string"},
+							},
+						},
+					},
+				},
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "Resultado de cada CEP, na ordem do pedido",
+						"content": map[string]any{
+							"application/json": map[string]any{
+								"schema": map[string]any{
+									"type":  "array",
+									"items": schemaRef("BatchItemResult"),
+								},
+							},
+						},
+					},
+					"204": map[string]any{"description": "Batch vazio (BATCH_EMPTY_STATUS=204)"},
+					"400": errorResponse("Corpo inválido, batch vazio ou grande demais"),
+					"413": errorResponse("Corpo acima de BATCH_MAX_BODY_BYTES"),
+				},
+			},
+		},
+		"/{cep}/temp": map[string]any{
+			"get": map[string]any{
+				"summary": "Só a temperatura, em texto puro, para clientes de shell e IoT",
+				"parameters": []any{
+					pathParam("cep", "CEP com 8 dígitos, com ou sem traço", "01001000"),
+					queryParam("unit", "Unidade da temperatura: C, F ou K (default: DEFAULT_TEMP_UNIT)", "string"),
+					queryParam("country", "País do código postal (default: BR)", "string"),
+				},
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "Temperatura na unidade pedida, ex.: 23.5",
+						"content": map[string]any{
+							"text/plain": map[string]any{"schema": map[string]any{"type": "string"}},
+						},
+					},
+					"400": errorResponse("malformed zipcode (caracteres de controle, UTF-8 inválido)"),
+					"404": errorResponse("can not find zipcode"),
+					"422": errorResponse("invalid zipcode ou invalid unit"),
+					"429": errorResponse("too many concurrent lookups for this zipcode (MAX_INFLIGHT_PER_CEP)"),
+					"500": errorResponse("Falha na WeatherAPI"),
+				},
+			},
+		},
+		"/search": map[string]any{
+			"get": map[string]any{
+				"summary": "CEPs candidatos para um endereço (busca reversa do ViaCEP)",
+				"parameters": []any{
+					queryParam("uf", "UF, ex.: SP", "string"),
+					queryParam("city", "Cidade, com ao menos 3 caracteres", "string"),
+					queryParam("street", "Logradouro ou parte dele, com ao menos 3 caracteres", "string"),
+				},
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "CEPs encontrados (lista vazia se nenhum)",
+						"content": map[string]any{
+							"application/json": map[string]any{
+								"schema": map[string]any{
+									"type":  "array",
+									"items": schemaRef("CEP"),
+								},
+							},
+						},
+					},
+					"400": errorResponse("UF inválida ou cidade/logradouro curtos demais"),
+					"500": errorResponse("Falha no ViaCEP"),
+					"503": errorResponse("zipcode service unavailable (rate limit do ViaCEP)"),
+				},
+			},
+		},
+		"/health": healthPath(),
+		"/livez":  healthPath(),
+		"/readyz": healthPath(),
+	},
+	"components": map[string]any{
+		"schemas": map[string]any{
+			"TemperatureResponse": map[string]any{
+				"type":     "object",
+				"required": []string{"city", "temp_C", "temp_F", "temp_K"},
+				"properties": map[string]any{
+					"city":           map[string]any{"type": "string"},
+					"region":         map[string]any{"type": "string", "description": "Estado, segundo a WeatherAPI; omitido se vazio"},
+					"uf":             map[string]any{"type": "string", "description": "UF do CEP; omitida se vazia"},
+					"temp_C":         map[string]any{"type": "number"},
+					"temp_F":         map[string]any{"type": "number"},
+					"temp_K":         map[string]any{"type": "number"},
+					"low_confidence": map[string]any{"type": "boolean", "description": "Localidade da WeatherAPI em país inesperado; omitido se false"},
+					"source":         map[string]any{"type": "string", "enum": []string{"historical_average"}, "description": "Origem da temperatura quando não é uma leitura atual (HISTORICAL_FALLBACK); omitido para leituras da WeatherAPI"},
+					"ddd":            map[string]any{"type": "string", "description": "Só com ?include=ddd"},
+					"ibge":           map[string]any{"type": "string", "description": "Código IBGE do município; só com ?include=ibge"},
+					"siafi":          map[string]any{"type": "string", "description": "Só com ?include=siafi"},
+					"generated_at":   map[string]any{"type": "string", "format": "date-time", "description": "Horário em que a resposta foi montada; só com ?generated_at=true ou GENERATED_AT=always"},
+					"primary_unit":   map[string]any{"type": "string", "enum": []string{"C", "F", "K"}, "description": "Unidade preferida pedida em ?primary=; omitida se não informada"},
+				},
+			},
+			"CEP": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"cep":        map[string]any{"type": "string"},
+					"logradouro": map[string]any{"type": "string"},
+					"bairro":     map[string]any{"type": "string"},
+					"localidade": map[string]any{"type": "string"},
+					"uf":         map[string]any{"type": "string"},
+				},
+			},
+			"ErrorResponse": map[string]any{
+				"type":     "object",
+				"required": []string{"message"},
+				"properties": map[string]any{
+					"message":  map[string]any{"type": "string"},
+					"code":     map[string]any{"type": "string", "description": "Código do erro, ex.: INVALID_FORMAT; só com VERBOSE_ERRORS=true"},
+					"details":  map[string]any{"type": "string", "description": "Detalhe do erro; só com VERBOSE_ERRORS=true"},
+					"trace_id": map[string]any{"type": "string", "description": "Trace da requisição, para correlação"},
+				},
+			},
+			"BatchItemResult": map[string]any{
+				"type":     "object",
+				"required": []string{"cep", "status"},
+				"properties": map[string]any{
+					"cep":    map[string]any{"type": "string"},
+					"status": map[string]any{"type": "integer"},
+					"result": schemaRef("TemperatureResponse"),
+					"error":  map[string]any{"type": "string"},
+				},
+			},
+		},
+	},
+}
+
+func pathParam(name, description, example string) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          "path",
+		"required":    true,
+		"description": description,
+		"schema":      map[string]any{"type": "string", "example": example},
+	}
+}
+
+func queryParam(name, description, typ string) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          "query",
+		"description": description,
+		"schema":      map[string]any{"type": typ},
+	}
+}
+
+func schemaRef(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func jsonResponse(description, schema string) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": schemaRef(schema)},
+		},
+	}
+}
+
+func errorResponse(description string) map[string]any {
+	return jsonResponse(description, "ErrorResponse")
+}
+
+func healthPath() map[string]any {
+	return map[string]any{
+		"get": map[string]any{
+			"responses": map[string]any{
+				"200": map[string]any{"description": `{"status":"ok"}`},
+			},
+		},
+	}
+}
+
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(openAPISpec)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Busca /openapi.json e decodifica o documento servido
+func fetchOpenAPISpec(t *testing.T) map[string]any {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	openAPIHandler(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var spec map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&spec); err != nil {
+		t.Fatalf("JSON inválido: %v", err)
+	}
+	return spec
+}
+
+// Todos os valores de "$ref" do documento
+func collectRefs(v any, refs *[]string) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if ref, ok := child.(string); ok && k == "$ref" {
+				*refs = append(*refs, ref)
+			}
+			collectRefs(child, refs)
+		}
+	case []any:
+		for _, child := range v {
+			collectRefs(child, refs)
+		}
+	}
+}
+
+// Nomes JSON dos campos de um struct, pelas tags
+func jsonFieldNames(typ reflect.Type) []string {
+	var names []string
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	spec := fetchOpenAPISpec(t)
+
+	if v, _ := spec["openapi"].(string); !regexp.MustCompile(`^3\.\d+\.\d+$`).MatchString(v) {
+		t.Errorf("openapi = %q, want 3.x.y", v)
+	}
+	info, _ := spec["info"].(map[string]any)
+	if info["title"] == "" || info["title"] == nil || info["version"] == "" || info["version"] == nil {
+		t.Errorf("info sem title ou version: %v", info)
+	}
+
+	paths, _ := spec["paths"].(map[string]any)
+	cepPath, _ := paths["/{cep}"].(map[string]any)
+	if _, ok := cepPath["get"]; !ok {
+		t.Fatal("GET /{cep} ausente")
+	}
+
+	// Toda operação tem respostas com status de 3 dígitos, e todo parâmetro do path
+	// está declarado como parâmetro obrigatório in=path
+	pathVar := regexp.MustCompile(`\{(\w+)\}`)
+	for path, item := range paths {
+		for method, op := range item.(map[string]any) {
+			op := op.(map[string]any)
+			responses, _ := op["responses"].(map[string]any)
+			if len(responses) == 0 {
+				t.Errorf("%s %s sem respostas", method, path)
+			}
+			for status := range responses {
+				if !regexp.MustCompile(`^[1-5]\d\d$`).MatchString(status) {
+					t.Errorf("%s %s: status %q inválido", method, path, status)
+				}
+			}
+
+			for _, m := range pathVar.FindAllStringSubmatch(path, -1) {
+				declared := false
+				params, _ := op["parameters"].([]any)
+				for _, p := range params {
+					p := p.(map[string]any)
+					if p["name"] == m[1] && p["in"] == "path" && p["required"] == true {
+						declared = true
+					}
+				}
+				if !declared {
+					t.Errorf("%s %s: parâmetro de path %q não declarado", method, path, m[1])
+				}
+			}
+		}
+	}
+
+	// Toda referência aponta para um schema existente
+	schemas := spec["components"].(map[string]any)["schemas"].(map[string]any)
+	var refs []string
+	collectRefs(spec, &refs)
+	for _, ref := range refs {
+		name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+		if _, exists := schemas[name]; !ok || !exists {
+			t.Errorf("$ref %q não resolve", ref)
+		}
+	}
+
+	// Os schemas das respostas acompanham os campos dos tipos
+	for name, typ := range map[string]reflect.Type{
+		"TemperatureResponse": reflect.TypeOf(TemperatureResponse{}),
+		"ErrorResponse":       reflect.TypeOf(ErrorResponse{}),
+	} {
+		var props []string
+		for prop := range schemas[name].(map[string]any)["properties"].(map[string]any) {
+			props = append(props, prop)
+		}
+		slices.Sort(props)
+		if want := jsonFieldNames(typ); !slices.Equal(props, want) {
+			t.Errorf("schema %s = %v, want %v", name, props, want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Métodos dos health checks, atendidos por healthCheckMiddleware antes do roteador
+var healthCheckMethods = map[string][]string{
+	"/health": {http.MethodGet, http.MethodHead},
+	"/livez":  {http.MethodGet, http.MethodHead},
+	"/readyz": {http.MethodGet, http.MethodHead},
+}
+
+// Responde OPTIONS em qualquer rota conhecida com 204 e o header Allow, derivado das
+// rotas registradas no router (mais os health checks). Caminhos sem rota seguem para
+// o handler e respondem 404 normalmente
+func optionsMiddleware(router *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			methods := allowedMethods(router, r)
+			if len(methods) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Allow", strings.Join(methods, ", "))
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// Métodos aceitos no caminho da requisição, incluindo OPTIONS; vazio se nenhuma rota
+// casa com o caminho
+func allowedMethods(router *mux.Router, r *http.Request) []string {
+	allowed := make(map[string]bool)
+	for _, m := range healthCheckMethods[r.URL.Path] {
+		allowed[m] = true
+	}
+
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, m := range methods {
+			probe := *r
+			probe.Method = m
+			var match mux.RouteMatch
+			if route.Match(&probe, &match) {
+				allowed[m] = true
+			}
+		}
+		return nil
+	})
+
+	if len(allowed) == 0 {
+		return nil
+	}
+	allowed[http.MethodOptions] = true
+
+	methods := make([]string, 0, len(allowed))
+	for m := range allowed {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	return methods
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// OPTIONS em qualquer rota conhecida responde 204 com os métodos registrados em Allow;
+// caminhos sem rota seguem para o 404 normal
+func TestNewHandlerOptions(t *testing.T) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := newHandler(cfg)
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantAllow  string
+	}{
+		{"/01001000", http.StatusNoContent, "GET, HEAD, OPTIONS"},
+		{"/", http.StatusNoContent, "GET, OPTIONS"},
+		{"/health", http.StatusNoContent, "GET, HEAD, OPTIONS"},
+		{"/readyz", http.StatusNoContent, "GET, HEAD, OPTIONS"},
+		// GET /batch casa com /{cep} (e responde 422), por isso aparece em Allow
+		{"/batch", http.StatusNoContent, "GET, HEAD, OPTIONS, POST"},
+		{"/01001000/temp", http.StatusNoContent, "GET, OPTIONS"},
+		{"/a/b/c", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+			if tt.wantStatus == http.StatusNoContent && rec.Body.Len() != 0 {
+				t.Errorf("corpo = %q, want vazio", rec.Body)
+			}
+		})
+	}
+}
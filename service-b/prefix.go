@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/validation"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CEP inexistente segundo o provedor (ViaCEP erro=true ou sem localidade)
+var errCEPNotFound = errors.New("CEP não encontrado")
+
+// Com CEP_PREFIX_FALLBACK=true, um CEP inexistente é trocado pela localidade mais próxima
+// encontrada pelo prefixo
+var cepPrefixFallback bool
+
+// Resolvers que sabem procurar endereços candidatos pelo prefixo do código postal
+type prefixResolver interface {
+	ResolvePrefix(ctx context.Context, code string) ([]CEP, error)
+}
+
+// Municípios de CEP único usam o CEP geral NNNNN-000, e municípios vizinhos do mesmo
+// sub-setor diferem no 5º dígito: os dez CEPs gerais com os 4 primeiros dígitos do
+// código cobrem as localidades candidatas. Localidades repetidas aparecem uma vez
+func (viaCEPResolver) ResolvePrefix(ctx context.Context, code string) ([]CEP, error) {
+	code = validation.NormalizeCEP(code)
+	results := make([]*CEP, 10)
+	errs := make([]error, 10)
+
+	var wg sync.WaitGroup
+	for d := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[d], errs[d] = getCEPInfo(ctx, fmt.Sprintf("%s%d000", code[:4], d))
+		}()
+	}
+	wg.Wait()
+
+	var candidates []CEP
+	seen := make(map[string]bool)
+	for d, cep := range results {
+		if errs[d] != nil {
+			if !errors.Is(errs[d], errCEPNotFound) {
+				return nil, errs[d]
+			}
+			continue
+		}
+		key := weatherCacheKey(cep.Localidade) + "|" + cep.Uf
+		if !seen[key] {
+			seen[key] = true
+			candidates = append(candidates, *cep)
+		}
+	}
+	return candidates, nil
+}
+
+// Procura pelo prefixo as localidades candidatas para um CEP inexistente, da mais próxima
+// (menor distância numérica até o CEP pedido) para a mais distante. Vazio se desabilitado,
+// se o resolver não souber buscar por prefixo ou se nada for encontrado
+func resolveByPrefix(ctx context.Context, resolver postalCodeResolver, code string) []CEP {
+	pr, ok := resolver.(prefixResolver)
+	if !cepPrefixFallback || !ok {
+		return nil
+	}
+	span := trace.SpanFromContext(ctx)
+
+	candidates, err := pr.ResolvePrefix(ctx, code)
+	if err != nil {
+		logf(ctx, "Erro na busca por prefixo do CEP %s: %v", code, err)
+		span.RecordError(err)
+		return nil
+	}
+	span.SetAttributes(attribute.Int("cep.prefix_candidates", len(candidates)))
+
+	target := cepNumber(code)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return abs(cepNumber(candidates[i].Cep)-target) < abs(cepNumber(candidates[j].Cep)-target)
+	})
+	return candidates
+}
+
+func cepNumber(cep string) int {
+	n, _ := strconv.Atoi(validation.NormalizeCEP(cep))
+	return n
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// ViaCEP simulado com os CEPs gerais de três municípios do sub-setor 0123; qualquer
+// outro CEP não existe
+func mockViaCEPPrefix(calls *atomic.Int32) http.Handler {
+	ceps := map[string]CEP{
+		"01230000": {Cep: "01230-000", Localidade: "Cidade A", Uf: "SP"},
+		"01235000": {Cep: "01235-000", Localidade: "Cidade B", Uf: "SP"},
+		"01237000": {Cep: "01237-000", Localidade: "Cidade C", Uf: "SP"},
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		cep, ok := ceps[strings.Split(strings.Trim(r.URL.Path, "/"), "/")[1]]
+		if !ok {
+			w.Write([]byte(`{"erro": true}`))
+			return
+		}
+		json.NewEncoder(w).Encode(cep)
+	})
+}
+
+// WeatherAPI simulada que devolve a própria localidade consultada como location.name
+func mockWeatherAPIEcho() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data WeatherData
+		data.Location.Name = r.URL.Query().Get("q")
+		data.Location.Country = "Brazil"
+		data.Current.TempC = 20
+		json.NewEncoder(w).Encode(data)
+	})
+}
+
+func TestWeatherHandlerPrefixFallback(t *testing.T) {
+	defer func(b bool) { cepPrefixFallback = b }(cepPrefixFallback)
+
+	tests := []struct {
+		name             string
+		enabled          bool
+		path             string
+		wantStatus       int
+		wantCity         string
+		wantAlternatives []VerboseAlternative
+	}{
+		{"verbose com alternativas", true, "/01234567?verbose=true", http.StatusOK, "Cidade B", []VerboseAlternative{
+			{CEP: "01237-000", City: "Cidade C", UF: "SP"},
+			{CEP: "01230-000", City: "Cidade A", UF: "SP"},
+		}},
+		{"sem verbose só a melhor", true, "/01234567", http.StatusOK, "Cidade B", nil},
+		{"desabilitado", false, "/01234567", http.StatusNotFound, "", nil},
+		{"prefixo sem candidatos", true, "/09990123", http.StatusNotFound, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cepPrefixFallback = tt.enabled
+			var viacepCalls atomic.Int32
+			withMockUpstreams(t, mockViaCEPPrefix(&viacepCalls), mockWeatherAPIEcho())
+			sr := withSpanRecorder(t)
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if !tt.enabled && viacepCalls.Load() != 1 {
+				t.Errorf("chamadas ao ViaCEP = %d, want 1 sem a busca por prefixo", viacepCalls.Load())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				City          string               `json:"city"`
+				LowConfidence bool                 `json:"low_confidence"`
+				Alternatives  []VerboseAlternative `json:"alternatives"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.City != tt.wantCity || !body.LowConfidence {
+				t.Errorf("city = %q, low_confidence = %v, want %q com baixa confiança", body.City, body.LowConfidence, tt.wantCity)
+			}
+			if len(body.Alternatives) != len(tt.wantAlternatives) {
+				t.Fatalf("alternatives = %+v, want %+v", body.Alternatives, tt.wantAlternatives)
+			}
+			for i := range body.Alternatives {
+				if body.Alternatives[i] != tt.wantAlternatives[i] {
+					t.Errorf("alternatives[%d] = %+v, want %+v", i, body.Alternatives[i], tt.wantAlternatives[i])
+				}
+			}
+
+			span := endedSpan(t, sr, "weather_handler")
+			if got := spanAttr(span, "cep.prefix_match").AsString(); got != "01235-000" {
+				t.Errorf("cep.prefix_match = %q, want 01235-000", got)
+			}
+			if got := spanAttr(span, "cep.prefix_candidates").AsInt64(); got != 3 {
+				t.Errorf("cep.prefix_candidates = %d, want 3", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/validation"
+)
+
+// Lê os CEPs do arquivo de preload: array JSON de strings (.json) ou um CEP por linha
+// (CSV, usando a primeira coluna). Linhas que não são CEPs válidos, como o cabeçalho
+// do CSV, são ignoradas
+func readPreloadCEPs(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var raw []string
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.NewDecoder(f).Decode(&raw); err != nil {
+			return nil, fmt.Errorf("erro ao decodificar %s: %w", path, err)
+		}
+	} else {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			first, _, _ := strings.Cut(scanner.Text(), ",")
+			raw = append(raw, strings.Trim(strings.TrimSpace(first), `"`))
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	var ceps []string
+	for _, cep := range raw {
+		if cep = validation.NormalizeCEP(cep); validation.IsValidCEP(cep) {
+			ceps = append(ceps, cep)
+		}
+	}
+	return ceps, nil
+}
+
+// Aquece o cache de CEPs (e, com withWeather, o de clima) com os CEPs do arquivo
+// CACHE_PRELOAD_FILE, em segundo plano. Falhas não são fatais: o arquivo ausente ou
+// um CEP que não resolve só são registrados no log
+func preloadCaches(ctx context.Context, path string, withWeather bool) {
+	go func() {
+		ceps, err := readPreloadCEPs(path)
+		if err != nil {
+			log.Printf("Preload do cache ignorado: %v", err)
+			return
+		}
+
+		ctx, span := tracer.Start(ctx, "cache_preload")
+		defer span.End()
+
+		loaded := 0
+		for _, cep := range ceps {
+			if ctx.Err() != nil {
+				return
+			}
+			cepInfo, err := getCEPInfo(ctx, cep)
+			if err != nil {
+				log.Printf("Preload: CEP %s não resolvido: %v", cep, err)
+				continue
+			}
+			if withWeather {
+				if _, _, err := getWeatherForAddress(ctx, cepInfo, false); err != nil {
+					log.Printf("Preload: clima de %s não resolvido: %v", cepInfo.Localidade, err)
+				}
+			}
+			loaded++
+		}
+		log.Printf("Preload do cache concluído: %d de %d CEPs", loaded, len(ceps))
+	}()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadPreloadCEPs(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []string
+	}{
+		{"json", "ceps.json", `["01001-000", "20040020", "inválido"]`, []string{"01001000", "20040020"}},
+		{"csv com cabeçalho", "ceps.csv", "cep,cidade\n01001-000,São Paulo\n\"20040020\",Rio de Janeiro\n", []string{"01001000", "20040020"}},
+		{"um por linha", "ceps.txt", "01001000\n\n  20040-020  \n", []string{"01001000", "20040020"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readPreloadCEPs(path)
+			if err != nil {
+				t.Fatalf("readPreloadCEPs: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("CEPs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("arquivo ausente", func(t *testing.T) {
+		if _, err := readPreloadCEPs(filepath.Join(t.TempDir(), "nao-existe.json")); err == nil {
+			t.Error("readPreloadCEPs sem arquivo não devolveu erro")
+		}
+	})
+}
+
+// Os CEPs do arquivo de preload e o clima das localidades são servidos do cache: a
+// consulta depois do preload não chama o ViaCEP nem a WeatherAPI
+func TestPreloadCachesServesFromCache(t *testing.T) {
+	var viacepCalls, weatherCalls atomic.Int32
+	withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		viacepCalls.Add(1)
+		mockViaCEP().ServeHTTP(w, r)
+	}), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		weatherCalls.Add(1)
+		mockWeatherAPI(23.5).ServeHTTP(w, r)
+	}))
+	cepCache = newCache[CEP]("cep", time.Hour)
+	weatherCache = newCache[WeatherData]("weather", time.Hour)
+
+	path := filepath.Join(t.TempDir(), "ceps.json")
+	if err := os.WriteFile(path, []byte(`["01001000", "01310100"]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	preloadCaches(ctx, path, true)
+
+	// O preload roda em segundo plano: espera o último CEP e o clima chegarem ao cache
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, cepOK := cepCache.Get(ctx, "01310100")
+		_, weatherOK := weatherCache.Get(ctx, weatherCacheKey(mockCEP.Localidade))
+		if cepOK && weatherOK {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("preload não terminou: cep %v, clima %v", cepOK, weatherOK)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := viacepCalls.Load(); n != 2 {
+		t.Errorf("chamadas ao ViaCEP no preload = %d, want 2", n)
+	}
+
+	viacepCalls.Store(0)
+	weatherCalls.Store(0)
+	for _, cep := range []string{"01001000", "01310100"} {
+		rec := httptest.NewRecorder()
+		newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+cep, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET /%s = %d, want 200; body = %s", cep, rec.Code, rec.Body)
+		}
+	}
+	if v, w := viacepCalls.Load(), weatherCalls.Load(); v != 0 || w != 0 {
+		t.Errorf("chamadas depois do preload: ViaCEP %d, WeatherAPI %d, want 0", v, w)
+	}
+}
+
+// Arquivo ausente só é registrado no log: nada é consultado
+func TestPreloadCachesMissingFile(t *testing.T) {
+	var calls atomic.Int32
+	withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}), mockWeatherAPI(23.5))
+
+	preloadCaches(context.Background(), filepath.Join(t.TempDir(), "nao-existe.json"), true)
+	time.Sleep(50 * time.Millisecond)
+	if n := calls.Load(); n != 0 {
+		t.Errorf("chamadas ao ViaCEP = %d, want 0", n)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Dependências verificadas pelo /readyz (READINESS_CHECKS)
+const (
+	readinessViaCEP  = "viacep"  // ViaCEP responde (qualquer status abaixo de 500)
+	readinessWeather = "weather" // breaker da WeatherAPI fechado; não gasta cota da chave
+	readinessOTLP    = "otlp"    // collector OTLP aceita conexão TCP
+)
+
+var (
+	readinessChecks       = []string{readinessWeather}
+	readinessCheckTimeout = 2 * time.Second
+
+	// Por quanto tempo o resultado de viacep e otlp é reaproveitado (READINESS_CACHE_TTL),
+	// para que probes frequentes de várias réplicas não virem carga nas dependências
+	readinessCacheTTL = 5 * time.Second
+
+	// Endpoint OTLP (host:porta) verificado por otlp; vazio quando os traces vão para arquivo
+	readinessOTLPEndpoint string
+)
+
+// CEP consultado na verificação do ViaCEP
+const readinessViaCEPProbe = "01001000"
+
+// Cliente da sonda do ViaCEP: sem a instrumentação nem o limite de conexões por host do
+// httpClient, para que a sonda não gere spans nem dispute conexões com as consultas
+var readinessHTTPClient = &http.Client{}
+
+var readinessCheckFuncs = map[string]func(ctx context.Context) error{
+	readinessViaCEP:  cachedReadinessCheck(readinessViaCEP, checkViaCEP),
+	readinessWeather: checkWeatherBreaker,
+	readinessOTLP:    cachedReadinessCheck(readinessOTLP, checkOTLP),
+}
+
+// Sondas simultâneas da mesma dependência são agrupadas
+var readinessProbes singleflight.Group
+
+// Protege os resultados guardados por cachedReadinessCheck
+var readinessCacheMu sync.Mutex
+
+// Reaproveita o resultado de check por readinessCacheTTL. Uma sonda interrompida pelo
+// cancelamento do pedido (cliente desconectado) não é guardada; estouro do prazo é
+func cachedReadinessCheck(name string, check func(ctx context.Context) error) func(ctx context.Context) error {
+	var (
+		lastErr   error
+		checkedAt time.Time
+	)
+	return func(ctx context.Context) error {
+		readinessCacheMu.Lock()
+		if !checkedAt.IsZero() && time.Since(checkedAt) < readinessCacheTTL {
+			err := lastErr
+			readinessCacheMu.Unlock()
+			return err
+		}
+		readinessCacheMu.Unlock()
+
+		_, _, err := coalesce(ctx, &readinessProbes, name, func() (struct{}, error) {
+			err := check(ctx)
+			if !errors.Is(ctx.Err(), context.Canceled) {
+				readinessCacheMu.Lock()
+				lastErr, checkedAt = err, time.Now()
+				readinessCacheMu.Unlock()
+			}
+			return struct{}{}, err
+		})
+		return err
+	}
+}
+
+// Resultado de uma verificação no corpo do /readyz
+type readinessResult struct {
+	Status string `json:"status"` // ok ou fail
+	Error  string `json:"error,omitempty"`
+}
+
+// Executa as verificações configuradas em paralelo, cada uma com readinessCheckTimeout
+func runReadinessChecks(ctx context.Context) map[string]readinessResult {
+	results := make(map[string]readinessResult, len(readinessChecks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range readinessChecks {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+			defer cancel()
+
+			result := readinessResult{Status: "ok"}
+			if err := readinessCheckFuncs[name](ctx); err != nil {
+				result = readinessResult{Status: "fail", Error: err.Error()}
+			}
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}(name)
+	}
+	wg.Wait()
+	return results
+}
+
+// Status do /readyz a partir das verificações. Uma dependência fora (viacep, otlp)
+// responde 503; só o breaker da WeatherAPI aberto é degradação, com
+// READYZ_DEGRADED_STATUS, pois a instância ainda atende do cache e com o fallback
+func readinessStatus(results map[string]readinessResult) (status string, code int, degraded bool) {
+	failed := false
+	for name, result := range results {
+		if result.Status == "ok" {
+			continue
+		}
+		if name == readinessWeather {
+			degraded = true
+		} else {
+			failed = true
+		}
+	}
+
+	switch {
+	case failed:
+		return "unavailable", http.StatusServiceUnavailable, degraded
+	case degraded:
+		return "degraded", readyzDegradedStatus, true
+	}
+	return "ok", http.StatusOK, false
+}
+
+// Uma única tentativa, sem retentativas nem orçamento de erros: é só uma sonda
+func checkViaCEP(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/ws/%s/json/", viaCEPBaseURL, readinessViaCEPProbe), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := readinessHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func checkWeatherBreaker(context.Context) error {
+	if state := weatherBreaker.State(); state != breakerClosed {
+		return fmt.Errorf("breaker %s", state)
+	}
+	return nil
+}
+
+func checkOTLP(ctx context.Context) error {
+	if readinessOTLPEndpoint == "" {
+		return nil
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", strings.TrimPrefix(readinessOTLPEndpoint, "http://"))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadinessStatus(t *testing.T) {
+	ok := readinessResult{Status: "ok"}
+	fail := readinessResult{Status: "fail", Error: "x"}
+
+	tests := []struct {
+		name         string
+		results      map[string]readinessResult
+		wantStatus   string
+		wantCode     int
+		wantDegraded bool
+	}{
+		{"sem verificações", map[string]readinessResult{}, "ok", http.StatusOK, false},
+		{"tudo ok", map[string]readinessResult{readinessWeather: ok, readinessViaCEP: ok}, "ok", http.StatusOK, false},
+		{"breaker aberto", map[string]readinessResult{readinessWeather: fail, readinessViaCEP: ok}, "degraded", http.StatusOK, true},
+		{"viacep fora", map[string]readinessResult{readinessWeather: ok, readinessViaCEP: fail}, "unavailable", http.StatusServiceUnavailable, false},
+		{"otlp fora", map[string]readinessResult{readinessOTLP: fail}, "unavailable", http.StatusServiceUnavailable, false},
+		{"breaker aberto e viacep fora", map[string]readinessResult{readinessWeather: fail, readinessViaCEP: fail}, "unavailable", http.StatusServiceUnavailable, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code, degraded := readinessStatus(tt.results)
+			if status != tt.wantStatus || code != tt.wantCode || degraded != tt.wantDegraded {
+				t.Errorf("readinessStatus = %q, %d, %v, want %q, %d, %v",
+					status, code, degraded, tt.wantStatus, tt.wantCode, tt.wantDegraded)
+			}
+		})
+	}
+}
+
+func TestReadyzHandler(t *testing.T) {
+	defer func(checks []string, breaker *circuitBreaker, status int, viacep func(context.Context) error) {
+		readinessChecks, weatherBreaker, readyzDegradedStatus = checks, breaker, status
+		readinessCheckFuncs[readinessViaCEP] = viacep
+	}(readinessChecks, weatherBreaker, readyzDegradedStatus, readinessCheckFuncs[readinessViaCEP])
+
+	viacepDown := func(context.Context) error { return errors.New("status 502") }
+	viacepUp := func(context.Context) error { return nil }
+
+	tests := []struct {
+		name           string
+		checks         []string
+		breakerOpen    bool
+		degradedStatus int
+		viacep         func(context.Context) error
+		wantCode       int
+		wantStatus     string
+		wantDegraded   bool
+		wantBreaker    string
+	}{
+		{"breaker fechado", []string{readinessWeather}, false, http.StatusOK, viacepUp, http.StatusOK, "ok", false, breakerClosed},
+		{"breaker aberto, 200", []string{readinessWeather}, true, http.StatusOK, viacepUp, http.StatusOK, "degraded", true, breakerOpen},
+		{"breaker aberto, 503", []string{readinessWeather}, true, http.StatusServiceUnavailable, viacepUp, http.StatusServiceUnavailable, "degraded", true, breakerOpen},
+		{"viacep fora", []string{readinessWeather, readinessViaCEP}, false, http.StatusOK, viacepDown, http.StatusServiceUnavailable, "unavailable", false, breakerClosed},
+		{"viacep no ar", []string{readinessViaCEP}, false, http.StatusOK, viacepUp, http.StatusOK, "ok", false, breakerClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readinessChecks = tt.checks
+			readyzDegradedStatus = tt.degradedStatus
+			readinessCheckFuncs[readinessViaCEP] = tt.viacep
+			weatherBreaker = newCircuitBreaker(2, time.Minute)
+			if tt.breakerOpen {
+				weatherBreaker.record(false)
+				weatherBreaker.record(false)
+			}
+
+			rec := httptest.NewRecorder()
+			readyzHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			var body struct {
+				Status         string                     `json:"status"`
+				Degraded       bool                       `json:"degraded"`
+				WeatherBreaker string                     `json:"weather_breaker"`
+				Checks         map[string]readinessResult `json:"checks"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Status != tt.wantStatus || body.Degraded != tt.wantDegraded || body.WeatherBreaker != tt.wantBreaker {
+				t.Errorf("body = %+v, want status %q, degraded %v, breaker %q",
+					body, tt.wantStatus, tt.wantDegraded, tt.wantBreaker)
+			}
+			if len(body.Checks) != len(tt.checks) {
+				t.Errorf("checks = %v, want %v", body.Checks, tt.checks)
+			}
+		})
+	}
+}
+
+func TestReadyzHandlerShuttingDown(t *testing.T) {
+	shuttingDown.Store(true)
+	defer shuttingDown.Store(false)
+
+	rec := httptest.NewRecorder()
+	readyzHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+}
+
+func TestCachedReadinessCheck(t *testing.T) {
+	defer func(ttl time.Duration) { readinessCacheTTL = ttl }(readinessCacheTTL)
+
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		cancel    bool
+		wantCalls int
+	}{
+		{"reaproveita dentro do ttl", time.Minute, false, 1},
+		{"ttl zero sempre verifica", 0, false, 3},
+		{"cancelada não é guardada", time.Minute, true, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readinessCacheTTL = tt.ttl
+			key := "test-" + tt.name
+			calls := 0
+			check := cachedReadinessCheck(key, func(ctx context.Context) error {
+				calls++
+				return ctx.Err()
+			})
+
+			for i := 0; i < 3; i++ {
+				ctx, cancel := context.WithCancel(context.Background())
+				if tt.cancel {
+					cancel()
+				}
+				check(ctx)
+				cancel()
+				// Com o ctx cancelado, check retorna sem esperar a sonda: Do aguarda a que
+				// estiver em andamento
+				readinessProbes.Do(key, func() (interface{}, error) { return nil, nil })
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("%d verificações, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCachedReadinessCheckKeepsFailure(t *testing.T) {
+	defer func(ttl time.Duration) { readinessCacheTTL = ttl }(readinessCacheTTL)
+	readinessCacheTTL = time.Minute
+
+	calls := 0
+	check := cachedReadinessCheck("test-failure", func(context.Context) error {
+		calls++
+		return errors.New("connection refused")
+	})
+	for i := 0; i < 2; i++ {
+		if err := check(context.Background()); err == nil {
+			t.Fatal("erro esperado")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("%d verificações, want 1", calls)
+	}
+}
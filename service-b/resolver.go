@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/validation"
+)
+
+// Resolve códigos postais de um país para o endereço cuja localidade alimenta a consulta de clima
+type postalCodeResolver interface {
+	// Nome do provedor, registrado no span (cep.provider)
+	Name() string
+	// Valida o formato do código postal antes de qualquer chamada externa
+	ValidFormat(code string) bool
+	// Busca o endereço do código postal; erro se não encontrado
+	Resolve(ctx context.Context, code string) (*CEP, error)
+}
+
+// País usado quando a requisição não informa ?country=
+const defaultCountry = "BR"
+
+// Resolvers por código de país (ISO 3166-1 alpha-2)
+var postalCodeResolvers = map[string]postalCodeResolver{
+	defaultCountry: viaCEPResolver{},
+}
+
+// Retorna o resolver do país (default: BR); ok é false para países não suportados
+func resolverFor(country string) (postalCodeResolver, bool) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if country == "" {
+		country = defaultCountry
+	}
+	r, ok := postalCodeResolvers[country]
+	return r, ok
+}
+
+// CEPs brasileiros via ViaCEP
+type viaCEPResolver struct{}
+
+func (viaCEPResolver) Name() string { return cepProviderViaCEP }
+
+func (viaCEPResolver) ValidFormat(code string) bool { return validation.IsValidCEP(code) }
+
+func (viaCEPResolver) Resolve(ctx context.Context, code string) (*CEP, error) {
+	return getCEPInfo(ctx, code)
+}
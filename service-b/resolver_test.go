@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Resolver de teste para códigos postais portugueses (NNNN-NNN)
+type stubResolver struct {
+	calls int
+}
+
+var ptPostalCode = regexp.MustCompile(`^\d{4}-\d{3}$`)
+
+func (*stubResolver) Name() string { return "stub" }
+
+func (*stubResolver) ValidFormat(code string) bool { return ptPostalCode.MatchString(code) }
+
+func (s *stubResolver) Resolve(ctx context.Context, code string) (*CEP, error) {
+	s.calls++
+	if code == "9999-999" {
+		return nil, errors.New("não encontrado")
+	}
+	return &CEP{Cep: code, Localidade: "Lisboa", Uf: "LX"}, nil
+}
+
+// Registra stub como resolver de PT até o fim do teste
+func withStubResolver(tb testing.TB, stub postalCodeResolver) {
+	tb.Helper()
+	postalCodeResolvers["PT"] = stub
+	tb.Cleanup(func() { delete(postalCodeResolvers, "PT") })
+}
+
+func TestResolverFor(t *testing.T) {
+	withStubResolver(t, &stubResolver{})
+
+	tests := []struct {
+		country string
+		want    string
+		ok      bool
+	}{
+		{"", cepProviderViaCEP, true},
+		{"BR", cepProviderViaCEP, true},
+		{" br ", cepProviderViaCEP, true},
+		{"PT", "stub", true},
+		{"pt", "stub", true},
+		{"AR", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.country, func(t *testing.T) {
+			r, ok := resolverFor(tt.country)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && r.Name() != tt.want {
+				t.Errorf("Name = %q, want %q", r.Name(), tt.want)
+			}
+		})
+	}
+}
+
+// ?country= escolhe o resolver; a localidade que ele devolve segue para a WeatherAPI
+func TestWeatherHandlerCountry(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantCalls  int
+		wantMsg    string
+	}{
+		{"país registrado", "/1000-001?country=PT", http.StatusOK, 1, ""},
+		{"formato do país", "/01001000?country=PT", http.StatusUnprocessableEntity, 0, "invalid zipcode"},
+		{"não encontrado", "/9999-999?country=PT", http.StatusNotFound, 1, "can not find zipcode"},
+		{"país não suportado", "/1000-001?country=AR", http.StatusUnprocessableEntity, 0, "unsupported country"},
+		{"BR continua no ViaCEP", "/01001000", http.StatusOK, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var weatherQuery string
+			withMockUpstreams(t, mockViaCEP(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				weatherQuery = r.URL.Query().Get("q")
+				mockWeatherAPI(18).ServeHTTP(w, r)
+			}))
+			stub := &stubResolver{}
+			withStubResolver(t, stub)
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if stub.calls != tt.wantCalls {
+				t.Errorf("chamadas ao resolver = %d, want %d", stub.calls, tt.wantCalls)
+			}
+
+			if tt.wantMsg != "" {
+				var body ErrorResponse
+				json.NewDecoder(rec.Body).Decode(&body)
+				if body.Message != tt.wantMsg {
+					t.Errorf("message = %q, want %q", body.Message, tt.wantMsg)
+				}
+			}
+			if tt.wantCalls == 1 && tt.wantStatus == http.StatusOK && !strings.Contains(weatherQuery, "Lisboa") {
+				t.Errorf("consulta à WeatherAPI = %q, want a localidade do resolver", weatherQuery)
+			}
+		})
+	}
+}
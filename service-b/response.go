@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Escreve a resposta de erro em JSON e registra o status e a mensagem no span
+func writeError(w http.ResponseWriter, span trace.Span, status int, msg string) {
+	writeUpstreamError(w, span, status, msg, "")
+}
+
+// Como writeError, para falhas causadas por um serviço externo (viacep, weatherapi)
+func writeUpstreamError(w http.ResponseWriter, span trace.Span, status int, msg, upstream string) {
+	writeLookupError(w, span, &lookupError{Status: status, Message: msg, Upstream: upstream})
+}
+
+// Respostas de erro com código e detalhes (VERBOSE_ERRORS)
+var verboseErrors bool
+
+// Escreve o erro da consulta; com VERBOSE_ERRORS=true inclui o código e os detalhes
+func writeLookupError(w http.ResponseWriter, span trace.Span, err *lookupError) {
+	recordErrorStatus(span, err.Status, err.Message, err.Upstream)
+
+	body := ErrorResponse{Message: err.Message, TraceID: traceIDOf(span)}
+	if verboseErrors {
+		body.Code, body.Details = err.Code, err.Details
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.Status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// Marca o span como erro com o status HTTP devolvido ao cliente e guarda o erro
+// entre os recentes (/admin/errors)
+func recordErrorStatus(span trace.Span, status int, msg, upstream string) {
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+	if upstream != "" {
+		span.SetAttributes(attribute.String("error.upstream", upstream))
+	}
+	span.SetStatus(codes.Error, msg)
+
+	recentErrors.add(errorEvent{
+		Time:     time.Now(),
+		TraceID:  traceIDOf(span),
+		Status:   status,
+		Message:  msg,
+		Upstream: upstream,
+	})
+}
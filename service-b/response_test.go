@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// writeError responde o JSON de erro com o status e marca o span com o mesmo status;
+// com VERBOSE_ERRORS=true o corpo leva também o código e os detalhes
+func TestWriteLookupError(t *testing.T) {
+	defer func(v bool, ring *errorRing) { verboseErrors, recentErrors = v, ring }(verboseErrors, recentErrors)
+
+	tests := []struct {
+		name     string
+		verbose  bool
+		err      *lookupError
+		wantCode string
+	}{
+		{"422", false, &lookupError{Status: http.StatusUnprocessableEntity, Message: "invalid zipcode", Code: "INVALID_FORMAT", Details: "7 dígitos"}, ""},
+		{"422 verboso", true, &lookupError{Status: http.StatusUnprocessableEntity, Message: "invalid zipcode", Code: "INVALID_FORMAT", Details: "7 dígitos"}, "INVALID_FORMAT"},
+		{"404 do upstream", false, &lookupError{Status: http.StatusNotFound, Message: "can not find zipcode", Upstream: cepProviderViaCEP}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verboseErrors = tt.verbose
+			recentErrors = newErrorRing(10)
+			sr := withSpanRecorder(t)
+			_, span := tracer.Start(context.Background(), "handler")
+
+			rec := httptest.NewRecorder()
+			writeLookupError(rec, span, tt.err)
+			span.End()
+
+			if rec.Code != tt.err.Status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.err.Status)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			var body ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("corpo inválido: %v (%s)", err, rec.Body)
+			}
+			if body.Message != tt.err.Message || body.Code != tt.wantCode || body.TraceID != span.SpanContext().TraceID().String() {
+				t.Errorf("corpo = %+v, want message %q, code %q e trace_id %s", body, tt.err.Message, tt.wantCode, span.SpanContext().TraceID())
+			}
+
+			ended := endedSpan(t, sr, "handler")
+			if ended.Status().Code != codes.Error || ended.Status().Description != tt.err.Message {
+				t.Errorf("status do span = %+v, want Error %q", ended.Status(), tt.err.Message)
+			}
+			if got := spanAttr(ended, "http.response.status_code").AsInt64(); got != int64(tt.err.Status) {
+				t.Errorf("http.response.status_code = %d, want %d", got, tt.err.Status)
+			}
+			if got := spanAttr(ended, "error.upstream").AsString(); got != tt.err.Upstream {
+				t.Errorf("error.upstream = %q, want %q", got, tt.err.Upstream)
+			}
+
+			recent := recentErrors.recent()
+			if len(recent) != 1 || recent[0].Status != tt.err.Status || recent[0].TraceID != body.TraceID {
+				t.Errorf("erros recentes = %+v, want o erro com o trace_id da resposta", recent)
+			}
+		})
+	}
+}
+
+// Sem span válido (ex.: tracing desligado) o corpo não leva trace_id
+func TestWriteErrorWithoutTrace(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeError(rec, trace.SpanFromContext(context.Background()), http.StatusNotFound, "can not find zipcode")
+
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := body["trace_id"]; ok || body["message"] != "can not find zipcode" {
+		t.Errorf("corpo = %v, want só message", body)
+	}
+}
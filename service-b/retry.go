@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Header com o orçamento de retentativas restante da requisição. O chamador envia
+// quantas retentativas ainda podem ser feitas e a resposta devolve o que sobrou,
+// limitando o total de retentativas em toda a cadeia de serviços
+const retryBudgetHeader = "X-Retry-Budget"
+
+// Número máximo de tentativas por chamada ao upstream
+var retryMaxAttempts = 3
+
+// Orçamento padrão para requisições que não informam o header
+var defaultRetryBudget = 2
+
+// Maior espera via Retry-After aceita num 429; acima disso (ou 0) o 429 não é repetido
+var retryAfterMax = 5 * time.Second
+
+// Upstream continuou respondendo 429 depois das retentativas
+var errUpstreamRateLimited = errors.New("upstream respondeu 429 (rate limit)")
+
+// Orçamento de retentativas compartilhado pela requisição
+type retryBudget struct {
+	remaining atomic.Int64
+}
+
+func newRetryBudget(n int) *retryBudget {
+	b := &retryBudget{}
+	b.remaining.Store(int64(n))
+	return b
+}
+
+// Consome uma retentativa do orçamento; retorna false se esgotado
+func (b *retryBudget) take() bool {
+	if b == nil {
+		return false
+	}
+	for {
+		n := b.remaining.Load()
+		if n <= 0 {
+			return false
+		}
+		if b.remaining.CompareAndSwap(n, n-1) {
+			return true
+		}
+	}
+}
+
+func (b *retryBudget) left() int {
+	if b == nil {
+		return 0
+	}
+	return int(b.remaining.Load())
+}
+
+type retryBudgetKey struct{}
+
+func retryBudgetFromContext(ctx context.Context) *retryBudget {
+	b, _ := ctx.Value(retryBudgetKey{}).(*retryBudget)
+	return b
+}
+
+// Lê o orçamento de retentativas do header (ou usa o padrão), disponibiliza no
+// contexto da requisição e devolve o restante no header da resposta
+func retryBudgetMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := defaultRetryBudget
+		if v := r.Header.Get(retryBudgetHeader); v != "" {
+			if parsed, err := strconv.Atoi(v); err == nil && parsed >= 0 {
+				n = parsed
+			}
+		}
+
+		budget := newRetryBudget(n)
+		ctx := context.WithValue(r.Context(), retryBudgetKey{}, budget)
+		next.ServeHTTP(&retryBudgetWriter{ResponseWriter: w, budget: budget}, r.WithContext(ctx))
+	})
+}
+
+// ResponseWriter que escreve o orçamento restante antes dos headers da resposta
+type retryBudgetWriter struct {
+	http.ResponseWriter
+	budget      *retryBudget
+	wroteHeader bool
+}
+
+func (w *retryBudgetWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.Header().Set(retryBudgetHeader, strconv.Itoa(w.budget.left()))
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *retryBudgetWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *retryBudgetWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Chamado por doWithRetry antes de cada tentativa com a cópia da requisição, que pode
+// alterar (ex.: a chave da WeatherAPI); a função devolvida recebe a resposta da
+// tentativa, nil em erro de rede
+type attemptHook func(req *http.Request) func(resp *http.Response)
+
+type attemptHookKey struct{}
+
+// Contexto cujas chamadas a doWithRetry, inclusive as do hedge, passam por hook
+func withAttemptHook(ctx context.Context, hook attemptHook) context.Context {
+	return context.WithValue(ctx, attemptHookKey{}, hook)
+}
+
+// Executa a requisição com retentativas para falhas transitórias (erro de rede, 5xx ou
+// 429), limitadas por retryMaxAttempts e pelo orçamento de retentativas da requisição
+func doWithRetry(ctx context.Context, req *http.Request) (*http.Response, error) {
+	span := trace.SpanFromContext(ctx)
+	budget := retryBudgetFromContext(ctx)
+	hook, _ := ctx.Value(attemptHookKey{}).(attemptHook)
+
+	var wait time.Duration
+	for attempt := 1; ; attempt++ {
+		attemptReq := req.Clone(ctx)
+		var done func(*http.Response)
+		if hook != nil {
+			done = hook(attemptReq)
+		}
+		resp, err := httpClient.Do(attemptReq)
+		if done != nil {
+			done(resp)
+		}
+		var retry bool
+		wait, retry = retryDelay(ctx, resp, err, attempt, wait)
+		if !retry || attempt >= retryMaxAttempts || !budget.take() {
+			span.SetAttributes(attribute.Int("retry.attempts", attempt))
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		span.AddEvent("retry", trace.WithAttributes(
+			attribute.Int("retry.attempt", attempt),
+			attribute.Int("retry.budget_left", budget.left()),
+			attribute.Int64("retry.wait_ms", wait.Milliseconds()),
+		))
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Espera até a próxima tentativa, a partir da espera anterior prev; retry é false se a
+// falha não é transitória, se o Retry-After de um 429 passa de retryAfterMax ou se a
+// espera não cabe no prazo de ctx
+func retryDelay(ctx context.Context, resp *http.Response, err error, attempt int, prev time.Duration) (wait time.Duration, retry bool) {
+	if !isRetryable(resp, err) {
+		return 0, false
+	}
+
+	wait = retryBackoff(attempt, prev)
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		if after, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if after > retryAfterMax {
+				return 0, false
+			}
+			wait = max(wait, after)
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		return 0, false
+	}
+	return wait, true
+}
+
+// Retry-After em segundos ou como data HTTP
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// Falhas transitórias: erro de rede (exceto cancelamento e o limite local de conexões),
+// 5xx ou 429 do upstream
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, errUpstreamSaturated)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return retryAfterMax > 0
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+// Estratégias de jitter do backoff (RETRY_JITTER)
+const (
+	retryJitterNone         = "none"         // backoff exponencial puro
+	retryJitterFull         = "full"         // aleatório entre 0 e o exponencial
+	retryJitterEqual        = "equal"        // metade do exponencial + aleatório até a outra metade
+	retryJitterDecorrelated = "decorrelated" // aleatório entre a base e 3x a espera anterior
+)
+
+var retryJitter = retryJitterNone
+
+// Base e teto do backoff
+const (
+	retryBackoffBase = 100 * time.Millisecond
+	retryBackoffMax  = 5 * time.Second
+)
+
+// Sorteio em [0, n); substituível por uma fonte com seed
+var retryRandInt63n = rand.Int63n
+
+// Backoff exponencial entre tentativas (100ms, 200ms, 400ms... até retryBackoffMax) com
+// o jitter de RETRY_JITTER, para que clientes que falharam juntos não repitam juntos.
+// prev é a espera anterior (a base, na primeira), usada só pelo decorrelated
+func retryBackoff(attempt int, prev time.Duration) time.Duration {
+	exp := retryBackoffMax
+	if attempt <= 16 {
+		exp = min(retryBackoffBase<<(attempt-1), retryBackoffMax)
+	}
+
+	switch retryJitter {
+	case retryJitterFull:
+		return randDuration(0, exp)
+	case retryJitterEqual:
+		return exp/2 + randDuration(0, exp-exp/2)
+	case retryJitterDecorrelated:
+		return min(randDuration(retryBackoffBase, 3*max(prev, retryBackoffBase)), retryBackoffMax)
+	}
+	return exp
+}
+
+// Duração aleatória em [lo, hi]
+func randDuration(lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	return lo + time.Duration(retryRandInt63n(int64(hi-lo)+1))
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	defer func(jitter string, randInt63n func(int64) int64) {
+		retryJitter, retryRandInt63n = jitter, randInt63n
+	}(retryJitter, retryRandInt63n)
+
+	lowest := func(int64) int64 { return 0 }
+	highest := func(n int64) int64 { return n - 1 }
+
+	tests := []struct {
+		name    string
+		jitter  string
+		rand    func(int64) int64
+		attempt int
+		prev    time.Duration
+		want    time.Duration
+	}{
+		{"sem jitter, 1ª", retryJitterNone, lowest, 1, 0, 100 * time.Millisecond},
+		{"sem jitter, 3ª", retryJitterNone, lowest, 3, 0, 400 * time.Millisecond},
+		{"sem jitter, no teto", retryJitterNone, lowest, 7, 0, retryBackoffMax},
+		{"sem jitter, sem overflow", retryJitterNone, lowest, 64, 0, retryBackoffMax},
+		{"full, mínimo", retryJitterFull, lowest, 3, 0, 0},
+		{"full, máximo", retryJitterFull, highest, 3, 0, 400 * time.Millisecond},
+		{"equal, mínimo", retryJitterEqual, lowest, 3, 0, 200 * time.Millisecond},
+		{"equal, máximo", retryJitterEqual, highest, 3, 0, 400 * time.Millisecond},
+		{"decorrelated, mínimo", retryJitterDecorrelated, lowest, 2, time.Second, retryBackoffBase},
+		{"decorrelated, máximo", retryJitterDecorrelated, highest, 2, time.Second, 3 * time.Second},
+		{"decorrelated, sem espera anterior", retryJitterDecorrelated, highest, 1, 0, 3 * retryBackoffBase},
+		{"decorrelated, no teto", retryJitterDecorrelated, highest, 2, 4 * time.Second, retryBackoffMax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retryJitter, retryRandInt63n = tt.jitter, tt.rand
+			if got := retryBackoff(tt.attempt, tt.prev); got != tt.want {
+				t.Errorf("retryBackoff(%d, %s) = %s, want %s", tt.attempt, tt.prev, got, tt.want)
+			}
+		})
+	}
+}
+
+// Com uma fonte aleatória de seed fixa, toda espera fica dentro dos limites da estratégia
+func TestRetryBackoffBounds(t *testing.T) {
+	defer func(jitter string, randInt63n func(int64) int64) {
+		retryJitter, retryRandInt63n = jitter, randInt63n
+	}(retryJitter, retryRandInt63n)
+	retryRandInt63n = rand.New(rand.NewSource(1)).Int63n
+
+	tests := []struct {
+		name   string
+		jitter string
+		bounds func(attempt int, prev time.Duration) (lo, hi time.Duration)
+	}{
+		{"full", retryJitterFull, func(attempt int, _ time.Duration) (time.Duration, time.Duration) {
+			return 0, min(retryBackoffBase<<(attempt-1), retryBackoffMax)
+		}},
+		{"equal", retryJitterEqual, func(attempt int, _ time.Duration) (time.Duration, time.Duration) {
+			exp := min(retryBackoffBase<<(attempt-1), retryBackoffMax)
+			return exp / 2, exp
+		}},
+		{"decorrelated", retryJitterDecorrelated, func(_ int, prev time.Duration) (time.Duration, time.Duration) {
+			return retryBackoffBase, min(3*max(prev, retryBackoffBase), retryBackoffMax)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retryJitter = tt.jitter
+			for i := 0; i < 1000; i++ {
+				attempt := i%8 + 1
+				prev := retryBackoffBase
+				for a := 1; a <= attempt; a++ {
+					lo, hi := tt.bounds(a, prev)
+					wait := retryBackoff(a, prev)
+					if wait < lo || wait > hi {
+						t.Fatalf("retryBackoff(%d, %s) = %s, fora de [%s, %s]", a, prev, wait, lo, hi)
+					}
+					prev = wait
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"ausente", "", 0, false},
+		{"segundos", "3", 3 * time.Second, true},
+		{"zero", "0", 0, true},
+		{"negativo", "-1", 0, false},
+		{"inválido", "soon", 0, false},
+		{"data http", now.Add(10 * time.Second).Format(http.TimeFormat), 10 * time.Second, true},
+		{"data passada", now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseRetryAfter(%q) = %s, %v, want %s, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	defer func(jitter string, afterMax time.Duration) {
+		retryJitter, retryAfterMax = jitter, afterMax
+	}(retryJitter, retryAfterMax)
+	retryJitter = retryJitterNone
+	retryAfterMax = 5 * time.Second
+
+	status := func(code int, retryAfter string) *http.Response {
+		resp := &http.Response{StatusCode: code, Header: http.Header{}}
+		if retryAfter != "" {
+			resp.Header.Set("Retry-After", retryAfter)
+		}
+		return resp
+	}
+
+	tests := []struct {
+		name      string
+		resp      *http.Response
+		err       error
+		attempt   int
+		deadline  time.Duration
+		wantWait  time.Duration
+		wantRetry bool
+	}{
+		{"erro de rede", nil, errors.New("connection reset"), 1, 0, 100 * time.Millisecond, true},
+		{"cancelada", nil, context.Canceled, 1, 0, 0, false},
+		{"limite local de conexões", nil, errUpstreamSaturated, 1, 0, 0, false},
+		{"5xx", status(http.StatusBadGateway, ""), nil, 2, 0, 200 * time.Millisecond, true},
+		{"4xx", status(http.StatusNotFound, ""), nil, 1, 0, 0, false},
+		{"429 sem Retry-After", status(http.StatusTooManyRequests, ""), nil, 1, 0, 100 * time.Millisecond, true},
+		{"429 com Retry-After", status(http.StatusTooManyRequests, "2"), nil, 1, 0, 2 * time.Second, true},
+		{"429 com Retry-After menor que o backoff", status(http.StatusTooManyRequests, "0"), nil, 3, 0, 400 * time.Millisecond, true},
+		{"429 com Retry-After acima do máximo", status(http.StatusTooManyRequests, "10"), nil, 1, 0, 0, false},
+		{"espera além do prazo", status(http.StatusBadGateway, ""), nil, 1, 50 * time.Millisecond, 0, false},
+		{"espera dentro do prazo", status(http.StatusBadGateway, ""), nil, 1, time.Minute, 100 * time.Millisecond, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+			wait, retry := retryDelay(ctx, tt.resp, tt.err, tt.attempt, retryBackoffBase)
+			if wait != tt.wantWait || retry != tt.wantRetry {
+				t.Errorf("retryDelay = %s, %v, want %s, %v", wait, retry, tt.wantWait, tt.wantRetry)
+			}
+		})
+	}
+}
+
+// ViaCEP responde 429 com Retry-After na primeira chamada e 200 na seguinte: a
+// requisição é repetida após a espera pedida e o cliente recebe a temperatura
+func TestWeatherHandlerViaCEPRateLimited(t *testing.T) {
+	var calls atomic.Int32
+	viacep := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		mockViaCEP().ServeHTTP(w, r)
+	})
+	withMockUpstreams(t, viacep, mockWeatherAPI(25))
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	retryBudgetMiddleware(newWeatherRouter()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("chamadas ao ViaCEP = %d, want 2", n)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retentativa após %s, antes do Retry-After de 1s", elapsed)
+	}
+}
+
+// 429 em todas as tentativas vira 503 para o cliente, não 404
+func TestWeatherHandlerViaCEPRateLimitExhausted(t *testing.T) {
+	defer func(d time.Duration) { retryAfterMax = d }(retryAfterMax)
+	retryAfterMax = 5 * time.Second
+
+	var calls atomic.Int32
+	viacep := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	withMockUpstreams(t, viacep, mockWeatherAPI(25))
+
+	rec := httptest.NewRecorder()
+	retryBudgetMiddleware(newWeatherRouter()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503; body = %s", rec.Code, rec.Body)
+	}
+	if n := calls.Load(); n < 2 {
+		t.Errorf("chamadas ao ViaCEP = %d, want retentativas", n)
+	}
+}
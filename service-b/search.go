@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/validation"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Tamanho mínimo de cidade e logradouro exigido pelo ViaCEP na busca por endereço
+const searchMinLength = 3
+
+// GET /search?uf=SP&city=São Paulo&street=Paulista: CEPs candidatos para o endereço,
+// via busca reversa do ViaCEP. Parâmetros ausentes ou inválidos respondem 400
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "search_handler")
+	defer span.End()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	uf := strings.ToUpper(strings.TrimSpace(query.Get("uf")))
+	city := strings.TrimSpace(query.Get("city"))
+	street := strings.TrimSpace(query.Get("street"))
+
+	if msg := validateSearch(uf, city, street); msg != "" {
+		span.SetAttributes(attribute.String("validation", "invalid_search"))
+		writeError(w, span, http.StatusBadRequest, msg)
+		return
+	}
+	span.SetAttributes(
+		attribute.String("search.uf", uf),
+		attribute.String("search.city", city),
+	)
+
+	ceps, err := searchCEPs(ctx, uf, city, street)
+	if err != nil {
+		logf(ctx, "Erro na busca de CEP por endereço: %v", err)
+		span.RecordError(err)
+		if errors.Is(err, errUpstreamRateLimited) || errors.Is(err, errUpstreamSaturated) {
+			writeUpstreamError(w, span, http.StatusServiceUnavailable, "zipcode service unavailable", cepProviderViaCEP)
+			return
+		}
+		writeUpstreamError(w, span, http.StatusInternalServerError, "zipcode search unavailable", cepProviderViaCEP)
+		return
+	}
+
+	span.SetAttributes(attribute.Int("search.results", len(ceps)))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ceps)
+}
+
+// Mensagem de erro para parâmetros inválidos; vazio se válidos
+func validateSearch(uf, city, street string) string {
+	if _, ok := capitalByUF[uf]; !ok {
+		return "invalid uf"
+	}
+	for _, v := range []string{city, street} {
+		if validation.IsMalformed(v) {
+			return "malformed search parameters"
+		}
+	}
+	if utf8.RuneCountInString(city) < searchMinLength || utf8.RuneCountInString(street) < searchMinLength {
+		return fmt.Sprintf("city and street need at least %d characters", searchMinLength)
+	}
+	return ""
+}
+
+// Consulta o ViaCEP em /ws/{uf}/{cidade}/{logradouro}/json/. Sem resultados, devolve
+// uma lista vazia
+func searchCEPs(ctx context.Context, uf, city, street string) ([]CEP, error) {
+	ctx, span := tracer.Start(ctx, "search_cep")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("api", cepProviderViaCEP))
+
+	searchURL := fmt.Sprintf("%s/ws/%s/%s/%s/json/", viaCEPBaseURL,
+		url.PathEscape(uf), url.PathEscape(city), url.PathEscape(street))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("erro ao criar request: %w", err)
+	}
+
+	resp, err := doWithRetry(ctx, req)
+	viaCEPErrorBudget.record(ctx, resp, err)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("erro ao buscar CEP por endereço: %w", err)
+	}
+	defer resp.Body.Close()
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		err := fmt.Errorf("erro na API ViaCEP: %w", errUpstreamRateLimited)
+		span.RecordError(err)
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("erro na API ViaCEP: status %d", resp.StatusCode)
+		span.RecordError(err)
+		return nil, err
+	}
+
+	ceps := []CEP{}
+	if err := json.NewDecoder(resp.Body).Decode(&ceps); err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("erro ao decodificar resposta da busca: %w", err)
+	}
+	return ceps, nil
+}
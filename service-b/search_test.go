@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+// ViaCEP simulado para a busca por endereço: responde a /ws/SP/São Paulo/Paulista/json/
+// com dois CEPs e a qualquer outro endereço com uma lista vazia
+func mockViaCEPSearch(gotPath *string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*gotPath = r.URL.Path
+		if r.URL.Path != "/ws/SP/São Paulo/Paulista/json/" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		json.NewEncoder(w).Encode([]CEP{
+			{Cep: "01310-100", Logradouro: "Avenida Paulista", Localidade: "São Paulo", Uf: "SP"},
+			{Cep: "01311-000", Logradouro: "Avenida Paulista", Localidade: "São Paulo", Uf: "SP"},
+		})
+	})
+}
+
+func TestValidateSearch(t *testing.T) {
+	tests := []struct {
+		name   string
+		uf     string
+		city   string
+		street string
+		want   string
+	}{
+		{"válido", "SP", "São Paulo", "Paulista", ""},
+		{"uf vazia", "", "São Paulo", "Paulista", "invalid uf"},
+		{"uf inexistente", "XX", "São Paulo", "Paulista", "invalid uf"},
+		{"cidade curta", "SP", "SP", "Paulista", "city and street need at least 3 characters"},
+		{"logradouro curto", "SP", "São Paulo", "Av", "city and street need at least 3 characters"},
+		{"acentos contam como um caractere", "PA", "Ipê", "Rua", ""},
+		{"byte inválido", "SP", "São Paulo", "Paulista\xff", "malformed search parameters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateSearch(tt.uf, tt.city, tt.street); got != tt.want {
+				t.Errorf("validateSearch(%q, %q, %q) = %q, want %q", tt.uf, tt.city, tt.street, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchHandler(t *testing.T) {
+	var gotPath string
+	withMockUpstreams(t, mockViaCEPSearch(&gotPath), mockWeatherAPI(25))
+
+	tests := []struct {
+		name       string
+		query      url.Values
+		wantStatus int
+		wantCEPs   []string
+		wantPath   string
+	}{
+		{"resultados", url.Values{"uf": {"sp"}, "city": {" São Paulo "}, "street": {"Paulista"}},
+			http.StatusOK, []string{"01310-100", "01311-000"}, "/ws/SP/São Paulo/Paulista/json/"},
+		{"sem resultados", url.Values{"uf": {"RJ"}, "city": {"Niterói"}, "street": {"Inexistente"}},
+			http.StatusOK, []string{}, "/ws/RJ/Niterói/Inexistente/json/"},
+		{"uf inválida", url.Values{"uf": {"ZZ"}, "city": {"São Paulo"}, "street": {"Paulista"}},
+			http.StatusBadRequest, nil, ""},
+		{"parâmetros ausentes", url.Values{"uf": {"SP"}}, http.StatusBadRequest, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotPath = ""
+			req := httptest.NewRequest("GET", "/search?"+tt.query.Encode(), nil)
+			rec := httptest.NewRecorder()
+			searchHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if gotPath != tt.wantPath {
+				t.Errorf("path no ViaCEP = %q, want %q", gotPath, tt.wantPath)
+			}
+			if tt.wantCEPs == nil {
+				return
+			}
+			var ceps []CEP
+			if err := json.NewDecoder(rec.Body).Decode(&ceps); err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, c := range ceps {
+				got = append(got, c.Cep)
+			}
+			if !slices.Equal(got, tt.wantCEPs) {
+				t.Errorf("CEPs = %v, want %v", got, tt.wantCEPs)
+			}
+		})
+	}
+}
+
+func TestSearchHandlerUpstreamError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		wantStatus int
+	}{
+		{"erro no ViaCEP", http.StatusInternalServerError, http.StatusInternalServerError},
+		{"ViaCEP limitando", http.StatusTooManyRequests, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}), mockWeatherAPI(25))
+
+			req := httptest.NewRequest("GET", "/search?uf=SP&city=S%C3%A3o+Paulo&street=Paulista", nil)
+			rec := httptest.NewRecorder()
+			searchHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
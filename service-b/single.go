@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Unidades de temperatura aceitas em DEFAULT_TEMP_UNIT e em ?primary=
+const (
+	tempUnitCelsius    = "C"
+	tempUnitFahrenheit = "F"
+	tempUnitKelvin     = "K"
+)
+
+// Unidade da resposta com ?single=true (DEFAULT_TEMP_UNIT)
+var defaultTempUnit = tempUnitCelsius
+
+// Resposta com uma única temperatura (?single=true), para clientes legados que só leem
+// um campo. A unidade vem de DEFAULT_TEMP_UNIT e é informada em unit
+type SingleTemperatureResponse struct {
+	City string      `json:"city"`
+	Temp Temperature `json:"temp"`
+	Unit string      `json:"unit"`
+
+	GeneratedAt string `json:"generated_at,omitempty"`
+}
+
+func newSingleResponse(resp TemperatureResponse, unit string) SingleTemperatureResponse {
+	return SingleTemperatureResponse{City: resp.City, Temp: temperatureIn(resp, unit), Unit: unit, GeneratedAt: resp.GeneratedAt}
+}
+
+// Temperatura da resposta na unidade pedida (C, F ou K)
+func temperatureIn(resp TemperatureResponse, unit string) Temperature {
+	switch unit {
+	case tempUnitFahrenheit:
+		return resp.TempF
+	case tempUnitKelvin:
+		return resp.TempK
+	}
+	return resp.TempC
+}
+
+// Interpreta ?primary=F (C, F ou K, sem diferenciar maiúsculas). Retorna "" se o
+// parâmetro não foi informado e erro para outras unidades
+func parsePrimaryUnit(param string) (string, error) {
+	if param == "" {
+		return "", nil
+	}
+	switch unit := strings.ToUpper(param); unit {
+	case tempUnitCelsius, tempUnitFahrenheit, tempUnitKelvin:
+		return unit, nil
+	}
+	return "", fmt.Errorf("invalid primary unit: %s", param)
+}
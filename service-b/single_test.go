@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParsePrimaryUnit(t *testing.T) {
+	tests := []struct {
+		name    string
+		param   string
+		want    string
+		wantErr bool
+	}{
+		{"ausente", "", "", false},
+		{"celsius", "C", tempUnitCelsius, false},
+		{"fahrenheit minúsculo", "f", tempUnitFahrenheit, false},
+		{"kelvin", "K", tempUnitKelvin, false},
+		{"inválida", "R", "", true},
+		{"nome por extenso", "celsius", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePrimaryUnit(tt.param)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parsePrimaryUnit(%q) = %q, want %q", tt.param, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemperatureIn(t *testing.T) {
+	resp := TemperatureResponse{TempC: 25, TempF: 77, TempK: 298}
+
+	tests := []struct {
+		unit string
+		want Temperature
+	}{
+		{tempUnitCelsius, 25},
+		{tempUnitFahrenheit, 77},
+		{tempUnitKelvin, 298},
+		{"", 25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.unit, func(t *testing.T) {
+			if got := temperatureIn(resp, tt.unit); got != tt.want {
+				t.Errorf("temperatureIn(%q) = %v, want %v", tt.unit, got, tt.want)
+			}
+		})
+	}
+}
+
+// ?single=true responde só city, temp e unit, na unidade de DEFAULT_TEMP_UNIT; sem ele a
+// resposta segue com as três temperaturas
+func TestWeatherHandlerSingle(t *testing.T) {
+	defer func(u string) { defaultTempUnit = u }(defaultTempUnit)
+	withMockUpstreams(t, mockViaCEP(), mockWeatherAPI(25))
+
+	tests := []struct {
+		unit     string
+		wantTemp float64
+	}{
+		{tempUnitCelsius, 25},
+		{tempUnitFahrenheit, 77},
+		{tempUnitKelvin, 298},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.unit, func(t *testing.T) {
+			defaultTempUnit = tt.unit
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000?single=true", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body)
+			}
+
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body["temp"] != tt.wantTemp || body["unit"] != tt.unit || body["city"] != "Sao Paulo" {
+				t.Errorf("resposta = %v, want temp %v e unit %s", body, tt.wantTemp, tt.unit)
+			}
+			for _, key := range []string{"temp_C", "temp_F", "temp_K"} {
+				if _, ok := body[key]; ok {
+					t.Errorf("%s presente na resposta única: %v", key, body)
+				}
+			}
+		})
+	}
+
+	t.Run("sem single", func(t *testing.T) {
+		defaultTempUnit = tempUnitFahrenheit
+		rec := httptest.NewRecorder()
+		newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body["temp_C"] != 25.0 || body["temp_F"] != 77.0 || body["temp"] != nil {
+			t.Errorf("resposta padrão = %v, want as três temperaturas", body)
+		}
+	})
+
+	t.Run("com int", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000?single=true&int=true", nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", rec.Code)
+		}
+	})
+}
+
+// ?primary= marca a unidade preferida em primary_unit sem remover as três temperaturas;
+// unidade inválida ou combinada com ?single=true responde 400
+func TestWeatherHandlerPrimary(t *testing.T) {
+	withMockUpstreams(t, mockViaCEP(), mockWeatherAPI(25))
+
+	tests := []struct {
+		name        string
+		query       string
+		wantStatus  int
+		wantPrimary any
+		wantMessage string
+	}{
+		{"celsius", "?primary=C", http.StatusOK, tempUnitCelsius, ""},
+		{"fahrenheit", "?primary=F", http.StatusOK, tempUnitFahrenheit, ""},
+		{"kelvin", "?primary=K", http.StatusOK, tempUnitKelvin, ""},
+		{"minúscula", "?primary=f", http.StatusOK, tempUnitFahrenheit, ""},
+		{"ausente", "", http.StatusOK, nil, ""},
+		{"inválida", "?primary=R", http.StatusBadRequest, nil, "invalid primary unit: R"},
+		{"com single", "?primary=F&single=true", http.StatusBadRequest, nil, "single cannot be combined with primary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if body["message"] != tt.wantMessage {
+					t.Errorf("message = %v, want %q", body["message"], tt.wantMessage)
+				}
+				return
+			}
+			if body["primary_unit"] != tt.wantPrimary {
+				t.Errorf("primary_unit = %v, want %v", body["primary_unit"], tt.wantPrimary)
+			}
+			if body["temp_C"] != 25.0 || body["temp_F"] != 77.0 || body["temp_K"] != 298.0 {
+				t.Errorf("temperaturas = %v, want as três unidades", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/telemetry"
+)
+
+var droppedSpans = newDroppedSpans(meter)
+
+func newDroppedSpans(m metric.Meter) metric.Int64Counter {
+	c, _ := m.Int64Counter("trace.export.spans_dropped",
+		metric.WithDescription("Spans descartados com a fila de exportação cheia"),
+		metric.WithUnit("{span}"),
+	)
+	return c
+}
+
+// Collectors que recebem os spans, no atributo collector de trace.export.spans_dropped
+const (
+	collectorPrimary   = "primary"
+	collectorSecondary = "secondary"
+)
+
+// Ocupação da fila de um batch span processor: spans entregues e ainda não exportados.
+// O SDK descarta em silêncio quando a fila enche; aqui o descarte acontece antes, no
+// mesmo limite (OTEL_BSP_MAX_QUEUE_SIZE), para ser contado em trace.export.spans_dropped
+// e avisado no log no máximo uma vez por TRACE_DROP_LOG_INTERVAL
+type spanQueueGuard struct {
+	collector   string
+	limit       int64
+	logInterval time.Duration
+
+	pending   atomic.Int64
+	unlogged  atomic.Int64 // descartes desde o último aviso
+	lastLogNs atomic.Int64
+}
+
+// Reserva um lugar na fila; false se está cheia (o span é descartado e contado)
+func (g *spanQueueGuard) admit(ctx context.Context) bool {
+	if g.pending.Add(1) <= g.limit {
+		return true
+	}
+	g.pending.Add(-1)
+
+	droppedSpans.Add(ctx, 1, metric.WithAttributes(attribute.String("collector", g.collector)))
+	g.unlogged.Add(1)
+	now := time.Now().UnixNano()
+	if last := g.lastLogNs.Load(); now-last >= int64(g.logInterval) && g.lastLogNs.CompareAndSwap(last, now) {
+		log.Printf("Fila de exportação de spans (%s) cheia: %d span(s) descartado(s) desde o último aviso", g.collector, g.unlogged.Swap(0))
+	}
+	return false
+}
+
+func (g *spanQueueGuard) done(n int) {
+	g.pending.Add(-int64(n))
+}
+
+// Batch span processor do collector com o descarte por fila cheia contado
+func newGuardedBatcher(collector string, exporter sdktrace.SpanExporter, cfg TracingConfig) sdktrace.SpanProcessor {
+	limit := cfg.MaxQueueSize
+	if limit <= 0 {
+		limit = sdktrace.DefaultMaxQueueSize
+	}
+	guard := &spanQueueGuard{collector: collector, limit: int64(limit), logInterval: cfg.DropLogInterval}
+	bsp := sdktrace.NewBatchSpanProcessor(guardedExporter{SpanExporter: exporter, guard: guard}, telemetry.BatchSpanProcessorOptions(cfg.Config)...)
+	return guardedProcessor{SpanProcessor: bsp, guard: guard}
+}
+
+type guardedProcessor struct {
+	sdktrace.SpanProcessor
+	guard *spanQueueGuard
+}
+
+// Como o batch span processor, ignora os spans não amostrados
+func (p guardedProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() && !p.guard.admit(context.Background()) {
+		return
+	}
+	p.SpanProcessor.OnEnd(s)
+}
+
+// Libera os lugares na fila quando o lote sai para o collector, com ou sem sucesso
+type guardedExporter struct {
+	sdktrace.SpanExporter
+	guard *spanQueueGuard
+}
+
+func (e guardedExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	defer e.guard.done(len(spans))
+	return e.SpanExporter.ExportSpans(ctx, spans)
+}
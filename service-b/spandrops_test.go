@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Troca o contador de spans descartados por um ligado a um leitor manual, restaurado ao
+// fim do teste
+func withDroppedSpansReader(tb testing.TB) *sdkmetric.ManualReader {
+	tb.Helper()
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	prev := droppedSpans
+	droppedSpans = newDroppedSpans(mp.Meter("service-b"))
+	tb.Cleanup(func() {
+		droppedSpans = prev
+		mp.Shutdown(context.Background())
+	})
+	return reader
+}
+
+// Valor do contador trace.export.spans_dropped por atributo collector
+func droppedCounts(tb testing.TB, reader *sdkmetric.ManualReader) map[string]int64 {
+	tb.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		tb.Fatal(err)
+	}
+	counts := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if m.Name != "trace.export.spans_dropped" || !ok {
+				continue
+			}
+			for _, dp := range sum.DataPoints {
+				collector, _ := dp.Attributes.Value(attribute.Key("collector"))
+				counts[collector.AsString()] += dp.Value
+			}
+		}
+	}
+	return counts
+}
+
+// Exporter que segura cada lote até release ser fechado
+type blockingExporter struct {
+	*tracetest.InMemoryExporter
+	release chan struct{}
+}
+
+func (e blockingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	<-e.release
+	return e.InMemoryExporter.ExportSpans(ctx, spans)
+}
+
+// Com o collector travado e uma fila de 2 spans, os excedentes são descartados e contados
+// em trace.export.spans_dropped, com um único aviso no log por TRACE_DROP_LOG_INTERVAL.
+// Liberada a fila, os spans admitidos são exportados e os novos voltam a ser aceitos
+func TestGuardedBatcherCountsDrops(t *testing.T) {
+	reader := withDroppedSpansReader(t)
+	var logs bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&logs)
+
+	exporter := blockingExporter{InMemoryExporter: tracetest.NewInMemoryExporter(), release: make(chan struct{})}
+	cfg := TracingConfig{
+		Config:          telemetry.Config{MaxQueueSize: 2, MaxExportBatchSize: 2, ScheduleDelay: time.Millisecond},
+		DropLogInterval: time.Hour,
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(newGuardedBatcher(collectorSecondary, exporter, cfg)))
+	defer tp.Shutdown(context.Background())
+
+	for range 10 {
+		_, span := tp.Tracer("test").Start(context.Background(), "span")
+		span.End()
+	}
+
+	if got := droppedCounts(t, reader); got[collectorSecondary] != 8 || len(got) != 1 {
+		t.Errorf("spans descartados = %v, want 8 no collector secondary", got)
+	}
+	if n := strings.Count(logs.String(), "Fila de exportação de spans (secondary) cheia"); n != 1 {
+		t.Errorf("avisos no log = %d, want 1:\n%s", n, logs.String())
+	}
+
+	close(exporter.release)
+	if err := tp.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(exporter.GetSpans()); got != 2 {
+		t.Errorf("spans exportados = %d, want 2", got)
+	}
+
+	_, span := tp.Tracer("test").Start(context.Background(), "span")
+	span.End()
+	if err := tp.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(exporter.GetSpans()); got != 3 {
+		t.Errorf("spans exportados após liberar a fila = %d, want 3", got)
+	}
+	if got := droppedCounts(t, reader); got[collectorSecondary] != 8 {
+		t.Errorf("spans descartados após liberar a fila = %v, want 8", got)
+	}
+}
+
+// Spans não amostrados não ocupam a fila nem contam como descartados
+func TestGuardedBatcherIgnoresUnsampled(t *testing.T) {
+	reader := withDroppedSpansReader(t)
+
+	exporter := tracetest.NewInMemoryExporter()
+	cfg := TracingConfig{Config: telemetry.Config{MaxQueueSize: 1}}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.NeverSample()),
+		sdktrace.WithSpanProcessor(newGuardedBatcher(collectorPrimary, exporter, cfg)),
+	)
+	defer tp.Shutdown(context.Background())
+
+	for range 5 {
+		_, span := tp.Tracer("test").Start(context.Background(), "span")
+		span.End()
+	}
+	tp.ForceFlush(context.Background())
+
+	if got := droppedCounts(t, reader); len(got) != 0 {
+		t.Errorf("spans descartados = %v, want nenhum", got)
+	}
+	if got := len(exporter.GetSpans()); got != 0 {
+		t.Errorf("spans exportados = %d, want 0", got)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Estratégias de nome dos spans das consultas em SPAN_NAMING
+const (
+	spanNamingByRoute = "by_route"
+	spanNamingByUF    = "by_uf"
+)
+
+// Estratégia ativa (SPAN_NAMING). Com by_uf, os spans das consultas (weather_handler,
+// batch_item) recebem a UF do CEP no nome, ex.: weather_handler:SP, para que backends
+// de alto volume possam particionar os traces por região
+var spanNaming = spanNamingByRoute
+
+// Acrescenta a UF ao nome do span com SPAN_NAMING=by_uf. Spans não amostrados não
+// expõem o nome e ficam como estão
+func nameSpanByUF(span trace.Span, uf string) {
+	if spanNaming != spanNamingByUF || uf == "" {
+		return
+	}
+	if ro, ok := span.(sdktrace.ReadOnlySpan); ok {
+		span.SetName(ro.Name() + ":" + uf)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Com SPAN_NAMING=by_uf os spans de consulta (weather_handler, batch_item) levam a UF
+// do CEP no nome; com by_route, ou sem UF no endereço, o nome fica o da rota
+func TestSpanNaming(t *testing.T) {
+	defer func(s string) { spanNaming = s }(spanNaming)
+	withBatchConfig(t)
+
+	tests := []struct {
+		name        string
+		naming      string
+		cep         CEP
+		wantHandler string
+		wantItem    string
+	}{
+		{"by_route", spanNamingByRoute, mockCEP, "weather_handler", "batch_item"},
+		{"by_uf", spanNamingByUF, mockCEP, "weather_handler:SP", "batch_item:SP"},
+		{"by_uf com outra UF", spanNamingByUF, CEP{Cep: "20040-020", Localidade: "Rio de Janeiro", Uf: "RJ"}, "weather_handler:RJ", "batch_item:RJ"},
+		{"by_uf sem UF", spanNamingByUF, CEP{Cep: "01001-000", Localidade: "São Paulo"}, "weather_handler", "batch_item"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spanNaming = tt.naming
+			withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(tt.cep)
+			}), mockWeatherAPI(23.5))
+			sr := withSpanRecorder(t)
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body)
+			}
+			rec = httptest.NewRecorder()
+			batchHandler(rec, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`["01001000"]`)))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("batch status = %d, want 200; body = %s", rec.Code, rec.Body)
+			}
+
+			var names []string
+			for _, s := range sr.Ended() {
+				names = append(names, s.Name())
+			}
+			for _, want := range []string{tt.wantHandler, tt.wantItem} {
+				if !slices.Contains(names, want) {
+					t.Errorf("spans = %v, want %q", names, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// GET /{cep}/temp?unit=C: só a temperatura, como text/plain (ex.: 23.5), para clientes
+// de shell e IoT que não querem interpretar JSON. Sem unit, usa DEFAULT_TEMP_UNIT; uma
+// unidade desconhecida responde 422. Os erros seguem em JSON, como nas demais rotas
+func tempTextHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "temp_text_handler")
+	defer span.End()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	unit := defaultTempUnit
+	if param := r.URL.Query().Get("unit"); param != "" {
+		switch unit = strings.ToUpper(param); unit {
+		case tempUnitCelsius, tempUnitFahrenheit, tempUnitKelvin:
+		default:
+			span.SetAttributes(attribute.String("validation", "invalid_unit"))
+			writeError(w, span, http.StatusUnprocessableEntity, "invalid unit")
+			return
+		}
+	}
+	span.SetAttributes(attribute.String("response.unit", unit))
+
+	result, lookupErr := lookupTemperature(ctx, r.URL.Query().Get("country"), mux.Vars(r)["cep"], false)
+	recordLookupOutcome(ctx, span, lookupOutcome(lookupErr))
+	if lookupErr != nil && lookupErr.Status == statusClientClosedRequest {
+		writeClientClosed(w, span)
+		return
+	}
+	if lookupErr != nil {
+		writeLookupError(w, span, lookupErr)
+		return
+	}
+
+	// Mesmo formato numérico do JSON (TEMP_PRECISION)
+	body, _ := temperatureIn(result.Response, unit).MarshalJSON()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", weatherCacheControl(result.Weather.Current.LastUpdatedEpoch, time.Now()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(append(body, '\n'))
+	span.AddEvent("response_written")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// /{cep}/temp responde só o número, em texto puro, na unidade de ?unit= (sem ela,
+// DEFAULT_TEMP_UNIT). Unidade desconhecida responde 422 em JSON, sem consultar os upstreams
+func TestTempTextHandler(t *testing.T) {
+	defer func(u string) { defaultTempUnit = u }(defaultTempUnit)
+	var calls atomic.Int32
+	withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		mockViaCEP().ServeHTTP(w, r)
+	}), mockWeatherAPI(25))
+
+	r := mux.NewRouter()
+	r.HandleFunc("/{cep}/temp", tempTextHandler).Methods("GET")
+
+	tests := []struct {
+		name        string
+		defaultUnit string
+		target      string
+		wantStatus  int
+		wantBody    string // corpo text/plain; vazio: erro em JSON
+		wantMessage string
+	}{
+		{"celsius", tempUnitCelsius, "/01001000/temp?unit=C", http.StatusOK, "25\n", ""},
+		{"fahrenheit", tempUnitCelsius, "/01001000/temp?unit=F", http.StatusOK, "77\n", ""},
+		{"kelvin", tempUnitCelsius, "/01001000/temp?unit=K", http.StatusOK, "298\n", ""},
+		{"minúscula", tempUnitCelsius, "/01001-000/temp?unit=k", http.StatusOK, "298\n", ""},
+		{"sem unit, default C", tempUnitCelsius, "/01001000/temp", http.StatusOK, "25\n", ""},
+		{"sem unit, default F", tempUnitFahrenheit, "/01001000/temp", http.StatusOK, "77\n", ""},
+		{"unidade desconhecida", tempUnitCelsius, "/01001000/temp?unit=X", http.StatusUnprocessableEntity, "", "invalid unit"},
+		{"unidade por extenso", tempUnitCelsius, "/01001000/temp?unit=celsius", http.StatusUnprocessableEntity, "", "invalid unit"},
+		{"CEP inválido", tempUnitCelsius, "/123/temp?unit=C", http.StatusUnprocessableEntity, "", "invalid zipcode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaultTempUnit = tt.defaultUnit
+			calls.Store(0)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			if tt.wantStatus == http.StatusOK {
+				if ct := rec.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+					t.Errorf("Content-Type = %q, want text/plain", ct)
+				}
+				if rec.Body.String() != tt.wantBody {
+					t.Errorf("corpo = %q, want %q", rec.Body, tt.wantBody)
+				}
+				return
+			}
+
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["message"] != tt.wantMessage {
+				t.Errorf("corpo = %s, want mensagem %q", rec.Body, tt.wantMessage)
+			}
+			if n := calls.Load(); n != 0 {
+				t.Errorf("chamadas ao ViaCEP = %d, want 0", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import "github.com/afga95/lab-go-otel-zipkin/shared/validation"
+
+// Faixa de CEPs (8 dígitos, inclusiva)
+type cepRange struct {
+	From, To string
+}
+
+// Faixas de CEP de teste (CEP_TEST_MODE_RANGES): respondem com dados fixos sem chamar
+// ViaCEP nem WeatherAPI, para demos e CI
+var cepTestModeRanges []cepRange
+
+// Cidade e temperatura fixas das respostas de teste
+const (
+	testModeCity   = "Cidade de Teste"
+	testModeRegion = "Sao Paulo"
+	testModeTempC  = 25.0
+)
+
+func testModeResult(cep string) (*lookupResult, bool) {
+	cep = validation.NormalizeCEP(cep)
+	for _, r := range cepTestModeRanges {
+		if cep >= r.From && cep <= r.To {
+			cepInfo := &CEP{Cep: cep, Localidade: testModeCity, Uf: "SP"}
+			weather := &WeatherData{}
+			weather.Location.Name = testModeCity
+			weather.Location.Region = testModeRegion
+			weather.Location.Country = "Brasil"
+			weather.Current.TempC = testModeTempC
+			weather.Current.Condition.Text = "Ensolarado"
+
+			return &lookupResult{
+				Response: TemperatureResponse{
+					City:   testModeCity,
+					Region: testModeRegion,
+					UF:     cepInfo.Uf,
+					TempC:  Temperature(testModeTempC),
+					TempF:  Temperature(celsiusToFahrenheit(testModeTempC)),
+					TempK:  Temperature(celsiusToKelvin(testModeTempC)),
+				},
+				CEP:     cepInfo,
+				Weather: weather,
+			}, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCEPTestModeRangesConfig(t *testing.T) {
+	t.Setenv("CEP_TEST_MODE_RANGES", "00000000-00000999, 99990000-99999999")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []cepRange{{"00000000", "00000999"}, {"99990000", "99999999"}}
+	if !slices.Equal(cfg.CEPTestModeRanges, want) {
+		t.Errorf("CEPTestModeRanges = %v, want %v", cfg.CEPTestModeRanges, want)
+	}
+}
+
+// CEPs nas faixas de teste recebem a resposta fixa sem chamar ViaCEP nem WeatherAPI, com
+// cep.test_range no span; fora das faixas, o fluxo normal segue
+func TestWeatherHandlerTestMode(t *testing.T) {
+	defer func(r []cepRange) { cepTestModeRanges = r }(cepTestModeRanges)
+	cepTestModeRanges = []cepRange{{"00000000", "00000999"}}
+
+	var calls atomic.Int32
+	count := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			h.ServeHTTP(w, r)
+		})
+	}
+	withMockUpstreams(t, count(mockViaCEP()), count(mockWeatherAPI(18)))
+	sr := withSpanRecorder(t)
+
+	tests := []struct {
+		cep       string
+		wantCity  string
+		wantTempC float64
+		testRange bool
+	}{
+		{"00000000", testModeCity, testModeTempC, true},
+		{"00000-999", testModeCity, testModeTempC, true},
+		{"00001000", "Sao Paulo", 18, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cep, func(t *testing.T) {
+			calls.Store(0)
+			sr.Reset()
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+tt.cep, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body)
+			}
+
+			var resp TemperatureResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.City != tt.wantCity || float64(resp.TempC) != tt.wantTempC {
+				t.Errorf("resposta = %s %v, want %s %v", resp.City, resp.TempC, tt.wantCity, tt.wantTempC)
+			}
+			if got := spanAttr(endedSpan(t, sr, "weather_handler"), "cep.test_range").AsBool(); got != tt.testRange {
+				t.Errorf("cep.test_range = %v, want %v", got, tt.testRange)
+			}
+			if n := calls.Load(); (n == 0) != tt.testRange {
+				t.Errorf("chamadas aos upstreams = %d", n)
+			}
+		})
+	}
+}
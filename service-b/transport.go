@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Versões de TLS aceitas em UPSTREAM_TLS_MIN_VERSION
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Transport das chamadas ao ViaCEP/WeatherAPI: o DefaultTransport com versão mínima de TLS
+func newUpstreamTransport(minTLSVersion uint16) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = &tls.Config{MinVersion: minTLSVersion}
+	return t
+}
+
+// Política de redirecionamento das chamadas ao ViaCEP/WeatherAPI: segue até maxHops
+// redirecionamentos (UPSTREAM_MAX_REDIRECTS), registrando cada um como evento no span
+// da chamada. 0 não segue nenhum e devolve a própria resposta 3xx
+func upstreamCheckRedirect(maxHops int) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		hop := len(via)
+		status := 0
+		if req.Response != nil {
+			status = req.Response.StatusCode
+		}
+		trace.SpanFromContext(req.Context()).AddEvent("upstream_redirect", trace.WithAttributes(
+			attribute.Int("redirect.hop", hop),
+			attribute.Int("redirect.status_code", status),
+			attribute.String("redirect.location", redactAPIKey(req.URL.String())),
+		))
+
+		if maxHops == 0 {
+			return http.ErrUseLastResponse
+		}
+		if hop > maxHops {
+			return fmt.Errorf("upstream excedeu %d redirecionamentos", maxHops)
+		}
+		return nil
+	}
+}
+
+// Todas as conexões com o host do upstream ficaram ocupadas além de UPSTREAM_CONN_WAIT_TIMEOUT
+var errUpstreamSaturated = errors.New("limite de conexões simultâneas com o upstream atingido")
+
+// Limita as requisições simultâneas a cada host de upstream (UPSTREAM_MAX_CONNS_PER_HOST),
+// para não sobrecarregar ViaCEP/WeatherAPI em picos. A vaga fica ocupada até o corpo da
+// resposta ser fechado; quem excede espera até wait e então falha com errUpstreamSaturated
+type hostLimitTransport struct {
+	next  http.RoundTripper
+	limit int
+	wait  time.Duration
+
+	mu   sync.Mutex
+	sems map[string]chan struct{}
+}
+
+// limit <= 0 desabilita o limite e devolve o próprio next
+func newHostLimitTransport(next *http.Transport, limit int, wait time.Duration) http.RoundTripper {
+	if limit <= 0 {
+		return next
+	}
+	// O limite do próprio pool garante o teto de conexões também nos redirecionamentos
+	next.MaxConnsPerHost = limit
+	return &hostLimitTransport{next: next, limit: limit, wait: wait, sems: make(map[string]chan struct{})}
+}
+
+func (t *hostLimitTransport) sem(host string) chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.sems[host]
+	if !ok {
+		s = make(chan struct{}, t.limit)
+		t.sems[host] = s
+	}
+	return s
+}
+
+func (t *hostLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sem := t.sem(req.URL.Host)
+	select {
+	case sem <- struct{}{}:
+	default:
+		// Sem vaga imediata: registra a espera no span da chamada
+		span := trace.SpanFromContext(req.Context())
+		span.AddEvent("upstream_host_wait", trace.WithAttributes(attribute.String("upstream.host", req.URL.Host)))
+		timer := time.NewTimer(t.wait)
+		defer timer.Stop()
+		select {
+		case sem <- struct{}{}:
+		case <-timer.C:
+			span.SetAttributes(attribute.Bool("upstream.host_saturated", true))
+			return nil, fmt.Errorf("%s: %w", req.URL.Host, errUpstreamSaturated)
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	var once sync.Once
+	release := func() { once.Do(func() { <-sem }) }
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// Corpo da resposta que libera a vaga do host ao ser fechado
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
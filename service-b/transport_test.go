@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestNewUpstreamTransportMinTLSVersion(t *testing.T) {
+	for name, version := range tlsVersions {
+		t.Run(name, func(t *testing.T) {
+			tr := newUpstreamTransport(version)
+			if tr.TLSClientConfig == nil || tr.TLSClientConfig.MinVersion != version {
+				t.Errorf("MinVersion = %+v, want %x", tr.TLSClientConfig, version)
+			}
+		})
+	}
+
+	if cfg, err := LoadConfig(); err != nil || cfg.UpstreamTLSMinVersion != tls.VersionTLS12 {
+		t.Errorf("UPSTREAM_TLS_MIN_VERSION padrão = %x (%v), want TLS 1.2", cfg.UpstreamTLSMinVersion, err)
+	}
+}
+
+// Um upstream limitado a TLS 1.2 é aceito com o mínimo 1.2 e recusado com o mínimo 1.3
+func TestNewUpstreamTransportRejectsOlderTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		min     uint16
+		wantErr bool
+	}{
+		{"mínimo 1.2", tls.VersionTLS12, false},
+		{"mínimo 1.3", tls.VersionTLS13, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newUpstreamTransport(tt.min)
+			defer tr.CloseIdleConnections()
+			tr.TLSClientConfig.RootCAs = srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+			resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// O cliente segue até maxHops redirecionamentos, com um evento upstream_redirect por
+// salto no span da chamada; acima disso falha, e com 0 devolve a própria resposta 3xx
+func TestUpstreamCheckRedirect(t *testing.T) {
+	defer func(k *apiKeyStore) { weatherAPIKey = k }(weatherAPIKey)
+	weatherAPIKey = &apiKeyStore{}
+	weatherAPIKey.Set("segredo")
+
+	// /r/N redireciona para /r/N-1 até /r/0, que responde 200
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/r/"))
+		if n == 0 {
+			w.Write([]byte("ok"))
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/r/%d?key=segredo", n-1), http.StatusFound)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		maxHops    int
+		redirects  int
+		wantStatus int // 0: erro
+		wantEvents int
+	}{
+		{"sem redirecionamento", 3, 0, http.StatusOK, 0},
+		{"dentro do limite", 3, 3, http.StatusOK, 3},
+		{"acima do limite", 3, 4, 0, 4},
+		{"desabilitado", 0, 2, http.StatusFound, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sr := withSpanRecorder(t)
+			ctx, span := tracer.Start(context.Background(), "viacep_call")
+			client := &http.Client{CheckRedirect: upstreamCheckRedirect(tt.maxHops)}
+
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/r/%d", srv.URL, tt.redirects), nil)
+			resp, err := client.Do(req)
+			span.End()
+
+			if tt.wantStatus == 0 {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatalf("status = %d, want erro de redirecionamentos", resp.StatusCode)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != tt.wantStatus {
+					t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+				}
+			}
+
+			var events []sdktrace.Event
+			for _, ev := range endedSpan(t, sr, "viacep_call").Events() {
+				if ev.Name == "upstream_redirect" {
+					events = append(events, ev)
+				}
+			}
+			if len(events) != tt.wantEvents {
+				t.Fatalf("eventos upstream_redirect = %d, want %d", len(events), tt.wantEvents)
+			}
+			for i, ev := range events {
+				for _, kv := range ev.Attributes {
+					switch kv.Key {
+					case "redirect.hop":
+						if kv.Value.AsInt64() != int64(i+1) {
+							t.Errorf("evento %d: redirect.hop = %d", i, kv.Value.AsInt64())
+						}
+					case "redirect.location":
+						if strings.Contains(kv.Value.AsString(), "segredo") {
+							t.Errorf("evento %d: chave exposta em %q", i, kv.Value.AsString())
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+// Sob carga concorrente, nunca há mais que limit requisições simultâneas no mesmo host,
+// e as excedentes esperam uma vaga em vez de falhar
+func TestHostLimitTransportConcurrentLoad(t *testing.T) {
+	const limit, requests = 2, 10
+
+	var inFlight, maxInFlight atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	tr := newUpstreamTransport(tls.VersionTLS12)
+	defer tr.CloseIdleConnections()
+	client := &http.Client{Transport: newHostLimitTransport(tr, limit, 5*time.Second)}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, requests)
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(srv.URL)
+			if err != nil {
+				errs <- err
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("requisição falhou: %v", err)
+	}
+	if got := maxInFlight.Load(); got > limit {
+		t.Errorf("requisições simultâneas no host = %d, want <= %d", got, limit)
+	}
+	if got := maxInFlight.Load(); got < 2 {
+		t.Errorf("requisições simultâneas no host = %d, want concorrência até o limite", got)
+	}
+}
+
+// Com o host no limite, a requisição excedente espera até wait e falha com
+// errUpstreamSaturated; fechar o corpo de uma resposta libera a vaga
+func TestHostLimitTransportSaturated(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/lenta" {
+			<-release
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	tr := newUpstreamTransport(tls.VersionTLS12)
+	defer tr.CloseIdleConnections()
+	client := &http.Client{Transport: newHostLimitTransport(tr, 1, 50*time.Millisecond)}
+
+	// Ocupa a única vaga com uma resposta cujo corpo ainda não foi fechado
+	resp, err := client.Get(srv.URL + "/rapida")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sr := withSpanRecorder(t)
+	ctx, span := tracer.Start(context.Background(), "viacep_call")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/rapida", nil)
+	start := time.Now()
+	_, err = client.Do(req)
+	span.End()
+	if !errors.Is(err, errUpstreamSaturated) {
+		t.Fatalf("err = %v, want errUpstreamSaturated", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("falhou em %v, want espera de UPSTREAM_CONN_WAIT_TIMEOUT", elapsed)
+	}
+	s := endedSpan(t, sr, "viacep_call")
+	if !spanAttr(s, "upstream.host_saturated").AsBool() {
+		t.Error("upstream.host_saturated ausente no span")
+	}
+	if !slices.ContainsFunc(s.Events(), func(ev sdktrace.Event) bool { return ev.Name == "upstream_host_wait" }) {
+		t.Error("evento upstream_host_wait ausente no span")
+	}
+
+	resp.Body.Close()
+	resp, err = client.Get(srv.URL + "/rapida")
+	if err != nil {
+		t.Fatalf("após liberar a vaga: %v", err)
+	}
+	resp.Body.Close()
+}
+
+// limit 0 desabilita o limite: o próprio transport é devolvido
+func TestNewHostLimitTransportDisabled(t *testing.T) {
+	tr := newUpstreamTransport(tls.VersionTLS12)
+	if got := newHostLimitTransport(tr, 0, time.Second); got != http.RoundTripper(tr) {
+		t.Errorf("newHostLimitTransport(limit 0) = %T, want o próprio *http.Transport", got)
+	}
+	if tr.MaxConnsPerHost != 0 {
+		t.Errorf("MaxConnsPerHost = %d, want 0", tr.MaxConnsPerHost)
+	}
+}
+
+// Com o ViaCEP saturado pelo limite de conexões, o handler responde 503
+func TestWeatherHandlerUpstreamSaturated(t *testing.T) {
+	release := make(chan struct{})
+	reached := make(chan struct{}, 1)
+	withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached <- struct{}{}
+		<-release
+		mockViaCEP().ServeHTTP(w, r)
+	}), mockWeatherAPI(22))
+	tr := newUpstreamTransport(tls.VersionTLS12)
+	defer tr.CloseIdleConnections()
+	httpClient.Transport = newHostLimitTransport(tr, 1, 50*time.Millisecond)
+
+	router := newWeatherRouter()
+	first := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+		first <- rec.Code
+	}()
+	<-reached
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01310100", nil))
+	close(release)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503; body = %s", rec.Code, rec.Body)
+	}
+	if code := <-first; code != http.StatusOK {
+		t.Errorf("primeira requisição: status = %d, want 200", code)
+	}
+}
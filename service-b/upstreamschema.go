@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Schemas das respostas de sucesso dos upstreams, usados como canário de mudanças
+// incompatíveis nas APIs externas
+//
+//go:embed schemas/*.json
+var upstreamSchemaFiles embed.FS
+
+// Confere as respostas dos upstreams contra os schemas (VALIDATE_UPSTREAM)
+var validateUpstream bool
+
+var upstreamSchemas = mustCompileUpstreamSchemas(map[string]string{
+	cepProviderViaCEP:         "schemas/viacep.json",
+	weatherProviderWeatherAPI: "schemas/weatherapi.json",
+})
+
+func mustCompileUpstreamSchemas(files map[string]string) map[string]*jsonschema.Schema {
+	c := jsonschema.NewCompiler()
+	schemas := make(map[string]*jsonschema.Schema, len(files))
+	for upstream, file := range files {
+		data, err := upstreamSchemaFiles.ReadFile(file)
+		if err != nil {
+			panic(err)
+		}
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+		if err != nil {
+			panic(fmt.Sprintf("%s: %v", file, err))
+		}
+		if err := c.AddResource(file, doc); err != nil {
+			panic(fmt.Sprintf("%s: %v", file, err))
+		}
+		schemas[upstream] = c.MustCompile(file)
+	}
+	return schemas
+}
+
+// Decodifica a resposta do upstream em v. Com VALIDATE_UPSTREAM, confere antes o corpo
+// contra o schema do upstream e, se o formato divergir, registra o evento
+// upstream_schema_drift no span. A divergência não interrompe a consulta: quem decide
+// se os dados bastam é o chamador
+func decodeUpstream(span trace.Span, upstream string, body io.Reader, v any) error {
+	if !validateUpstream {
+		return json.NewDecoder(body).Decode(v)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if err := checkUpstreamSchema(upstream, data); err != nil {
+		span.AddEvent("upstream_schema_drift", trace.WithAttributes(
+			attribute.String("upstream", upstream),
+			attribute.String("schema.error", err.Error()),
+		))
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Valida o corpo contra o schema do upstream; JSON inválido fica para o decoder
+func checkUpstreamSchema(upstream string, data []byte) error {
+	schema, ok := upstreamSchemas[upstream]
+	if !ok {
+		return nil
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	return schema.Validate(doc)
+}
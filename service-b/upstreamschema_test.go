@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestCheckUpstreamSchema(t *testing.T) {
+	viacep, _ := json.Marshal(mockCEP)
+
+	tests := []struct {
+		name     string
+		upstream string
+		body     string
+		wantErr  bool
+	}{
+		{"ViaCEP completo", cepProviderViaCEP, string(viacep), false},
+		{"ViaCEP não encontrado", cepProviderViaCEP, `{"erro": true}`, false},
+		{"ViaCEP sem localidade", cepProviderViaCEP, `{"cep":"01001-000","uf":"SP"}`, true},
+		{"ViaCEP com uf numérica", cepProviderViaCEP, `{"cep":"01001-000","localidade":"São Paulo","uf":35}`, true},
+		{"WeatherAPI completa", weatherProviderWeatherAPI,
+			`{"location":{"name":"Sao Paulo"},"current":{"last_updated_epoch":1,"temp_c":20,"condition":{"code":1000}}}`, false},
+		{"WeatherAPI sem temp_c", weatherProviderWeatherAPI,
+			`{"location":{"name":"Sao Paulo"},"current":{"last_updated_epoch":1,"condition":{"code":1000}}}`, true},
+		{"WeatherAPI sem location", weatherProviderWeatherAPI,
+			`{"current":{"last_updated_epoch":1,"temp_c":20,"condition":{"code":1000}}}`, true},
+		{"JSON inválido fica para o decoder", weatherProviderWeatherAPI, `{`, false},
+		{"upstream sem schema", "outro", `{}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkUpstreamSchema(tt.upstream, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkUpstreamSchema = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Uma resposta da WeatherAPI sem current.condition registra upstream_schema_drift com
+// VALIDATE_UPSTREAM=true, sem interromper a consulta; com a validação desligada, nada
+func TestWeatherHandlerUpstreamSchemaDrift(t *testing.T) {
+	defer func(v bool) { validateUpstream = v }(validateUpstream)
+
+	withMockUpstreams(t, mockViaCEP(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"location":{"name":"Sao Paulo","region":"Sao Paulo","country":"Brazil"},"current":{"last_updated_epoch":%d,"temp_c":20}}`,
+			time.Now().Unix())
+	}))
+	sr := withSpanRecorder(t)
+
+	for _, validate := range []bool{true, false} {
+		t.Run(fmt.Sprint(validate), func(t *testing.T) {
+			validateUpstream = validate
+			sr.Reset()
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body)
+			}
+
+			var drift []sdktrace.Event
+			for _, s := range sr.Ended() {
+				for _, ev := range s.Events() {
+					if ev.Name == "upstream_schema_drift" {
+						drift = append(drift, ev)
+					}
+				}
+			}
+			if !validate {
+				if len(drift) != 0 {
+					t.Errorf("eventos de drift com a validação desligada: %v", drift)
+				}
+				return
+			}
+			if len(drift) != 1 {
+				t.Fatalf("eventos de drift = %d, want 1", len(drift))
+			}
+			for _, kv := range drift[0].Attributes {
+				if kv.Key == "upstream" && kv.Value.AsString() != weatherProviderWeatherAPI {
+					t.Errorf("upstream = %q, want %s", kv.Value.AsString(), weatherProviderWeatherAPI)
+				}
+			}
+		})
+	}
+}
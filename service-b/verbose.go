@@ -0,0 +1,108 @@
+package main
+
+import (
+	"time"
+	_ "time/tzdata" // a imagem alpine não tem a base de fusos horários
+)
+
+// Resposta detalhada (?verbose=true): as temperaturas mais os dados de localização
+// e da condição atual devolvidos pela WeatherAPI
+type VerboseTemperatureResponse struct {
+	TemperatureResponse
+	Location  VerboseLocation `json:"location"`
+	Condition string          `json:"condition"`
+	Humidity  int             `json:"humidity"`
+	FeelsLike Temperature     `json:"feelslike_C"`
+
+	// Só com ?aqi=true
+	AirQuality *VerboseAirQuality `json:"air_quality,omitempty"`
+
+	// Outras localidades da busca por prefixo (CEP_PREFIX_FALLBACK), da mais próxima para
+	// a mais distante, para o cliente escolher; omitido fora dela
+	Alternatives []VerboseAlternative `json:"alternatives,omitempty"`
+}
+
+type VerboseAlternative struct {
+	CEP  string `json:"cep"`
+	City string `json:"city"`
+	UF   string `json:"uf"`
+}
+
+type VerboseLocation struct {
+	Name      string  `json:"name"`
+	Region    string  `json:"region"`
+	Country   string  `json:"country"`
+	Lat       float64 `json:"lat"`
+	Lon       float64 `json:"lon"`
+	TzID      string  `json:"tz_id"`
+	Localtime string  `json:"localtime"`
+
+	// Correspondência entre a localidade do CEP e name: exact, partial ou mismatch;
+	// omitido na consulta direta pelo código postal
+	Match string `json:"match,omitempty"`
+}
+
+type VerboseAirQuality struct {
+	PM25         float64 `json:"pm2_5"`
+	PM10         float64 `json:"pm10"`
+	O3           float64 `json:"o3"`
+	NO2          float64 `json:"no2"`
+	USEPAIndex   int     `json:"us_epa_index"`
+	GBDefraIndex int     `json:"gb_defra_index"`
+}
+
+func newVerboseResponse(result *lookupResult) VerboseTemperatureResponse {
+	loc := result.Weather.Location
+	resp := VerboseTemperatureResponse{
+		TemperatureResponse: result.Response,
+		Location: VerboseLocation{
+			Name:      loc.Name,
+			Region:    loc.Region,
+			Country:   loc.Country,
+			Lat:       loc.Lat,
+			Lon:       loc.Lon,
+			TzID:      loc.TzID,
+			Localtime: formatLocaltime(loc.Localtime, loc.LocaltimeEpoch, loc.TzID),
+			Match:     locationMatch(result.CEP.Localidade, loc.Name),
+		},
+		Condition: result.Weather.Current.Condition.Text,
+		Humidity:  result.Weather.Current.Humidity,
+		FeelsLike: Temperature(result.Weather.Current.FeelslikeC),
+	}
+
+	for _, alt := range result.Alternatives {
+		resp.Alternatives = append(resp.Alternatives, VerboseAlternative{CEP: alt.Cep, City: alt.Localidade, UF: alt.Uf})
+	}
+
+	if aq := result.Weather.Current.AirQuality; aq != nil {
+		resp.AirQuality = &VerboseAirQuality{
+			PM25:         aq.PM25,
+			PM10:         aq.PM10,
+			O3:           aq.O3,
+			NO2:          aq.NO2,
+			USEPAIndex:   aq.USEPAIndex,
+			GBDefraIndex: aq.GBDefraIndex,
+		}
+	}
+	return resp
+}
+
+// Formato do horário local da WeatherAPI, ex.: "2024-01-02 15:04" (a hora pode vir sem zero à esquerda)
+const weatherAPILocaltimeLayout = "2006-01-02 15:04"
+
+// Normaliza o horário local da WeatherAPI para RFC3339 no fuso tz_id da resposta.
+// Se o texto não puder ser interpretado, usa o epoch; sem nenhum dos dois, devolve o original
+func formatLocaltime(localtime string, epoch int, tzID string) string {
+	loc, err := time.LoadLocation(tzID)
+	if err != nil || tzID == "" {
+		return localtime
+	}
+
+	if t, err := time.ParseInLocation(weatherAPILocaltimeLayout, localtime, loc); err == nil {
+		return t.Format(time.RFC3339)
+	}
+	if epoch > 0 {
+		return time.Unix(int64(epoch), 0).In(loc).Format(time.RFC3339)
+	}
+	return localtime
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFormatLocaltime(t *testing.T) {
+	tests := []struct {
+		name      string
+		localtime string
+		epoch     int
+		tzID      string
+		want      string
+	}{
+		{"São Paulo", "2024-01-02 15:04", 0, "America/Sao_Paulo", "2024-01-02T15:04:00-03:00"},
+		{"hora sem zero à esquerda", "2024-01-02 9:05", 0, "America/Sao_Paulo", "2024-01-02T09:05:00-03:00"},
+		{"Manaus", "2024-07-10 23:59", 0, "America/Manaus", "2024-07-10T23:59:00-04:00"},
+		{"horário de verão de Lisboa", "2024-07-10 12:00", 0, "Europe/Lisbon", "2024-07-10T12:00:00+01:00"},
+		{"texto inválido usa o epoch", "ontem", 1704218640, "America/Sao_Paulo", "2024-01-02T15:04:00-03:00"},
+		{"texto inválido sem epoch", "ontem", 0, "America/Sao_Paulo", "ontem"},
+		{"sem tz_id", "2024-01-02 15:04", 1704218640, "", "2024-01-02 15:04"},
+		{"tz_id desconhecido", "2024-01-02 15:04", 0, "America/Atlantida", "2024-01-02 15:04"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatLocaltime(tt.localtime, tt.epoch, tt.tzID); got != tt.want {
+				t.Errorf("formatLocaltime(%q, %d, %q) = %q, want %q", tt.localtime, tt.epoch, tt.tzID, got, tt.want)
+			}
+		})
+	}
+}
+
+// Resposta da WeatherAPI com o bloco air_quality, que só vem quando a consulta pede aqi=yes
+const weatherAPIAirQualityResponse = `{
+	"location": {"name": "Sao Paulo", "region": "Sao Paulo", "country": "Brazil"},
+	"current": {
+		"temp_c": 23.5,
+		"condition": {"text": "Sol", "code": 1000},
+		"air_quality": {"co": 270.3, "no2": 21.5, "o3": 62.9, "so2": 4.1, "pm2_5": 12.4, "pm10": 18.7, "us-epa-index": 1, "gb-defra-index": 2}
+	}
+}`
+
+func TestWeatherHandlerAirQuality(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantAQI  bool
+		wantResp bool
+	}{
+		{"verbose com aqi", "?verbose=true&aqi=true", true, true},
+		{"verbose sem aqi", "?verbose=true", false, false},
+		{"aqi sem verbose é ignorado", "?aqi=true", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotAQI string
+			withMockUpstreams(t, mockViaCEP(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotAQI = r.URL.Query().Get("aqi")
+				if gotAQI != "yes" {
+					mockWeatherAPI(23.5).ServeHTTP(w, r)
+					return
+				}
+				io.WriteString(w, weatherAPIAirQualityResponse)
+			}))
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000"+tt.query, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			if (gotAQI == "yes") != tt.wantAQI {
+				t.Errorf("aqi na consulta à WeatherAPI = %q, want pedido %v", gotAQI, tt.wantAQI)
+			}
+
+			var body VerboseTemperatureResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if !tt.wantResp {
+				if body.AirQuality != nil {
+					t.Errorf("air_quality = %+v, want ausente", body.AirQuality)
+				}
+				return
+			}
+			want := VerboseAirQuality{PM25: 12.4, PM10: 18.7, O3: 62.9, NO2: 21.5, USEPAIndex: 1, GBDefraIndex: 2}
+			if body.AirQuality == nil || *body.AirQuality != want {
+				t.Errorf("air_quality = %+v, want %+v", body.AirQuality, want)
+			}
+		})
+	}
+}
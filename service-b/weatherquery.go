@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/validation"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Formulações da consulta de clima, na ordem em que são tentadas
+const (
+	weatherQueryCity         = "city"         // só a localidade do CEP
+	weatherQueryCityUF       = "city_uf"      // "Cidade,UF"
+	weatherQueryMunicipality = "municipality" // município do código IBGE, resolvido sob demanda
+	weatherQueryUFCapital    = "uf_capital"   // capital da UF
+)
+
+// Capitais por UF
+var capitalByUF = map[string]string{
+	"AC": "Rio Branco", "AL": "Maceió", "AP": "Macapá", "AM": "Manaus",
+	"BA": "Salvador", "CE": "Fortaleza", "DF": "Brasília", "ES": "Vitória",
+	"GO": "Goiânia", "MA": "São Luís", "MT": "Cuiabá", "MS": "Campo Grande",
+	"MG": "Belo Horizonte", "PA": "Belém", "PB": "João Pessoa", "PR": "Curitiba",
+	"PE": "Recife", "PI": "Teresina", "RJ": "Rio de Janeiro", "RN": "Natal",
+	"RS": "Porto Alegre", "RO": "Porto Velho", "RR": "Boa Vista", "SC": "Florianópolis",
+	"SP": "São Paulo", "SE": "Aracaju", "TO": "Palmas",
+}
+
+type weatherQuery struct {
+	Formulation string
+	Location    string
+	// Localidade de fallback (WEATHER_LOCALITY_FALLBACK); vazio para a do próprio CEP
+	Fallback string
+}
+
+// Formulações possíveis para o endereço, seguidas dos fallbacks de WEATHER_LOCALITY_FALLBACK
+// na ordem configurada. Sem UF, só a localidade. A localidade do município fica vazia:
+// é consultada no IBGE só se chegar a vez dela
+func weatherQueries(cepInfo *CEP) []weatherQuery {
+	queries := []weatherQuery{{Formulation: weatherQueryCity, Location: cepInfo.Localidade}}
+
+	uf := strings.ToUpper(strings.TrimSpace(cepInfo.Uf))
+	if uf == "" {
+		return queries
+	}
+	queries = append(queries, weatherQuery{Formulation: weatherQueryCityUF, Location: cepInfo.Localidade + "," + uf})
+	for _, fallback := range weatherLocalityFallback {
+		switch fallback {
+		case localityFallbackMunicipality:
+			if cepInfo.Ibge != "" {
+				queries = append(queries, weatherQuery{Formulation: weatherQueryMunicipality, Fallback: fallback})
+			}
+		case localityFallbackCapital:
+			if capital, ok := capitalByUF[uf]; ok && weatherCacheKey(capital) != weatherCacheKey(cepInfo.Localidade) {
+				queries = append(queries, weatherQuery{Formulation: weatherQueryUFCapital, Location: capital, Fallback: fallback})
+			}
+		}
+	}
+	return queries
+}
+
+// Tratamento de uma localidade devolvida em outro país (WEATHER_COUNTRY_MISMATCH)
+const (
+	countryMismatchRetry = "retry" // tenta a próxima formulação
+	countryMismatchFlag  = "flag"  // aceita o resultado como baixa confiança
+)
+
+var (
+	// Países aceitos em location.country (WEATHER_EXPECTED_COUNTRIES); vazio ou "*"
+	// desabilita a checagem
+	weatherExpectedCountries []string
+	weatherCountryMismatch   = countryMismatchRetry
+)
+
+// A WeatherAPI consulta por nome: uma cidade homônima no exterior pode ser devolvida
+func isExpectedCountry(country string) bool {
+	if len(weatherExpectedCountries) == 0 {
+		return true
+	}
+	for _, c := range weatherExpectedCountries {
+		if c == "*" || strings.EqualFold(strings.TrimSpace(country), c) {
+			return true
+		}
+	}
+	return false
+}
+
+// Consulta o clima do endereço. Enquanto a WeatherAPI não reconhecer a localidade, tenta
+// a próxima formulação (Cidade,UF e depois os fallbacks configurados), registrando no
+// span qual delas funcionou e, se foi um fallback, qual em weather.locality_fallback.
+// Outras falhas encerram a busca. Uma localidade em país inesperado também passa para a
+// próxima formulação (ou, com WEATHER_COUNTRY_MISMATCH=flag, é aceita); se nenhuma
+// resolver, o primeiro resultado é devolvido com lowConfidence
+func getWeatherForAddress(ctx context.Context, cepInfo *CEP, withAQI bool) (weather *WeatherData, lowConfidence bool, err error) {
+	span := trace.SpanFromContext(ctx)
+
+	var mismatched *WeatherData
+	var mismatchedFormulation string
+	for i, q := range weatherQueries(cepInfo) {
+		if i > 0 {
+			span.AddEvent("weather_query_escalated", trace.WithAttributes(
+				attribute.String("weather.query_formulation", q.Formulation),
+			))
+		}
+
+		if q.Formulation == weatherQueryMunicipality {
+			// Município igual à localidade já tentada ou não resolvido: nada a acrescentar
+			name, ok := municipalityName(ctx, cepInfo.Ibge)
+			if !ok || weatherCacheKey(name) == weatherCacheKey(cepInfo.Localidade) {
+				continue
+			}
+			q.Location = name + "," + strings.ToUpper(strings.TrimSpace(cepInfo.Uf))
+		}
+
+		var weatherInfo *WeatherData
+		weatherInfo, err = getWeatherInfo(ctx, q.Location, withAQI)
+		if err == nil {
+			if isExpectedCountry(weatherInfo.Location.Country) {
+				span.SetAttributes(attribute.String("weather.query_formulation", q.Formulation))
+				if q.Fallback != "" {
+					span.SetAttributes(attribute.String("weather.locality_fallback", q.Fallback))
+				}
+				return weatherInfo, false, nil
+			}
+
+			span.AddEvent("weather_country_mismatch", trace.WithAttributes(
+				attribute.String("weather.query_formulation", q.Formulation),
+				attribute.String("weather.country", weatherInfo.Location.Country),
+			))
+			if mismatched == nil {
+				mismatched, mismatchedFormulation = weatherInfo, q.Formulation
+			}
+			if weatherCountryMismatch == countryMismatchFlag {
+				break
+			}
+			continue
+		}
+
+		var apiErr *weatherAPIError
+		if !errors.As(err, &apiErr) || !apiErr.isLocationNotFound() {
+			return nil, false, err
+		}
+	}
+
+	if mismatched != nil {
+		span.SetAttributes(
+			attribute.String("weather.query_formulation", mismatchedFormulation),
+			attribute.Bool("weather.low_confidence", true),
+		)
+		return mismatched, true, nil
+	}
+	return nil, false, err
+}
+
+// Caminhos da consulta de clima, registrados em lookup.path
+const (
+	lookupPathDirect  = "direct"  // código postal direto na WeatherAPI
+	lookupPathAddress = "address" // CEP → cidade (ViaCEP) → WeatherAPI
+)
+
+// Tenta o código postal direto na WeatherAPI (WEATHER_DIRECT_POSTAL_CODE)
+var weatherDirectPostalCode bool
+
+// Consulta a WeatherAPI com o próprio código postal como q, poupando a chamada ao ViaCEP.
+// ok é false se desabilitado, se a WeatherAPI falhar ou não reconhecer o código, ou se a
+// localidade vier de país inesperado; nesses casos o chamador segue pelo endereço
+func getWeatherByPostalCode(ctx context.Context, code string, withAQI bool) (*WeatherData, bool) {
+	if !weatherDirectPostalCode {
+		return nil, false
+	}
+	span := trace.SpanFromContext(ctx)
+
+	weatherInfo, err := getWeatherInfo(ctx, validation.NormalizeCEP(code), withAQI)
+	switch {
+	case err != nil:
+		span.AddEvent("weather_direct_postal_code_failed", trace.WithAttributes(attribute.String("error", err.Error())))
+		return nil, false
+	case !isExpectedCountry(weatherInfo.Location.Country):
+		span.AddEvent("weather_country_mismatch", trace.WithAttributes(
+			attribute.String("weather.query_formulation", lookupPathDirect),
+			attribute.String("weather.country", weatherInfo.Location.Country),
+		))
+		return nil, false
+	}
+	return weatherInfo, true
+}
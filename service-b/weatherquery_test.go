@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Localidade que a WeatherAPI simulada não reconhece, de um CEP sem código IBGE
+var unknownLocalityCEP = CEP{Cep: "13999-000", Localidade: "Vila Perdida", Uf: "SP"}
+
+// Enquanto a WeatherAPI responde "No matching location found", a consulta passa para
+// Cidade,UF e então para a capital da UF, registrando a formulação que funcionou. Outras
+// falhas encerram a busca
+func TestWeatherHandlerQueryEscalation(t *testing.T) {
+	defer func(f []string) { weatherLocalityFallback = f }(weatherLocalityFallback)
+	weatherLocalityFallback = []string{localityFallbackCapital}
+
+	tests := []struct {
+		name            string
+		known           []string // localidades reconhecidas pela WeatherAPI
+		failWith        int      // status de falha para as não reconhecidas; 0: 400 com 1006
+		wantQueries     []string
+		wantStatus      int
+		wantFormulation string
+	}{
+		{"cidade", []string{"Vila Perdida"}, 0, []string{"Vila Perdida"}, http.StatusOK, weatherQueryCity},
+		{"cidade e UF", []string{"Vila Perdida,SP"}, 0, []string{"Vila Perdida", "Vila Perdida,SP"}, http.StatusOK, weatherQueryCityUF},
+		{"capital", []string{"São Paulo"}, 0, []string{"Vila Perdida", "Vila Perdida,SP", "São Paulo"}, http.StatusOK, weatherQueryUFCapital},
+		{"nenhuma", nil, 0, []string{"Vila Perdida", "Vila Perdida,SP", "São Paulo"}, http.StatusNotFound, ""},
+		{"outra falha encerra", []string{"São Paulo"}, http.StatusUnauthorized, []string{"Vila Perdida"}, http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var queries []string
+			withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(unknownLocalityCEP)
+			}), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				q := r.URL.Query().Get("q")
+				mu.Lock()
+				queries = append(queries, q)
+				mu.Unlock()
+				if slices.Contains(tt.known, q) {
+					mockWeatherAPI(21).ServeHTTP(w, r)
+					return
+				}
+				if tt.failWith != 0 {
+					w.WriteHeader(tt.failWith)
+					io.WriteString(w, `{"error":{"code":2006,"message":"API key is invalid."}}`)
+					return
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				io.WriteString(w, `{"error":{"code":1006,"message":"No matching location found."}}`)
+			}))
+			sr := withSpanRecorder(t)
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/13999000", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if !slices.Equal(queries, tt.wantQueries) {
+				t.Errorf("consultas = %q, want %q", queries, tt.wantQueries)
+			}
+			span := endedSpan(t, sr, "weather_handler")
+			if got := spanAttr(span, "weather.query_formulation").AsString(); got != tt.wantFormulation {
+				t.Errorf("weather.query_formulation = %q, want %q", got, tt.wantFormulation)
+			}
+		})
+	}
+}
+
+func TestIsExpectedCountry(t *testing.T) {
+	defer func(c []string) { weatherExpectedCountries = c }(weatherExpectedCountries)
+
+	tests := []struct {
+		name     string
+		expected []string
+		country  string
+		want     bool
+	}{
+		{"checagem desabilitada", nil, "Portugal", true},
+		{"curinga", []string{"*"}, "Portugal", true},
+		{"mesmo país", []string{"Brazil"}, "Brazil", true},
+		{"maiúsculas e espaços", []string{"Brazil"}, " BRAZIL ", true},
+		{"um dos países", []string{"Brazil", "Brasil"}, "Brasil", true},
+		{"outro país", []string{"Brazil"}, "Portugal", false},
+		{"país vazio", []string{"Brazil"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weatherExpectedCountries = tt.expected
+			if got := isExpectedCountry(tt.country); got != tt.want {
+				t.Errorf("isExpectedCountry(%q) = %v, want %v", tt.country, got, tt.want)
+			}
+		})
+	}
+}
+
+// Só com a cidade, a WeatherAPI simulada devolve uma homônima em Portugal; com a UF
+// (quando reconhecida), a brasileira. Com retry a consulta passa para Cidade,UF; com
+// flag, ou se nenhuma formulação resolver, o resultado estrangeiro volta com
+// low_confidence
+func TestWeatherHandlerCountryMismatch(t *testing.T) {
+	defer func(c []string, m string, f []string) {
+		weatherExpectedCountries, weatherCountryMismatch, weatherLocalityFallback = c, m, f
+	}(weatherExpectedCountries, weatherCountryMismatch, weatherLocalityFallback)
+	weatherExpectedCountries = []string{"Brazil", "Brasil"}
+	weatherLocalityFallback = nil
+
+	tests := []struct {
+		name              string
+		mismatch          string
+		ufKnown           bool
+		wantQueries       []string
+		wantCountry       string
+		wantLowConfidence bool
+		wantFormulation   string
+	}{
+		{"retry com a UF", countryMismatchRetry, true, []string{"Vila Perdida", "Vila Perdida,SP"}, "Brazil", false, weatherQueryCityUF},
+		{"retry sem alternativa", countryMismatchRetry, false, []string{"Vila Perdida", "Vila Perdida,SP"}, "Portugal", true, weatherQueryCity},
+		{"flag", countryMismatchFlag, true, []string{"Vila Perdida"}, "Portugal", true, weatherQueryCity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weatherCountryMismatch = tt.mismatch
+
+			var mu sync.Mutex
+			var queries []string
+			withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(unknownLocalityCEP)
+			}), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				q := r.URL.Query().Get("q")
+				mu.Lock()
+				queries = append(queries, q)
+				mu.Unlock()
+				if q == "Vila Perdida,SP" && !tt.ufKnown {
+					w.WriteHeader(http.StatusBadRequest)
+					io.WriteString(w, `{"error":{"code":1006,"message":"No matching location found."}}`)
+					return
+				}
+				var data WeatherData
+				data.Location.Name = "Vila Perdida"
+				data.Location.Country = "Portugal"
+				data.Current.TempC = 14
+				if q == "Vila Perdida,SP" {
+					data.Location.Country = "Brazil"
+					data.Current.TempC = 27
+				}
+				json.NewEncoder(w).Encode(data)
+			}))
+			sr := withSpanRecorder(t)
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/13999000", nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, http.StatusOK, rec.Body)
+			}
+			var body struct {
+				TempC         float64 `json:"temp_C"`
+				LowConfidence bool    `json:"low_confidence"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			wantTemp := 14.0
+			if tt.wantCountry == "Brazil" {
+				wantTemp = 27
+			}
+			if body.TempC != wantTemp || body.LowConfidence != tt.wantLowConfidence {
+				t.Errorf("temp_C = %v, low_confidence = %v, want %v e %v", body.TempC, body.LowConfidence, wantTemp, tt.wantLowConfidence)
+			}
+			if !slices.Equal(queries, tt.wantQueries) {
+				t.Errorf("consultas = %q, want %q", queries, tt.wantQueries)
+			}
+
+			span := endedSpan(t, sr, "weather_handler")
+			if got := spanAttr(span, "weather.query_formulation").AsString(); got != tt.wantFormulation {
+				t.Errorf("weather.query_formulation = %q, want %q", got, tt.wantFormulation)
+			}
+			if got := spanAttr(span, "weather.low_confidence").AsBool(); got != tt.wantLowConfidence {
+				t.Errorf("weather.low_confidence = %v, want %v", got, tt.wantLowConfidence)
+			}
+			if !slices.ContainsFunc(span.Events(), func(e sdktrace.Event) bool { return e.Name == "weather_country_mismatch" }) {
+				t.Error("evento weather_country_mismatch ausente")
+			}
+		})
+	}
+}
+
+// Com WEATHER_DIRECT_POSTAL_CODE=true a WeatherAPI é consultada com o próprio CEP, sem o
+// ViaCEP; se ela não reconhecer o código (ou devolver outro país), segue pelo endereço.
+// lookup.path registra o caminho usado
+func TestWeatherHandlerDirectPostalCode(t *testing.T) {
+	defer func(b bool, c []string) { weatherDirectPostalCode, weatherExpectedCountries = b, c }(weatherDirectPostalCode, weatherExpectedCountries)
+	weatherExpectedCountries = []string{"Brazil"}
+
+	tests := []struct {
+		name        string
+		enabled     bool
+		direct      string // resposta ao q=01001000: "ok", "not_found" ou "foreign"
+		wantQueries []string
+		wantViaCEP  int32
+		wantPath    string
+	}{
+		{"direto", true, "ok", []string{"01001000"}, 0, lookupPathDirect},
+		{"código não reconhecido", true, "not_found", []string{"01001000", "São Paulo"}, 1, lookupPathAddress},
+		{"outro país", true, "foreign", []string{"01001000", "São Paulo"}, 1, lookupPathAddress},
+		{"desabilitado", false, "ok", []string{"São Paulo"}, 1, lookupPathAddress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weatherDirectPostalCode = tt.enabled
+
+			var viacepCalls atomic.Int32
+			var mu sync.Mutex
+			var queries []string
+			withMockUpstreams(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				viacepCalls.Add(1)
+				mockViaCEP().ServeHTTP(w, r)
+			}), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				q := r.URL.Query().Get("q")
+				mu.Lock()
+				queries = append(queries, q)
+				mu.Unlock()
+				switch {
+				case q == "01001000" && tt.direct == "not_found":
+					w.WriteHeader(http.StatusBadRequest)
+					io.WriteString(w, `{"error":{"code":1006,"message":"No matching location found."}}`)
+				case q == "01001000" && tt.direct == "foreign":
+					var data WeatherData
+					data.Location.Name = "Lisboa"
+					data.Location.Country = "Portugal"
+					json.NewEncoder(w).Encode(data)
+				default:
+					mockWeatherAPI(23.5).ServeHTTP(w, r)
+				}
+			}))
+			sr := withSpanRecorder(t)
+
+			rec := httptest.NewRecorder()
+			newWeatherRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body)
+			}
+			if !slices.Equal(queries, tt.wantQueries) {
+				t.Errorf("consultas = %q, want %q", queries, tt.wantQueries)
+			}
+			if n := viacepCalls.Load(); n != tt.wantViaCEP {
+				t.Errorf("chamadas ao ViaCEP = %d, want %d", n, tt.wantViaCEP)
+			}
+			span := endedSpan(t, sr, "weather_handler")
+			if got := spanAttr(span, "lookup.path").AsString(); got != tt.wantPath {
+				t.Errorf("lookup.path = %q, want %q", got, tt.wantPath)
+			}
+		})
+	}
+}
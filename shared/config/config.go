@@ -0,0 +1,230 @@
+// Package config lê e valida variáveis de ambiente para a configuração dos serviços,
+// acumulando os erros de todas as variáveis inválidas em vez de parar na primeira
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Erro de configuração: variável de ambiente com valor inválido
+type Error struct {
+	Var    string
+	Value  string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s inválido: %q (%s)", e.Var, e.Value, e.Reason)
+}
+
+// Leitor de variáveis de ambiente que acumula os erros de validação. Cada método
+// devolve o default quando a variável não está definida ou é inválida
+type Parser struct {
+	errs []error
+}
+
+// Os *Error de todas as variáveis inválidas combinados com errors.Join; nil se nenhuma
+func (p *Parser) Err() error {
+	return errors.Join(p.errs...)
+}
+
+// Registra a variável name como inválida
+func (p *Parser) Fail(name, value, reason string) {
+	p.errs = append(p.errs, &Error{Var: name, Value: value, Reason: reason})
+}
+
+func (p *Parser) Str(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// Booleano nos formatos de strconv.ParseBool (true, false, 1, 0...); default false
+func (p *Parser) Bool(name string) bool {
+	v := os.Getenv(name)
+	if v == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		p.Fail(name, v, "use true ou false")
+		return false
+	}
+	return b
+}
+
+func (p *Parser) IntWithMin(name string, def, min int, reason string) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min {
+		p.Fail(name, v, reason)
+		return def
+	}
+	return n
+}
+
+func (p *Parser) PositiveInt(name string, def int) int {
+	return p.IntWithMin(name, def, 1, "use um inteiro maior que zero")
+}
+
+func (p *Parser) NonNegativeInt(name string, def int) int {
+	return p.IntWithMin(name, def, 0, "use um inteiro maior ou igual a zero")
+}
+
+func (p *Parser) Int64(name string, def int64) int64 {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		p.Fail(name, v, "use um inteiro")
+		return def
+	}
+	return n
+}
+
+// Valor textual restrito aos permitidos
+func (p *Parser) Choice(name, def string, allowed ...string) string {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	if slices.Contains(allowed, v) {
+		return v
+	}
+	p.Fail(name, v, fmt.Sprintf("use um de %v", allowed))
+	return def
+}
+
+// Inteiro restrito aos permitidos
+func (p *Parser) OneOf(name string, def int, allowed ...int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	if n, err := strconv.Atoi(v); err == nil && slices.Contains(allowed, n) {
+		return n
+	}
+	p.Fail(name, v, fmt.Sprintf("use um de %v", allowed))
+	return def
+}
+
+func (p *Parser) DurationWithMin(name string, def, min time.Duration, reason string) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < min {
+		p.Fail(name, v, reason)
+		return def
+	}
+	return d
+}
+
+func (p *Parser) PositiveDuration(name string, def time.Duration) time.Duration {
+	return p.DurationWithMin(name, def, time.Nanosecond, "use uma duração maior que zero, ex.: 10s")
+}
+
+func (p *Parser) NonNegativeDuration(name string, def time.Duration) time.Duration {
+	return p.DurationWithMin(name, def, 0, "use uma duração, ex.: 10m")
+}
+
+// Duração em milissegundos, no formato das variáveis OTEL_*
+func (p *Parser) Millis(name string) time.Duration {
+	return time.Duration(p.PositiveInt(name, 0)) * time.Millisecond
+}
+
+// Probabilidade entre 0 e 1
+func (p *Parser) Rate(name string, def float64) float64 {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 || f > 1 {
+		p.Fail(name, v, "use um valor entre 0 e 1")
+		return def
+	}
+	return f
+}
+
+// Lista separada por vírgulas, ignorando itens vazios
+func (p *Parser) List(name string) []string {
+	return splitList(os.Getenv(name))
+}
+
+// Como List, com a lista def quando a variável não está definida
+func (p *Parser) ListOr(name, def string) []string {
+	if os.Getenv(name) == "" {
+		return splitList(def)
+	}
+	return p.List(name)
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Lista separada por vírgulas de valores restritos aos permitidos, com a lista def
+// quando a variável não está definida; "none" resulta em lista vazia
+func (p *Parser) ChoiceList(name, def string, allowed ...string) []string {
+	if os.Getenv(name) == "none" {
+		return nil
+	}
+	items := p.ListOr(name, def)
+	for _, item := range items {
+		if !slices.Contains(allowed, item) {
+			p.Fail(name, os.Getenv(name), fmt.Sprintf("use valores de %v separados por vírgula, ou none", allowed))
+			return splitList(def)
+		}
+	}
+	return items
+}
+
+// Lista de inteiros separados por vírgulas
+func (p *Parser) IntList(name string) []int {
+	var ns []int
+	for _, item := range p.List(name) {
+		n, err := strconv.Atoi(item)
+		if err != nil {
+			p.Fail(name, os.Getenv(name), "use inteiros separados por vírgula")
+			return nil
+		}
+		ns = append(ns, n)
+	}
+	return ns
+}
+
+// Durações por chave, no formato "chave=duração" separado por vírgulas,
+// ex.: /{cep}=5s,/batch=30s
+func (p *Parser) DurationMap(name string) map[string]time.Duration {
+	m := make(map[string]time.Duration)
+	for _, item := range p.List(name) {
+		key, value, ok := strings.Cut(item, "=")
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if !ok || strings.TrimSpace(key) == "" || err != nil || d <= 0 {
+			p.Fail(name, os.Getenv(name), "use pares rota=duração separados por vírgula, ex.: /{cep}=5s")
+			return nil
+		}
+		m[strings.TrimSpace(key)] = d
+	}
+	return m
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParserBool(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    bool
+		wantErr bool
+	}{
+		{"", false, false},
+		{"true", true, false},
+		{"TRUE", true, false},
+		{"1", true, false},
+		{"false", false, false},
+		{"0", false, false},
+		{"sim", false, true},
+		{"yes", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("FLAG", tt.value)
+			var p Parser
+			if got := p.Bool("FLAG"); got != tt.want {
+				t.Errorf("Bool = %v, want %v", got, tt.want)
+			}
+
+			var cfgErr *Error
+			if got := errors.As(p.Err(), &cfgErr); got != tt.wantErr {
+				t.Fatalf("Err = %v, want erro %v", p.Err(), tt.wantErr)
+			}
+			if tt.wantErr && (cfgErr.Var != "FLAG" || cfgErr.Value != tt.value) {
+				t.Errorf("Error = %+v, want FLAG=%q", cfgErr, tt.value)
+			}
+		})
+	}
+}
+
+// Valores válidos são lidos; inválidos mantêm o default e viram um *Error
+func TestParserValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		read    func(p *Parser) any
+		want    any
+		wantErr bool
+	}{
+		{"str vazio usa default", "", func(p *Parser) any { return p.Str("V", "def") }, "def", false},
+		{"str", "abc", func(p *Parser) any { return p.Str("V", "def") }, "abc", false},
+		{"positiveInt", "3", func(p *Parser) any { return p.PositiveInt("V", 1) }, 3, false},
+		{"positiveInt zero", "0", func(p *Parser) any { return p.PositiveInt("V", 1) }, 1, true},
+		{"nonNegativeInt zero", "0", func(p *Parser) any { return p.NonNegativeInt("V", 1) }, 0, false},
+		{"nonNegativeInt negativo", "-1", func(p *Parser) any { return p.NonNegativeInt("V", 1) }, 1, true},
+		{"int64", "-5", func(p *Parser) any { return p.Int64("V", 1) }, int64(-5), false},
+		{"int64 inválido", "x", func(p *Parser) any { return p.Int64("V", 1) }, int64(1), true},
+		{"choice", "b", func(p *Parser) any { return p.Choice("V", "a", "a", "b") }, "b", false},
+		{"choice fora da lista", "c", func(p *Parser) any { return p.Choice("V", "a", "a", "b") }, "a", true},
+		{"oneOf", "400", func(p *Parser) any { return p.OneOf("V", 204, 204, 400) }, 400, false},
+		{"oneOf fora da lista", "500", func(p *Parser) any { return p.OneOf("V", 204, 204, 400) }, 204, true},
+		{"positiveDuration", "2s", func(p *Parser) any { return p.PositiveDuration("V", time.Second) }, 2 * time.Second, false},
+		{"positiveDuration zero", "0s", func(p *Parser) any { return p.PositiveDuration("V", time.Second) }, time.Second, true},
+		{"nonNegativeDuration zero", "0s", func(p *Parser) any { return p.NonNegativeDuration("V", time.Second) }, time.Duration(0), false},
+		{"duração sem unidade", "10", func(p *Parser) any { return p.NonNegativeDuration("V", time.Second) }, time.Second, true},
+		{"millis", "250", func(p *Parser) any { return p.Millis("V") }, 250 * time.Millisecond, false},
+		{"rate", "0.5", func(p *Parser) any { return p.Rate("V", 1) }, 0.5, false},
+		{"rate acima de 1", "1.5", func(p *Parser) any { return p.Rate("V", 1) }, 1.0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("V", tt.value)
+			var p Parser
+			if got := tt.read(&p); got != tt.want {
+				t.Errorf("valor = %v (%T), want %v (%T)", got, got, tt.want, tt.want)
+			}
+			if gotErr := p.Err() != nil; gotErr != tt.wantErr {
+				t.Errorf("Err = %v, want erro %v", p.Err(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParserLists(t *testing.T) {
+	t.Setenv("LISTA", " a, ,b ,c")
+	t.Setenv("ESCOLHAS", "x,z")
+	t.Setenv("NENHUM", "none")
+	t.Setenv("INTEIROS", "1000,1003")
+	t.Setenv("PRAZOS", "/{cep}=5s, /batch=30s")
+
+	var p Parser
+	if got := p.List("LISTA"); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("List = %v", got)
+	}
+	if got := p.ListOr("INDEFINIDA", "d,e"); !slices.Equal(got, []string{"d", "e"}) {
+		t.Errorf("ListOr = %v", got)
+	}
+	if got := p.ChoiceList("ESCOLHAS", "x", "x", "y", "z"); !slices.Equal(got, []string{"x", "z"}) {
+		t.Errorf("ChoiceList = %v", got)
+	}
+	if got := p.ChoiceList("NENHUM", "x", "x", "y"); got != nil {
+		t.Errorf("ChoiceList(none) = %v, want nil", got)
+	}
+	if got := p.IntList("INTEIROS"); !slices.Equal(got, []int{1000, 1003}) {
+		t.Errorf("IntList = %v", got)
+	}
+	if got := p.DurationMap("PRAZOS"); len(got) != 2 || got["/{cep}"] != 5*time.Second || got["/batch"] != 30*time.Second {
+		t.Errorf("DurationMap = %v", got)
+	}
+	if err := p.Err(); err != nil {
+		t.Errorf("Err = %v, want nil", err)
+	}
+}
+
+// Todas as variáveis inválidas são reportadas juntas, cada uma como *Error
+func TestParserAccumulatesErrors(t *testing.T) {
+	t.Setenv("A", "x")
+	t.Setenv("B", "-1")
+	t.Setenv("C", "talvez")
+
+	var p Parser
+	p.PositiveInt("A", 1)
+	p.NonNegativeInt("B", 1)
+	p.Bool("C")
+
+	var vars []string
+	for _, err := range p.Err().(interface{ Unwrap() []error }).Unwrap() {
+		var cfgErr *Error
+		if !errors.As(err, &cfgErr) {
+			t.Fatalf("erro %v não é *Error", err)
+		}
+		vars = append(vars, cfgErr.Var)
+	}
+	if !slices.Equal(vars, []string{"A", "B", "C"}) {
+		t.Errorf("variáveis com erro = %v, want [A B C]", vars)
+	}
+
+	want := `A inválido: "x" (use um inteiro maior que zero)`
+	if got := (&Error{Var: "A", Value: "x", Reason: "use um inteiro maior que zero"}).Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
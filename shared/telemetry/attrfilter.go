@@ -0,0 +1,73 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Remove atributos dos spans na exportação, para conter a cardinalidade (ex.: cep,
+// localidade) em backends que cobram por ela. Com allow, só as chaves listadas são
+// mantidas; as chaves em deny são sempre removidas
+type attributeFilteringExporter struct {
+	sdktrace.SpanExporter
+	allow map[attribute.Key]bool
+	deny  map[attribute.Key]bool
+}
+
+// Envolve o exporter com o filtro de TRACE_ATTR_ALLOWLIST/TRACE_ATTR_DENYLIST; sem
+// listas configuradas, devolve o exporter original
+func WithAttributeFilter(exporter sdktrace.SpanExporter, cfg Config) sdktrace.SpanExporter {
+	if len(cfg.AttributeAllowlist) == 0 && len(cfg.AttributeDenylist) == 0 {
+		return exporter
+	}
+	return attributeFilteringExporter{
+		SpanExporter: exporter,
+		allow:        attributeKeySet(cfg.AttributeAllowlist),
+		deny:         attributeKeySet(cfg.AttributeDenylist),
+	}
+}
+
+func attributeKeySet(keys []string) map[attribute.Key]bool {
+	if len(keys) == 0 {
+		return nil
+	}
+	set := make(map[attribute.Key]bool, len(keys))
+	for _, k := range keys {
+		set[attribute.Key(k)] = true
+	}
+	return set
+}
+
+func (e attributeFilteringExporter) keep(key attribute.Key) bool {
+	if e.allow != nil && !e.allow[key] {
+		return false
+	}
+	return !e.deny[key]
+}
+
+func (e attributeFilteringExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	// O slice pertence ao batch span processor: exporta uma cópia
+	filtered := make([]sdktrace.ReadOnlySpan, len(spans))
+	for i, s := range spans {
+		filtered[i] = filteredSpan{ReadOnlySpan: s, keep: e.keep}
+	}
+	return e.SpanExporter.ExportSpans(ctx, filtered)
+}
+
+// Span somente leitura com os atributos filtrados
+type filteredSpan struct {
+	sdktrace.ReadOnlySpan
+	keep func(attribute.Key) bool
+}
+
+func (s filteredSpan) Attributes() []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	for _, kv := range s.ReadOnlySpan.Attributes() {
+		if s.keep(kv.Key) {
+			attrs = append(attrs, kv)
+		}
+	}
+	return attrs
+}
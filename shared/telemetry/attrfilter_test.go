@@ -0,0 +1,59 @@
+package telemetry
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithAttributeFilter(t *testing.T) {
+	attrs := []attribute.KeyValue{
+		attribute.String("cep", "01001000"),
+		attribute.String("localidade", "São Paulo"),
+		attribute.Int("http.response.status_code", 200),
+		attribute.String("weather.provider", "weatherapi"),
+	}
+
+	tests := []struct {
+		name  string
+		allow []string
+		deny  []string
+		want  []attribute.Key
+	}{
+		{"sem listas", nil, nil, []attribute.Key{"cep", "localidade", "http.response.status_code", "weather.provider"}},
+		{"denylist", nil, []string{"cep", "localidade"}, []attribute.Key{"http.response.status_code", "weather.provider"}},
+		{"allowlist", []string{"http.response.status_code", "cep"}, nil, []attribute.Key{"cep", "http.response.status_code"}},
+		{"deny prevalece sobre allow", []string{"cep", "weather.provider"}, []string{"cep"}, []attribute.Key{"weather.provider"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(WithAttributeFilter(exporter, Config{
+				AttributeAllowlist: tt.allow,
+				AttributeDenylist:  tt.deny,
+			})))
+			defer tp.Shutdown(context.Background())
+
+			_, span := tp.Tracer("test").Start(context.Background(), "weather_handler")
+			span.SetAttributes(attrs...)
+			span.End()
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("spans exportados = %d, want 1", len(spans))
+			}
+			var got []attribute.Key
+			for _, kv := range spans[0].Attributes {
+				got = append(got, kv.Key)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("atributos = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
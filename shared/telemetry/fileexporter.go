@@ -0,0 +1,99 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Exporter que grava os spans em JSON lines num arquivo (TRACE_EXPORTER=file), para
+// depuração sem collector e artefatos de CI
+type fileSpanExporter struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// Span como gravado no arquivo, uma linha por span
+type fileSpan struct {
+	Name         string         `json:"name"`
+	TraceID      string         `json:"trace_id"`
+	SpanID       string         `json:"span_id"`
+	ParentSpanID string         `json:"parent_span_id,omitempty"`
+	Kind         string         `json:"kind"`
+	Start        time.Time      `json:"start"`
+	End          time.Time      `json:"end"`
+	Status       string         `json:"status"`
+	StatusMsg    string         `json:"status_message,omitempty"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+	Events       []fileEvent    `json:"events,omitempty"`
+}
+
+type fileEvent struct {
+	Name       string         `json:"name"`
+	Time       time.Time      `json:"time"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// Abre (ou cria) o arquivo em modo append
+func newFileSpanExporter(path string) (*fileSpanExporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir arquivo de traces: %w", err)
+	}
+	return &fileSpanExporter{file: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (e *fileSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, s := range spans {
+		if err := e.enc.Encode(newFileSpan(s)); err != nil {
+			return fmt.Errorf("erro ao gravar span: %w", err)
+		}
+	}
+	return nil
+}
+
+func (e *fileSpanExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.file.Close()
+}
+
+func newFileSpan(s sdktrace.ReadOnlySpan) fileSpan {
+	fs := fileSpan{
+		Name:       s.Name(),
+		TraceID:    s.SpanContext().TraceID().String(),
+		SpanID:     s.SpanContext().SpanID().String(),
+		Kind:       s.SpanKind().String(),
+		Start:      s.StartTime(),
+		End:        s.EndTime(),
+		Status:     s.Status().Code.String(),
+		StatusMsg:  s.Status().Description,
+		Attributes: make(map[string]any),
+	}
+	if s.Parent().HasSpanID() {
+		fs.ParentSpanID = s.Parent().SpanID().String()
+	}
+	for _, kv := range s.Attributes() {
+		fs.Attributes[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	for _, ev := range s.Events() {
+		fe := fileEvent{Name: ev.Name, Time: ev.Time}
+		if len(ev.Attributes) > 0 {
+			fe.Attributes = make(map[string]any, len(ev.Attributes))
+			for _, kv := range ev.Attributes {
+				fe.Attributes[string(kv.Key)] = kv.Value.AsInterface()
+			}
+		}
+		fs.Events = append(fs.Events, fe)
+	}
+	return fs
+}
@@ -0,0 +1,106 @@
+package telemetry
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Spans gravados em JSON lines, um por linha
+func readFileSpans(t *testing.T, path string) []fileSpan {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var spans []fileSpan
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var s fileSpan
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+			t.Fatalf("linha não é um span JSON: %v (%s)", err, scanner.Text())
+		}
+		spans = append(spans, s)
+	}
+	return spans
+}
+
+// Com TRACE_EXPORTER=file cada span vira uma linha JSON com ids, pai, status, atributos
+// e eventos; o arquivo é aberto em append, preservando os spans anteriores
+func TestFileSpanExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	export := func() (parent, child trace.SpanContext) {
+		exporter, err := NewTraceExporter(Config{Exporter: ExporterFile, FilePath: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+		tracer := tp.Tracer("test")
+
+		ctx, parentSpan := tracer.Start(context.Background(), "weather_handler", trace.WithSpanKind(trace.SpanKindServer))
+		_, childSpan := tracer.Start(ctx, "viacep_call")
+		childSpan.SetAttributes(attribute.String("cep", "01001000"), attribute.Int("http.response.status_code", 404))
+		childSpan.AddEvent("retry", trace.WithAttributes(attribute.Int("retry.attempt", 1)))
+		childSpan.RecordError(errors.New("falhou"))
+		childSpan.SetStatus(codes.Error, "can not find zipcode")
+		childSpan.End()
+		parentSpan.End()
+
+		if err := tp.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return parentSpan.SpanContext(), childSpan.SpanContext()
+	}
+
+	parent, child := export()
+	spans := readFileSpans(t, path)
+	if len(spans) != 2 {
+		t.Fatalf("linhas = %d, want 2", len(spans))
+	}
+
+	c, p := spans[0], spans[1]
+	if c.Name != "viacep_call" || c.TraceID != child.TraceID().String() || c.SpanID != child.SpanID().String() {
+		t.Errorf("span filho = %s %s/%s, want viacep_call %s/%s", c.Name, c.TraceID, c.SpanID, child.TraceID(), child.SpanID())
+	}
+	if c.ParentSpanID != parent.SpanID().String() || p.ParentSpanID != "" {
+		t.Errorf("parent_span_id = %q e %q, want %s e vazio", c.ParentSpanID, p.ParentSpanID, parent.SpanID())
+	}
+	if p.Kind != "server" || c.Kind != "internal" {
+		t.Errorf("kind = %q e %q, want server e internal", p.Kind, c.Kind)
+	}
+	if c.Status != "Error" || c.StatusMsg != "can not find zipcode" {
+		t.Errorf("status = %q %q, want Error can not find zipcode", c.Status, c.StatusMsg)
+	}
+	if c.Attributes["cep"] != "01001000" || c.Attributes["http.response.status_code"] != 404.0 {
+		t.Errorf("atributos = %v", c.Attributes)
+	}
+	if len(c.Events) != 2 || c.Events[0].Name != "retry" || c.Events[0].Attributes["retry.attempt"] != 1.0 || c.Events[1].Name != "exception" {
+		t.Errorf("eventos = %+v, want retry e exception", c.Events)
+	}
+	if c.End.Before(c.Start) || p.Start.After(c.Start) {
+		t.Errorf("horários inconsistentes: filho %v–%v, pai a partir de %v", c.Start, c.End, p.Start)
+	}
+
+	export()
+	if n := len(readFileSpans(t, path)); n != 4 {
+		t.Errorf("linhas após a segunda execução = %d, want 4 (append)", n)
+	}
+}
+
+func TestNewTraceExporterFileError(t *testing.T) {
+	_, err := NewTraceExporter(Config{Exporter: ExporterFile, FilePath: filepath.Join(t.TempDir(), "inexistente", "traces.jsonl")})
+	if err == nil {
+		t.Error("want erro para diretório inexistente")
+	}
+}
@@ -0,0 +1,117 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Exporter de traces: arquivo JSON lines com TRACE_EXPORTER=file; senão OTLP via gRPC.
+// Com OTLP_HTTP_FALLBACK_ENDPOINT configurado, confere na inicialização se o collector
+// gRPC responde em OTLP_GRPC_CONNECT_TIMEOUT e, se não responder, exporta via OTLP HTTP
+func NewTraceExporter(cfg Config) (sdktrace.SpanExporter, error) {
+	if cfg.SpanExporter != nil {
+		return cfg.SpanExporter, nil
+	}
+	if cfg.Exporter == ExporterFile {
+		log.Printf("Gravando traces em %s", cfg.FilePath)
+		return newFileSpanExporter(cfg.FilePath)
+	}
+
+	// Configuração do exporter OTLP usando grpc.NewClient
+	otlpEndpoint := strings.TrimPrefix(cfg.OTLPEndpoint, "http://")
+	conn, err := grpc.NewClient(
+		otlpEndpoint,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao conectar com OTLP endpoint: %w", err)
+	}
+
+	if cfg.HTTPFallbackEndpoint != "" && !waitForConnReady(conn, cfg.GRPCConnectTimeout) {
+		conn.Close()
+		log.Printf("Collector OTLP gRPC em %s indisponível: exportando traces via OTLP HTTP para %s", otlpEndpoint, cfg.HTTPFallbackEndpoint)
+		return newHTTPTraceExporter(cfg)
+	}
+	log.Printf("Exportando traces via OTLP gRPC para %s", otlpEndpoint)
+
+	// Timeout de exportação, para que um collector lento não bloqueie o batch processor
+	exporterOpts := []otlptracegrpc.Option{otlptracegrpc.WithGRPCConn(conn)}
+	if cfg.ExportTimeout > 0 {
+		exporterOpts = append(exporterOpts, otlptracegrpc.WithTimeout(cfg.ExportTimeout))
+	}
+
+	exporter, err := otlptracegrpc.New(context.Background(), exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar exporter: %w", err)
+	}
+	return exporter, nil
+}
+
+func newHTTPTraceExporter(cfg Config) (*otlptrace.Exporter, error) {
+	exporterOpts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(strings.TrimPrefix(cfg.HTTPFallbackEndpoint, "http://")),
+		otlptracehttp.WithInsecure(),
+	}
+	if cfg.ExportTimeout > 0 {
+		exporterOpts = append(exporterOpts, otlptracehttp.WithTimeout(cfg.ExportTimeout))
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar exporter HTTP: %w", err)
+	}
+	return exporter, nil
+}
+
+// Exporter do collector secundário (OTEL_EXPORTER_OTLP_ENDPOINT_SECONDARY), que recebe os
+// mesmos spans do principal via OTLP gRPC, ex.: durante a migração de collector. nil se
+// não configurado
+func NewSecondaryTraceExporter(cfg Config) (sdktrace.SpanExporter, error) {
+	if cfg.SecondaryOTLPEndpoint == "" {
+		return nil, nil
+	}
+
+	endpoint := strings.TrimPrefix(cfg.SecondaryOTLPEndpoint, "http://")
+	exporterOpts := []otlptracegrpc.Option{
+		otlptracegrpc.WithEndpoint(endpoint),
+		otlptracegrpc.WithInsecure(),
+	}
+	if cfg.ExportTimeout > 0 {
+		exporterOpts = append(exporterOpts, otlptracegrpc.WithTimeout(cfg.ExportTimeout))
+	}
+
+	exporter, err := otlptracegrpc.New(context.Background(), exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar exporter secundário: %w", err)
+	}
+	log.Printf("Exportando traces também para o collector secundário em %s", endpoint)
+	return exporter, nil
+}
+
+// Conecta e aguarda a conexão ficar pronta; false se não ficar até o timeout
+func waitForConnReady(conn *grpc.ClientConn, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	conn.Connect()
+	for {
+		state := conn.GetState()
+		if state == connectivity.Ready {
+			return true
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return false
+		}
+	}
+}
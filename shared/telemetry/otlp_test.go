@@ -0,0 +1,99 @@
+package telemetry
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+)
+
+// Collector gRPC simulado que conta as exportações recebidas
+type countingTraceService struct {
+	coltracepb.UnimplementedTraceServiceServer
+	exports atomic.Int32
+}
+
+func (s *countingTraceService) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	s.exports.Add(1)
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+// Endereço em que ninguém escuta
+func unreachableAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+// Com o collector gRPC inacessível na inicialização, os spans vão para o endpoint OTLP
+// HTTP; com ele acessível, o HTTP não recebe nada
+func TestNewTraceExporterHTTPFallback(t *testing.T) {
+	var httpExports atomic.Int32
+	httpCollector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/traces" {
+			httpExports.Add(1)
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer httpCollector.Close()
+
+	grpcService := &countingTraceService{}
+	grpcServer := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(grpcServer, grpcService)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go grpcServer.Serve(l)
+	defer grpcServer.Stop()
+
+	tests := []struct {
+		name     string
+		grpcAddr string
+		wantGRPC int32
+		wantHTTP int32
+	}{
+		{"gRPC inacessível", unreachableAddr(t), 0, 1},
+		{"gRPC acessível", l.Addr().String(), 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grpcService.exports.Store(0)
+			httpExports.Store(0)
+
+			exporter, err := NewTraceExporter(Config{
+				OTLPEndpoint:         tt.grpcAddr,
+				HTTPFallbackEndpoint: httpCollector.URL,
+				GRPCConnectTimeout:   500 * time.Millisecond,
+				ExportTimeout:        2 * time.Second,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			_, span := tp.Tracer("test").Start(context.Background(), "span")
+			span.End()
+			tp.Shutdown(context.Background())
+
+			if got := grpcService.exports.Load(); got != tt.wantGRPC {
+				t.Errorf("exportações gRPC = %d, want %d", got, tt.wantGRPC)
+			}
+			if got := httpExports.Load(); got != tt.wantHTTP {
+				t.Errorf("exportações HTTP = %d, want %d", got, tt.wantHTTP)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package telemetry
+
+import (
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// Handlers do net/http/pprof em /debug/pprof/
+func PprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// Sobe os handlers do net/http/pprof em /debug/pprof/ num listener separado,
+// para não expor o profiling na porta pública do serviço
+func StartPprofServer(addr string) {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           PprofHandler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		log.Printf("pprof disponível em http://%s/debug/pprof/", addr)
+		if err := server.ListenAndServe(); err != nil {
+			log.Printf("Erro no servidor pprof: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,159 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/validation"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Faixa de CEPs (8 dígitos, inclusiva)
+type cepRange struct {
+	From, To string
+}
+
+// Faixas de CEP por UF, segundo os Correios
+var cepRangesByUF = map[string][]cepRange{
+	"SP": {{"01000000", "19999999"}},
+	"RJ": {{"20000000", "28999999"}},
+	"ES": {{"29000000", "29999999"}},
+	"MG": {{"30000000", "39999999"}},
+	"BA": {{"40000000", "48999999"}},
+	"SE": {{"49000000", "49999999"}},
+	"PE": {{"50000000", "56999999"}},
+	"AL": {{"57000000", "57999999"}},
+	"PB": {{"58000000", "58999999"}},
+	"RN": {{"59000000", "59999999"}},
+	"CE": {{"60000000", "63999999"}},
+	"PI": {{"64000000", "64999999"}},
+	"MA": {{"65000000", "65999999"}},
+	"PA": {{"66000000", "68899999"}},
+	"AP": {{"68900000", "68999999"}},
+	"AM": {{"69000000", "69299999"}, {"69400000", "69899999"}},
+	"RR": {{"69300000", "69399999"}},
+	"AC": {{"69900000", "69999999"}},
+	"DF": {{"70000000", "72799999"}, {"73000000", "73699999"}},
+	"GO": {{"72800000", "72999999"}, {"73700000", "76799999"}},
+	"RO": {{"76800000", "76999999"}},
+	"TO": {{"77000000", "77999999"}},
+	"MT": {{"78000000", "78899999"}},
+	"MS": {{"79000000", "79999999"}},
+	"PR": {{"80000000", "87999999"}},
+	"SC": {{"88000000", "89999999"}},
+	"RS": {{"90000000", "99999999"}},
+}
+
+// UF do CEP pela faixa dos Correios; vazio se o CEP não tiver 8 dígitos
+func ufForCEP(cep string) string {
+	cep = validation.NormalizeCEP(cep)
+	if !validation.IsValidCEP(cep) {
+		return ""
+	}
+	for uf, ranges := range cepRangesByUF {
+		for _, r := range ranges {
+			if cep >= r.From && cep <= r.To {
+				return uf
+			}
+		}
+	}
+	return ""
+}
+
+// Amostragem direcionada: sempre amostra requisições de CEPs nas regiões configuradas
+// (UFs, ex.: "SP", ou prefixos de CEP, ex.: "0131") e aplica a taxa às demais
+type regionSampler struct {
+	ufs      map[string]bool
+	prefixes []string
+	fallback sdktrace.Sampler
+}
+
+func NewRegionSampler(regions []string, ratio float64) (sdktrace.Sampler, error) {
+	s := &regionSampler{
+		ufs:      make(map[string]bool),
+		fallback: sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)),
+	}
+	for _, region := range regions {
+		region = strings.ToUpper(strings.TrimSpace(region))
+		switch {
+		case region == "":
+		case cepRangesByUF[region] != nil:
+			s.ufs[region] = true
+		case strings.Trim(region, "0123456789") == "":
+			s.prefixes = append(s.prefixes, region)
+		default:
+			return nil, fmt.Errorf("região de amostragem inválida: %q (use uma UF ou um prefixo de CEP)", region)
+		}
+	}
+	return s, nil
+}
+
+// Só a raiz do trace decide pela região. Spans com pai (local ou remoto) seguem a decisão
+// do pai, para que um filho amostrado nunca fique sem o span pai no trace
+func (s *regionSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if !trace.SpanContextFromContext(p.ParentContext).IsValid() {
+		if cep := cepFromSamplingParameters(p); cep != "" && s.matches(cep) {
+			return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSample}
+		}
+	}
+	return s.fallback.ShouldSample(p)
+}
+
+func (s *regionSampler) Description() string {
+	return fmt.Sprintf("RegionSampler{ufs=%d,prefixes=%v,fallback=%s}", len(s.ufs), s.prefixes, s.fallback.Description())
+}
+
+func (s *regionSampler) matches(cep string) bool {
+	for _, prefix := range s.prefixes {
+		if strings.HasPrefix(cep, prefix) {
+			return true
+		}
+	}
+	return len(s.ufs) > 0 && s.ufs[ufForCEP(cep)]
+}
+
+// Membro do baggage com o CEP da requisição, para quando ele não está no path (ex.: no
+// corpo do POST do Serviço A)
+const samplingCEPBaggageKey = "cep"
+
+// Coloca o CEP no baggage de ctx para que a decisão de amostragem do span raiz o considere.
+// Deve ser chamado antes do middleware de tracing; CEPs inválidos são ignorados
+func ContextWithSamplingCEP(ctx context.Context, cep string) context.Context {
+	cep = validation.NormalizeCEP(cep)
+	if !validation.IsValidCEP(cep) {
+		return ctx
+	}
+	member, err := baggage.NewMemberRaw(samplingCEPBaggageKey, cep)
+	if err != nil {
+		return ctx
+	}
+	bag, err := baggage.FromContext(ctx).SetMember(member)
+	if err != nil {
+		return ctx
+	}
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+// CEP disponível no início do span: baggage (ContextWithSamplingCEP), atributo "cep" ou
+// o path da requisição (/{cep})
+func cepFromSamplingParameters(p sdktrace.SamplingParameters) string {
+	if cep := baggage.FromContext(p.ParentContext).Member(samplingCEPBaggageKey).Value(); cep != "" {
+		return cep
+	}
+	for _, attr := range p.Attributes {
+		switch attr.Key {
+		case attribute.Key("cep"):
+			return validation.NormalizeCEP(attr.Value.AsString())
+		case attribute.Key("url.path"):
+			path := strings.Trim(attr.Value.AsString(), "/")
+			if cep := validation.NormalizeCEP(path); validation.IsValidCEP(cep) {
+				return cep
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Contexto com um span pai (local ou remoto), amostrado ou não
+func withParent(sampled, remote bool) context.Context {
+	var flags trace.TraceFlags
+	if sampled {
+		flags = trace.FlagsSampled
+	}
+	psc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: flags,
+		Remote:     remote,
+	})
+	return trace.ContextWithSpanContext(context.Background(), psc)
+}
+
+func TestRegionSamplerTargetsRoot(t *testing.T) {
+	// Taxa zero: só as regiões alvo são amostradas
+	sampler, err := NewRegionSampler([]string{"RJ", "0131"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		attrs []attribute.KeyValue
+		want  sdktrace.SamplingDecision
+	}{
+		{"UF alvo", context.Background(), []attribute.KeyValue{attribute.String("cep", "20040-020")}, sdktrace.RecordAndSample},
+		{"prefixo alvo", context.Background(), []attribute.KeyValue{attribute.String("cep", "01310100")}, sdktrace.RecordAndSample},
+		{"path /{cep}", context.Background(), []attribute.KeyValue{attribute.String("url.path", "/20040020")}, sdktrace.RecordAndSample},
+		{"baggage", ContextWithSamplingCEP(context.Background(), "20040-020"), nil, sdktrace.RecordAndSample},
+		{"fora das regiões", context.Background(), []attribute.KeyValue{attribute.String("cep", "01001000")}, sdktrace.Drop},
+		{"sem cep", context.Background(), []attribute.KeyValue{attribute.String("url.path", "/batch")}, sdktrace.Drop},
+		{"pai local não amostrado", withParent(false, false), []attribute.KeyValue{attribute.String("cep", "20040020")}, sdktrace.Drop},
+		{"pai remoto não amostrado", withParent(false, true), []attribute.KeyValue{attribute.String("cep", "20040020")}, sdktrace.Drop},
+		{"pai amostrado", withParent(true, false), []attribute.KeyValue{attribute.String("cep", "01001000")}, sdktrace.RecordAndSample},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sampler.ShouldSample(sdktrace.SamplingParameters{
+				ParentContext: tt.ctx,
+				TraceID:       trace.TraceID{2},
+				Name:          "span",
+				Attributes:    tt.attrs,
+			})
+			if got.Decision != tt.want {
+				t.Errorf("Decision = %v, want %v", got.Decision, tt.want)
+			}
+		})
+	}
+}
+
+// Fora das regiões alvo, a fração amostrada segue TRACE_SAMPLE_RATIO
+func TestRegionSamplerRatio(t *testing.T) {
+	const n = 2000
+	for _, ratio := range []float64{0, 0.25, 1} {
+		sampler, err := NewRegionSampler([]string{"RJ"}, ratio)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var sampled, targeted int
+		for i := 0; i < n; i++ {
+			var id trace.TraceID
+			binary.BigEndian.PutUint64(id[8:], uint64(i)*0x9E3779B97F4A7C15)
+			p := sdktrace.SamplingParameters{ParentContext: context.Background(), TraceID: id, Name: "span"}
+
+			p.Attributes = []attribute.KeyValue{attribute.String("cep", "01001000")}
+			if sampler.ShouldSample(p).Decision == sdktrace.RecordAndSample {
+				sampled++
+			}
+			p.Attributes = []attribute.KeyValue{attribute.String("cep", "20040020")}
+			if sampler.ShouldSample(p).Decision == sdktrace.RecordAndSample {
+				targeted++
+			}
+		}
+
+		if got := float64(sampled) / n; got < ratio-0.05 || got > ratio+0.05 {
+			t.Errorf("ratio %v: fração amostrada = %.3f", ratio, got)
+		}
+		if targeted != n {
+			t.Errorf("ratio %v: região alvo amostrada %d de %d", ratio, targeted, n)
+		}
+	}
+}
+
+func TestNewRegionSamplerInvalidRegion(t *testing.T) {
+	for _, region := range []string{"XX", "01a"} {
+		if _, err := NewRegionSampler([]string{region}, 1); err == nil {
+			t.Errorf("região %q aceita, want erro", region)
+		}
+	}
+}
+
+func TestUFForCEP(t *testing.T) {
+	tests := []struct {
+		cep  string
+		want string
+	}{
+		{"01001-000", "SP"},
+		{"20040020", "RJ"},
+		{"69301000", "RR"},
+		{"69400000", "AM"},
+		{"73700000", "GO"},
+		{"123", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ufForCEP(tt.cep); got != tt.want {
+			t.Errorf("ufForCEP(%q) = %q, want %q", tt.cep, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Limites por span, para que spans patológicos (ex.: corpos decodificados enormes
+// em atributos) não sobrecarreguem o collector. O SDK descarta o excedente e
+// trunca valores longos
+func SpanLimits(cfg Config) sdktrace.SpanLimits {
+	limits := sdktrace.NewSpanLimits()
+	limits.AttributeCountLimit = cfg.AttributeCountLimit
+	limits.AttributeValueLengthLimit = cfg.AttributeValueLengthLimit
+	limits.EventCountLimit = cfg.EventCountLimit
+	return limits
+}
+
+// Marca na exportação os spans que perderam atributos, eventos ou links pelos limites
+const spanTruncatedKey = attribute.Key("otel.span.truncated")
+
+type truncationMarkingExporter struct {
+	sdktrace.SpanExporter
+}
+
+// Envolve o exporter com a marcação otel.span.truncated
+func WithTruncationMarking(exporter sdktrace.SpanExporter) sdktrace.SpanExporter {
+	return truncationMarkingExporter{exporter}
+}
+
+func (e truncationMarkingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	// O slice pertence ao batch span processor: copia antes de substituir itens
+	marked := spans
+	copied := false
+	for i, s := range spans {
+		if s.DroppedAttributes() == 0 && s.DroppedEvents() == 0 && s.DroppedLinks() == 0 {
+			continue
+		}
+		if !copied {
+			marked = append([]sdktrace.ReadOnlySpan(nil), spans...)
+			copied = true
+		}
+		marked[i] = truncatedSpan{s}
+	}
+	return e.SpanExporter.ExportSpans(ctx, marked)
+}
+
+// Span somente leitura com o atributo de truncamento adicionado
+type truncatedSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+func (s truncatedSpan) Attributes() []attribute.KeyValue {
+	return append(s.ReadOnlySpan.Attributes(), spanTruncatedKey.Bool(true))
+}
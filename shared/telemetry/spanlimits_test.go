@@ -0,0 +1,88 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithTruncationMarking(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(WithTruncationMarking(exporter)),
+		sdktrace.WithSpanLimits(SpanLimits(Config{AttributeCountLimit: 2, AttributeValueLengthLimit: 4, EventCountLimit: 1})),
+	)
+	defer tp.Shutdown(context.Background())
+	tracer := tp.Tracer("test")
+
+	tests := []struct {
+		name          string
+		attrs         int
+		events        int
+		value         string
+		wantTruncated bool
+	}{
+		{"dentro dos limites", 2, 1, "abc", false},
+		{"atributos descartados", 3, 0, "abc", true},
+		{"eventos descartados", 0, 2, "abc", true},
+		// Valores longos são cortados pelo SDK sem contar como descarte
+		{"valor truncado", 1, 0, "abcdefgh", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter.Reset()
+			_, span := tracer.Start(context.Background(), "span")
+			for i := 0; i < tt.attrs; i++ {
+				span.SetAttributes(attribute.String(string(rune('a'+i)), tt.value))
+			}
+			for i := 0; i < tt.events; i++ {
+				span.AddEvent("event")
+			}
+			span.End()
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("%d spans exportados, want 1", len(spans))
+			}
+			truncated := false
+			for _, kv := range spans[0].Attributes {
+				if kv.Key == spanTruncatedKey {
+					truncated = kv.Value.AsBool()
+				}
+			}
+			if truncated != tt.wantTruncated {
+				t.Errorf("otel.span.truncated = %v, want %v", truncated, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+// Os spans são substituídos numa cópia: o slice do batch span processor fica intacto
+func TestWithTruncationMarkingKeepsInput(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(recorder),
+		sdktrace.WithSpanLimits(SpanLimits(Config{AttributeCountLimit: 1, AttributeValueLengthLimit: -1, EventCountLimit: 1})),
+	)
+	defer tp.Shutdown(context.Background())
+
+	_, span := tp.Tracer("test").Start(context.Background(), "span")
+	span.SetAttributes(attribute.Int("a", 1), attribute.Int("b", 2))
+	span.End()
+
+	spans := recorder.Ended()
+	exporter := tracetest.NewInMemoryExporter()
+	if err := WithTruncationMarking(exporter).ExportSpans(context.Background(), spans); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := spans[0].(truncatedSpan); ok {
+		t.Error("o slice de entrada foi alterado")
+	}
+	if got := exporter.GetSpans(); len(got) != 1 || len(got[0].Attributes) != 2 {
+		t.Errorf("exportado = %v, want o atributo a e a marcação", got)
+	}
+}
@@ -0,0 +1,98 @@
+// Package telemetry reúne a configuração de tracing comum aos serviços: exporters
+// (OTLP gRPC/HTTP, arquivo, collector secundário), amostragem por região, limites e
+// filtros de atributos dos spans e o servidor de pprof
+package telemetry
+
+import (
+	"time"
+
+	"github.com/afga95/lab-go-otel-zipkin/shared/config"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Destinos dos traces em TRACE_EXPORTER
+const (
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+)
+
+// Configuração de tracing lida do ambiente por cada serviço
+type Config struct {
+	Exporter string // TRACE_EXPORTER: otlp ou file
+	FilePath string // TRACE_FILE_PATH: arquivo JSON lines com TRACE_EXPORTER=file
+
+	OTLPEndpoint  string
+	ExportTimeout time.Duration // OTEL_EXPORTER_OTLP_TIMEOUT
+
+	HTTPFallbackEndpoint  string        // OTLP_HTTP_FALLBACK_ENDPOINT: vazio desabilita o fallback
+	SecondaryOTLPEndpoint string        // OTEL_EXPORTER_OTLP_ENDPOINT_SECONDARY: vazio desabilita
+	GRPCConnectTimeout    time.Duration // OTLP_GRPC_CONNECT_TIMEOUT
+
+	MaxQueueSize       int           // OTEL_BSP_MAX_QUEUE_SIZE
+	MaxExportBatchSize int           // OTEL_BSP_MAX_EXPORT_BATCH_SIZE
+	ScheduleDelay      time.Duration // OTEL_BSP_SCHEDULE_DELAY
+	BSPExportTimeout   time.Duration // OTEL_BSP_EXPORT_TIMEOUT
+
+	SampleRatio   float64  // TRACE_SAMPLE_RATIO
+	TargetRegions []string // TRACE_TARGET_REGIONS: UFs ou prefixos de CEP sempre amostrados
+
+	AttributeCountLimit       int // OTEL_SPAN_ATTRIBUTE_COUNT_LIMIT
+	AttributeValueLengthLimit int // OTEL_SPAN_ATTRIBUTE_VALUE_LENGTH_LIMIT
+	EventCountLimit           int // OTEL_SPAN_EVENT_COUNT_LIMIT
+
+	AttributeAllowlist []string // TRACE_ATTR_ALLOWLIST: se definida, só estes atributos são exportados
+	AttributeDenylist  []string // TRACE_ATTR_DENYLIST: atributos nunca exportados
+
+	// Exporter já criado, usado no lugar do de Exporter (ex.: o em memória dos testes de
+	// integração). Não vem do ambiente
+	SpanExporter sdktrace.SpanExporter `json:"-"`
+}
+
+// Lê a configuração de tracing do ambiente; os erros ficam acumulados em p
+func ConfigFromEnv(p *config.Parser) Config {
+	return Config{
+		Exporter: p.Choice("TRACE_EXPORTER", ExporterOTLP, ExporterOTLP, ExporterFile),
+		FilePath: p.Str("TRACE_FILE_PATH", "traces.jsonl"),
+
+		OTLPEndpoint:  p.Str("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4317"),
+		ExportTimeout: p.Millis("OTEL_EXPORTER_OTLP_TIMEOUT"),
+
+		HTTPFallbackEndpoint:  p.Str("OTLP_HTTP_FALLBACK_ENDPOINT", ""),
+		SecondaryOTLPEndpoint: p.Str("OTEL_EXPORTER_OTLP_ENDPOINT_SECONDARY", ""),
+		GRPCConnectTimeout:    p.PositiveDuration("OTLP_GRPC_CONNECT_TIMEOUT", 5*time.Second),
+
+		MaxQueueSize:       p.PositiveInt("OTEL_BSP_MAX_QUEUE_SIZE", 0),
+		MaxExportBatchSize: p.PositiveInt("OTEL_BSP_MAX_EXPORT_BATCH_SIZE", 0),
+		ScheduleDelay:      p.Millis("OTEL_BSP_SCHEDULE_DELAY"),
+		BSPExportTimeout:   p.Millis("OTEL_BSP_EXPORT_TIMEOUT"),
+		SampleRatio:        p.Rate("TRACE_SAMPLE_RATIO", 1),
+		TargetRegions:      p.List("TRACE_TARGET_REGIONS"),
+
+		AttributeCountLimit:       p.PositiveInt("OTEL_SPAN_ATTRIBUTE_COUNT_LIMIT", 128),
+		AttributeValueLengthLimit: p.PositiveInt("OTEL_SPAN_ATTRIBUTE_VALUE_LENGTH_LIMIT", 4096),
+		EventCountLimit:           p.PositiveInt("OTEL_SPAN_EVENT_COUNT_LIMIT", 128),
+
+		AttributeAllowlist: p.List("TRACE_ATTR_ALLOWLIST"),
+		AttributeDenylist:  p.List("TRACE_ATTR_DENYLIST"),
+	}
+}
+
+// Parâmetros do batch span processor definidos via OTEL_BSP_*
+func BatchSpanProcessorOptions(cfg Config) []sdktrace.BatchSpanProcessorOption {
+	var opts []sdktrace.BatchSpanProcessorOption
+
+	if cfg.MaxQueueSize > 0 {
+		opts = append(opts, sdktrace.WithMaxQueueSize(cfg.MaxQueueSize))
+	}
+	if cfg.MaxExportBatchSize > 0 {
+		opts = append(opts, sdktrace.WithMaxExportBatchSize(cfg.MaxExportBatchSize))
+	}
+	if cfg.ScheduleDelay > 0 {
+		opts = append(opts, sdktrace.WithBatchTimeout(cfg.ScheduleDelay))
+	}
+	if cfg.BSPExportTimeout > 0 {
+		opts = append(opts, sdktrace.WithExportTimeout(cfg.BSPExportTimeout))
+	}
+
+	return opts
+}
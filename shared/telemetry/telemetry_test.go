@@ -0,0 +1,35 @@
+package telemetry
+
+import (
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// As variáveis OTEL_BSP_* chegam ao batch span processor; valores zero mantêm os
+// defaults do SDK
+func TestBatchSpanProcessorOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want sdktrace.BatchSpanProcessorOptions
+	}{
+		{"defaults do SDK", Config{}, sdktrace.BatchSpanProcessorOptions{}},
+		{"todos definidos", Config{MaxQueueSize: 4096, MaxExportBatchSize: 256, ScheduleDelay: 2 * time.Second, BSPExportTimeout: 10 * time.Second},
+			sdktrace.BatchSpanProcessorOptions{MaxQueueSize: 4096, MaxExportBatchSize: 256, BatchTimeout: 2 * time.Second, ExportTimeout: 10 * time.Second}},
+		{"só a fila", Config{MaxQueueSize: 100}, sdktrace.BatchSpanProcessorOptions{MaxQueueSize: 100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got sdktrace.BatchSpanProcessorOptions
+			for _, opt := range BatchSpanProcessorOptions(tt.cfg) {
+				opt(&got)
+			}
+			if got != tt.want {
+				t.Errorf("opções = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
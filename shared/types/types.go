@@ -0,0 +1,160 @@
+// Package types reúne os tipos trocados entre os serviços e com as APIs externas
+package types
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+)
+
+// Endereço retornado pelo ViaCEP
+type CEP struct {
+	Cep         string `json:"cep"`
+	Logradouro  string `json:"logradouro"`
+	Complemento string `json:"complemento"`
+	Bairro      string `json:"bairro"`
+	Localidade  string `json:"localidade"`
+	Uf          string `json:"uf"`
+	Ibge        string `json:"ibge"`
+	Gia         string `json:"gia"`
+	Ddd         string `json:"ddd"`
+	Siafi       string `json:"siafi"`
+	Erro        bool   `json:"erro,omitempty"`
+}
+
+// Resposta de /v1/current.json da WeatherAPI
+type WeatherData struct {
+	Location struct {
+		Name           string  `json:"name"`
+		Region         string  `json:"region"`
+		Country        string  `json:"country"`
+		Lat            float64 `json:"lat"`
+		Lon            float64 `json:"lon"`
+		TzID           string  `json:"tz_id"`
+		LocaltimeEpoch int     `json:"localtime_epoch"`
+		Localtime      string  `json:"localtime"`
+	} `json:"location"`
+	Current struct {
+		LastUpdatedEpoch int     `json:"last_updated_epoch"`
+		LastUpdated      string  `json:"last_updated"`
+		TempC            float64 `json:"temp_c"`
+		TempF            float64 `json:"temp_f"`
+		IsDay            int     `json:"is_day"`
+		Condition        struct {
+			Text string `json:"text"`
+			Icon string `json:"icon"`
+			Code int    `json:"code"`
+		} `json:"condition"`
+		WindMph    float64 `json:"wind_mph"`
+		WindKph    float64 `json:"wind_kph"`
+		WindDegree int     `json:"wind_degree"`
+		WindDir    string  `json:"wind_dir"`
+		PressureMb float64 `json:"pressure_mb"`
+		PressureIn float64 `json:"pressure_in"`
+		PrecipMm   float64 `json:"precip_mm"`
+		PrecipIn   float64 `json:"precip_in"`
+		Humidity   int     `json:"humidity"`
+		Cloud      int     `json:"cloud"`
+		FeelslikeC float64 `json:"feelslike_c"`
+		FeelslikeF float64 `json:"feelslike_f"`
+		WindchillC float64 `json:"windchill_c"`
+		WindchillF float64 `json:"windchill_f"`
+		HeatindexC float64 `json:"heatindex_c"`
+		HeatindexF float64 `json:"heatindex_f"`
+		DewpointC  float64 `json:"dewpoint_c"`
+		DewpointF  float64 `json:"dewpoint_f"`
+		VisKm      float64 `json:"vis_km"`
+		VisMiles   float64 `json:"vis_miles"`
+		Uv         float64 `json:"uv"`
+		GustMph    float64 `json:"gust_mph"`
+		GustKph    float64 `json:"gust_kph"`
+
+		// Só presente quando a consulta é feita com aqi=yes
+		AirQuality *WeatherAirQuality `json:"air_quality,omitempty"`
+	} `json:"current"`
+
+	// Erro embutido numa resposta 200, em casos raros; ausente nas respostas normais
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Qualidade do ar devolvida pela WeatherAPI (concentrações em μg/m³)
+type WeatherAirQuality struct {
+	CO           float64 `json:"co"`
+	NO2          float64 `json:"no2"`
+	O3           float64 `json:"o3"`
+	SO2          float64 `json:"so2"`
+	PM25         float64 `json:"pm2_5"`
+	PM10         float64 `json:"pm10"`
+	USEPAIndex   int     `json:"us-epa-index"`
+	GBDefraIndex int     `json:"gb-defra-index"`
+}
+
+// Resposta de temperatura do Serviço B. Region (estado, da WeatherAPI) e UF (do CEP)
+// são opcionais e omitidos quando vazios. LowConfidence indica que a localidade
+// encontrada pela WeatherAPI pode não ser a do CEP (ex.: cidade homônima no exterior).
+// PrimaryUnit (C, F ou K, de ?primary=) indica a unidade que o cliente prefere exibir;
+// as três temperaturas continuam presentes. Source só aparece quando a temperatura não é
+// uma leitura atual, ex.: historical_average (média do mês com a WeatherAPI indisponível)
+type TemperatureResponse struct {
+	City   string      `json:"city"`
+	Region string      `json:"region,omitempty"`
+	UF     string      `json:"uf,omitempty"`
+	TempC  Temperature `json:"temp_C"`
+	TempF  Temperature `json:"temp_F"`
+	TempK  Temperature `json:"temp_K"`
+
+	LowConfidence bool   `json:"low_confidence,omitempty"`
+	PrimaryUnit   string `json:"primary_unit,omitempty"`
+	Source        string `json:"source,omitempty"`
+	GeneratedAt   string `json:"generated_at,omitempty"` // RFC3339, horário em que a resposta foi montada
+
+	// Só com ?include=ddd,ibge,siafi
+	DDD   string `json:"ddd,omitempty"`
+	IBGE  string `json:"ibge,omitempty"`
+	Siafi string `json:"siafi,omitempty"`
+}
+
+// Corpo das respostas de erro dos serviços. TraceID identifica o trace da requisição,
+// para que o cliente possa informá-lo ao relatar um problema. Code e Details só são
+// preenchidos com VERBOSE_ERRORS=true, ex.: {"code":"INVALID_FORMAT","details":"expected 8 digits, got 7"}
+type ErrorResponse struct {
+	Message string `json:"message"`
+	Code    string `json:"code,omitempty"`
+	Details string `json:"details,omitempty"`
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+// Temperatura das respostas, serializada sempre em notação decimal: o encoding/json
+// usa notação científica para valores como 0.00001 (1e-05), que quebra parsers simples
+type Temperature float64
+
+// Casas decimais das temperaturas no JSON, fixadas uma única vez na inicialização do
+// serviço (SetTemperaturePrecision); -1 usa o mínimo necessário para representar o valor
+var (
+	temperaturePrecision     = -1
+	temperaturePrecisionOnce sync.Once
+)
+
+// Define as casas decimais das temperaturas no JSON. Só a primeira chamada tem efeito:
+// a precisão é fixada antes de o serviço atender requisições e não muda depois
+func SetTemperaturePrecision(precision int) {
+	temperaturePrecisionOnce.Do(func() { temperaturePrecision = precision })
+}
+
+// Acrescenta t a dst com precision casas decimais (-1: mínimo necessário), sempre em
+// notação decimal. NaN e infinito não têm representação em JSON e são um erro
+func (t Temperature) AppendFixed(dst []byte, precision int) ([]byte, error) {
+	f := float64(t)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("temperatura inválida: %v", f)
+	}
+	return strconv.AppendFloat(dst, f, 'f', precision, 64), nil
+}
+
+func (t Temperature) MarshalJSON() ([]byte, error) {
+	return t.AppendFixed(nil, temperaturePrecision)
+}
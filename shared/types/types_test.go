@@ -0,0 +1,187 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"slices"
+	"testing"
+)
+
+func BenchmarkTemperatureResponseEncode(b *testing.B) {
+	resp := TemperatureResponse{
+		City: "São Paulo", Region: "Sao Paulo", UF: "SP",
+		TempC: 23.5, TempF: 74.3, TempK: 296.5,
+		GeneratedAt: "2025-01-01T12:00:00Z",
+	}
+
+	enc := json.NewEncoder(io.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := enc.Encode(resp); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTemperatureAppendFixed(b *testing.B) {
+	benchmarks := []struct {
+		name      string
+		precision int
+	}{
+		{"precisão mínima", -1},
+		{"duas casas", 2},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			buf := make([]byte, 0, 32)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := Temperature(296.456).AppendFixed(buf[:0], bm.precision); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestTemperatureAppendFixed(t *testing.T) {
+	tests := []struct {
+		name      string
+		temp      Temperature
+		precision int
+		want      string
+		wantErr   bool
+	}{
+		{"inteiro", 25, -1, "25", false},
+		{"fracionário", 23.5, -1, "23.5", false},
+		{"negativo", -3.25, -1, "-3.25", false},
+		{"sem notação científica", 0.00001, -1, "0.00001", false},
+		{"valor grande", 1e21, -1, "1000000000000000000000", false},
+		{"duas casas", 23.5, 2, "23.50", false},
+		{"arredonda", 296.456, 1, "296.5", false},
+		{"zero casas", 23.5, 0, "24", false},
+		{"NaN", Temperature(math.NaN()), -1, "", true},
+		{"infinito", Temperature(math.Inf(1)), 2, "", true},
+		{"menos infinito", Temperature(math.Inf(-1)), -1, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.temp.AppendFixed(nil, tt.precision)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("AppendFixed(%v, %d) = %s, want %s", float64(tt.temp), tt.precision, got, tt.want)
+			}
+		})
+	}
+}
+
+// Sem SetTemperaturePrecision, o JSON usa o mínimo de casas necessário
+func TestTemperatureMarshalJSON(t *testing.T) {
+	got, err := json.Marshal([]Temperature{25, 23.5, 0.00001})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "[25,23.5,0.00001]" {
+		t.Errorf("Marshal = %s, want [25,23.5,0.00001]", got)
+	}
+
+	if _, err := json.Marshal(Temperature(math.NaN())); err == nil {
+		t.Error("Marshal(NaN) sem erro")
+	}
+}
+
+// Chaves do objeto JSON, na ordem em que aparecem
+func jsonKeys(t *testing.T, v any) []string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.Token() // {
+	var keys []string
+	for dec.More() {
+		tok, _ := dec.Token()
+		keys = append(keys, tok.(string))
+		var skip json.RawMessage
+		dec.Decode(&skip)
+	}
+	return keys
+}
+
+// Os nomes dos campos no JSON são o contrato com os clientes e com as APIs externas:
+// não podem mudar ao mexer nos tipos
+func TestJSONFieldNames(t *testing.T) {
+	t.Run("TemperatureResponse", func(t *testing.T) {
+		resp := TemperatureResponse{
+			City: "São Paulo", Region: "Sao Paulo", UF: "SP", TempC: 1, TempF: 1, TempK: 1,
+			LowConfidence: true, PrimaryUnit: "C", Source: "historical_average", GeneratedAt: "2025-01-01T12:00:00Z",
+			DDD: "11", IBGE: "3550308", Siafi: "7107",
+		}
+		want := []string{"city", "region", "uf", "temp_C", "temp_F", "temp_K", "low_confidence", "primary_unit", "source", "generated_at", "ddd", "ibge", "siafi"}
+		if got := jsonKeys(t, resp); !slices.Equal(got, want) {
+			t.Errorf("chaves = %v, want %v", got, want)
+		}
+
+		// Opcionais vazios são omitidos
+		want = []string{"city", "temp_C", "temp_F", "temp_K"}
+		if got := jsonKeys(t, TemperatureResponse{City: "São Paulo"}); !slices.Equal(got, want) {
+			t.Errorf("chaves sem opcionais = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("ErrorResponse", func(t *testing.T) {
+		want := []string{"message", "code", "details", "trace_id"}
+		if got := jsonKeys(t, ErrorResponse{Message: "m", Code: "c", Details: "d", TraceID: "t"}); !slices.Equal(got, want) {
+			t.Errorf("chaves = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("CEP do ViaCEP", func(t *testing.T) {
+		body := `{"cep":"01001-000","logradouro":"Praça da Sé","complemento":"lado ímpar","bairro":"Sé","localidade":"São Paulo","uf":"SP","ibge":"3550308","gia":"1004","ddd":"11","siafi":"7107"}`
+		var got CEP
+		if err := json.Unmarshal([]byte(body), &got); err != nil {
+			t.Fatal(err)
+		}
+		want := CEP{Cep: "01001-000", Logradouro: "Praça da Sé", Complemento: "lado ímpar", Bairro: "Sé", Localidade: "São Paulo",
+			Uf: "SP", Ibge: "3550308", Gia: "1004", Ddd: "11", Siafi: "7107"}
+		if got != want {
+			t.Errorf("CEP = %+v, want %+v", got, want)
+		}
+
+		if err := json.Unmarshal([]byte(`{"erro": true}`), &got); err != nil || !got.Erro {
+			t.Errorf("erro = %v (%v), want true", got.Erro, err)
+		}
+	})
+
+	t.Run("WeatherData da WeatherAPI", func(t *testing.T) {
+		body := `{
+			"location": {"name": "Sao Paulo", "region": "Sao Paulo", "country": "Brazil", "lat": -23.53, "lon": -46.62,
+				"tz_id": "America/Sao_Paulo", "localtime_epoch": 1704218640, "localtime": "2024-01-02 15:04"},
+			"current": {"last_updated_epoch": 1704218400, "temp_c": 23.5, "feelslike_c": 25.1, "humidity": 70,
+				"condition": {"text": "Sol", "code": 1000},
+				"air_quality": {"pm2_5": 12.4, "us-epa-index": 1, "gb-defra-index": 2}}
+		}`
+		var got WeatherData
+		if err := json.Unmarshal([]byte(body), &got); err != nil {
+			t.Fatal(err)
+		}
+		loc, cur := got.Location, got.Current
+		if loc.Name != "Sao Paulo" || loc.Country != "Brazil" || loc.Lat != -23.53 || loc.TzID != "America/Sao_Paulo" ||
+			loc.LocaltimeEpoch != 1704218640 || loc.Localtime != "2024-01-02 15:04" {
+			t.Errorf("location = %+v", loc)
+		}
+		if cur.LastUpdatedEpoch != 1704218400 || cur.TempC != 23.5 || cur.FeelslikeC != 25.1 || cur.Humidity != 70 ||
+			cur.Condition.Text != "Sol" || cur.Condition.Code != 1000 {
+			t.Errorf("current = %+v", cur)
+		}
+		if aq := cur.AirQuality; aq == nil || aq.PM25 != 12.4 || aq.USEPAIndex != 1 || aq.GBDefraIndex != 2 {
+			t.Errorf("air_quality = %+v", aq)
+		}
+	})
+}
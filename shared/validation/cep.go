@@ -0,0 +1,63 @@
+// Package validation reúne as regras de validação compartilhadas pelos serviços
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var cepPattern = regexp.MustCompile(`^\d{8}$`)
+
+// Remove traços e espaços do CEP
+func NormalizeCEP(cep string) string {
+	return strings.TrimSpace(strings.ReplaceAll(cep, "-", ""))
+}
+
+// Validação de CEP: exatamente 8 dígitos ASCII após NormalizeCEP. O CEP é tratado
+// sempre como string (nunca convertido para inteiro) para preservar zeros à
+// esquerda, ex.: 01001000
+func IsValidCEP(cep string) bool {
+	return cepPattern.MatchString(NormalizeCEP(cep))
+}
+
+// Entrada malformada: UTF-8 inválido ou caracteres de controle (ex.: %00 decodificado
+// no path). Diferente de um CEP inválido, não é nem texto legível
+func IsMalformed(s string) bool {
+	if !utf8.ValidString(s) {
+		return true
+	}
+	return strings.IndexFunc(s, unicode.IsControl) >= 0
+}
+
+// Códigos dos problemas de validação do CEP, devolvidos nas respostas de erro
+// detalhadas (VERBOSE_ERRORS=true)
+const (
+	CodeMissing           = "MISSING"
+	CodeMalformed         = "MALFORMED"
+	CodeInvalidCharacters = "INVALID_CHARACTERS"
+	CodeInvalidFormat     = "INVALID_FORMAT"
+)
+
+// Descreve por que o CEP é inválido, como código e detalhe legível, ex.:
+// INVALID_FORMAT, "expected 8 digits, got 7". Códigos vazios: CEP válido
+func DescribeCEP(cep string) (code, details string) {
+	normalized := NormalizeCEP(cep)
+	switch {
+	case normalized == "":
+		return CodeMissing, "zipcode is required"
+	case IsMalformed(cep):
+		return CodeMalformed, "zipcode contains control characters or invalid UTF-8"
+	}
+	for i, r := range normalized {
+		if r < '0' || r > '9' {
+			return CodeInvalidCharacters, fmt.Sprintf("expected only digits, got %q at position %d", r, i+1)
+		}
+	}
+	if n := len(normalized); n != 8 {
+		return CodeInvalidFormat, fmt.Sprintf("expected 8 digits, got %d", n)
+	}
+	return "", ""
+}
@@ -0,0 +1,138 @@
+package validation
+
+import "testing"
+
+func TestIsValidCEP(t *testing.T) {
+	tests := []struct {
+		name string
+		cep  string
+		want bool
+	}{
+		{"oito dígitos", "01001000", true},
+		{"zero à esquerda", "00000000", true},
+		{"vazio", "", false},
+		{"só espaços", "   ", false},
+		{"sete dígitos", "0100100", false},
+		{"nove dígitos", "010010000", false},
+		{"letras", "0100100a", false},
+		{"só letras", "abcdefgh", false},
+		{"traço na posição usual", "01001-000", true},
+		{"traço em outra posição", "0100-1000", true},
+		{"traço no início", "-01001000", true},
+		{"vários traços", "01-00-10-00", true},
+		{"traço sem dígitos suficientes", "0100-100", false},
+		{"espaço no início e no fim", " 01001000 ", true},
+		{"espaço no meio", "01001 000", false},
+		{"espaço depois do traço", "01001- 000", false},
+		{"pontos", "01.001-000", false},
+		{"ponto no fim", "01001000.", false},
+		{"dígitos árabe-índicos", "٠١٠٠١٠٠٠", false},
+		{"dígitos de largura total", "０１００１０００", false},
+		{"sinal de mais", "+01001000", false},
+		{"sinal de menos com sete dígitos", "-0100100", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsValidCEP(tt.cep); got != tt.want {
+				t.Errorf("IsValidCEP(%q) = %v, want %v", tt.cep, got, tt.want)
+			}
+		})
+	}
+}
+
+// CEPs com zeros à esquerda seguem como string em todo o caminho: nenhum zero é perdido
+func TestNormalizeCEPKeepsLeadingZeros(t *testing.T) {
+	tests := []struct {
+		cep  string
+		want string
+	}{
+		{"01001000", "01001000"},
+		{"01001-000", "01001000"},
+		{" 00000-001 ", "00000001"},
+		{"00100000", "00100000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cep, func(t *testing.T) {
+			if got := NormalizeCEP(tt.cep); got != tt.want {
+				t.Errorf("NormalizeCEP(%q) = %q, want %q", tt.cep, got, tt.want)
+			}
+		})
+	}
+}
+
+// Resultado dos benchmarks, para que o compilador não elimine as chamadas
+var benchValid bool
+
+func BenchmarkIsValidCEP(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		cep  string
+	}{
+		{"válido", "01001000"},
+		{"com traço", "01001-000"},
+		{"com espaços", " 01001-000 "},
+		{"inválido", "0100100a"},
+		{"vazio", ""},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchValid = IsValidCEP(bm.cep)
+			}
+		})
+	}
+}
+
+// Malformado é o que nem é texto legível; letras e tamanho errado são só CEP inválido
+func TestIsMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want bool
+	}{
+		{"válido", "01001000", false},
+		{"letras", "abcdefgh", false},
+		{"sete dígitos", "0100100", false},
+		{"acentos", "são-paulo", false},
+		{"byte nulo", "0100\x001000", true},
+		{"quebra de linha", "01001000\n", true},
+		{"tab", "01001\t000", true},
+		{"DEL", "01001000\x7f", true},
+		{"UTF-8 inválido", "0100\xff1000", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsMalformed(tt.s); got != tt.want {
+				t.Errorf("IsMalformed(%q) = %v, want %v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescribeCEP(t *testing.T) {
+	tests := []struct {
+		name        string
+		cep         string
+		wantCode    string
+		wantDetails string
+	}{
+		{"válido", "01001-000", "", ""},
+		{"vazio", "", CodeMissing, "zipcode is required"},
+		{"só espaços", "   ", CodeMissing, "zipcode is required"},
+		{"byte nulo", "0100\x001000", CodeMalformed, "zipcode contains control characters or invalid UTF-8"},
+		{"UTF-8 inválido", "0100\xff1000", CodeMalformed, "zipcode contains control characters or invalid UTF-8"},
+		{"letra", "0100100a", CodeInvalidCharacters, `expected only digits, got 'a' at position 8`},
+		{"espaço no meio", "01001 000", CodeInvalidCharacters, `expected only digits, got ' ' at position 6`},
+		{"sete dígitos", "0100100", CodeInvalidFormat, "expected 8 digits, got 7"},
+		{"nove dígitos", "010010000", CodeInvalidFormat, "expected 8 digits, got 9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, details := DescribeCEP(tt.cep)
+			if code != tt.wantCode || details != tt.wantDetails {
+				t.Errorf("DescribeCEP(%q) = %q, %q, want %q, %q", tt.cep, code, details, tt.wantCode, tt.wantDetails)
+			}
+		})
+	}
+}